@@ -0,0 +1,241 @@
+// Package render implements the render command, which generates the
+// Prometheus configuration from Service manifests without a Kubernetes API
+// server, the same way the daemon does.
+package render
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/flag"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+)
+
+const (
+	// stdin is the value of the services flag reading manifests from stdin.
+	stdin = "-"
+)
+
+type Config struct {
+	// Flag holds the daemon flag names, which the render command shares for
+	// the options affecting the generated configuration.
+	Flag *flag.Flag
+	// Fs is the filesystem the certificate files of clusters are looked up
+	// in.
+	Fs afero.Fs
+
+	Stdin  io.Reader
+	Stdout io.Writer
+}
+
+type Command struct {
+	cobraCommand *cobra.Command
+	flag         *flag.Flag
+	fs           afero.Fs
+	stdin        io.Reader
+	stdout       io.Writer
+}
+
+func New(config Config) (*Command, error) {
+	if config.Flag == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Flag must not be empty", config)
+	}
+	if config.Fs == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Fs must not be empty", config)
+	}
+	if config.Stdin == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Stdin must not be empty", config)
+	}
+	if config.Stdout == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Stdout must not be empty", config)
+	}
+
+	c := &Command{
+		flag:   config.Flag,
+		fs:     config.Fs,
+		stdin:  config.Stdin,
+		stdout: config.Stdout,
+	}
+
+	c.cobraCommand = &cobra.Command{
+		Use:   "render",
+		Short: "Render the Prometheus configuration from Service manifests.",
+		Long: "Render the Prometheus configuration from a base configuration and Service manifests, " +
+			"without connecting to Kubernetes. The output is identical to the configuration the daemon writes. " +
+			"Like the daemon, clusters whose certificate files are missing from the certificate directory are left out, " +
+			"unless --check-certificates=false is given, e.g. when rendering away from the certificates.",
+		RunE: c.execute,
+	}
+
+	f := c.cobraCommand.Flags()
+
+	f.String("base", "", "Path of the base prometheus.yml to add the scrape configs to.")
+	f.Bool("check-certificates", true, "Whether to leave out clusters whose certificate files are missing from the certificate directory, as the daemon does.")
+	f.String("services", stdin, "Path of a Service manifest file, or a directory of Service manifest files. - reads the manifests from stdin.")
+
+	c.flag.AddScrapeConfigFlags(f)
+
+	return c, nil
+}
+
+func (c *Command) CobraCommand() *cobra.Command {
+	return c.cobraCommand
+}
+
+func (c *Command) execute(cmd *cobra.Command, args []string) error {
+	f := cmd.Flags()
+
+	var err error
+
+	var base []byte
+	{
+		p, _ := f.GetString("base")
+		if p == "" {
+			return microerror.Maskf(invalidFlagError, "--base must not be empty")
+		}
+
+		base, err = ioutil.ReadFile(p)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var services []v1.Service
+	{
+		p, _ := f.GetString("services")
+
+		services, err = c.readServices(p)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var metaConfig prometheus.Config
+	{
+		metaConfig.CertDirectory, _ = f.GetString(c.flag.Service.Resource.Certificate.Directory)
+		metaConfig.DiscoveryURL, _ = f.GetString(c.flag.Service.Prometheus.DiscoveryURL)
+		metaConfig.EtcdScrapeDelay, _ = f.GetDuration(c.flag.Service.Prometheus.Etcd.ScrapeDelay)
+		metaConfig.EtcdScrapeMode, _ = f.GetString(c.flag.Service.Prometheus.Etcd.ScrapeMode)
+		metaConfig.Provider, _ = f.GetString(c.flag.Service.Prometheus.Provider)
+		metaConfig.ShardCount, _ = f.GetInt(c.flag.Service.Prometheus.ShardCount)
+		metaConfig.ShardIndex, _ = f.GetInt(c.flag.Service.Prometheus.ShardIndex)
+
+		if metaConfig.DiscoveryURL != "" {
+			version, _ := f.GetString(c.flag.Service.Prometheus.Version)
+			err := prometheus.ValidateDiscovery(metaConfig.DiscoveryURL, version)
+			if err != nil {
+				return microerror.Maskf(invalidFlagError, "--%s can not be used: %s", c.flag.Service.Prometheus.DiscoveryURL, err)
+			}
+		}
+		if metaConfig.Provider == "" {
+			return microerror.Maskf(invalidFlagError, "--%s must not be empty", c.flag.Service.Prometheus.Provider)
+		}
+		// Probing etcd requires access to the workload clusters.
+		if metaConfig.EtcdScrapeMode != prometheus.EtcdScrapeModeDelay && metaConfig.EtcdScrapeMode != prometheus.EtcdScrapeModeAnnotation {
+			return microerror.Maskf(invalidFlagError, "--%s must be one of %#q, %#q", c.flag.Service.Prometheus.Etcd.ScrapeMode, prometheus.EtcdScrapeModeDelay, prometheus.EtcdScrapeModeAnnotation)
+		}
+
+		p, _ := f.GetString(c.flag.Service.Prometheus.ExporterCatalog)
+		if p != "" {
+			data, err := ioutil.ReadFile(p)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			metaConfig.Exporters, err = prometheus.LoadExporters(data)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+
+		s, _ := f.GetString(c.flag.Service.Prometheus.SampleLimits)
+		metaConfig.SampleLimits, err = prometheus.ParseSampleLimits(s, metaConfig.Exporters)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if checkCertificates, _ := f.GetBool("check-certificates"); checkCertificates {
+		validServices := prometheus.FilterInvalidServices(services)
+		validServices = prometheus.FilterShardServices(validServices, metaConfig.ShardIndex, metaConfig.ShardCount)
+
+		missing := map[string]bool{}
+		for _, service := range validServices {
+			clusterID := prometheus.GetClusterID(service)
+
+			paths, err := prometheus.MissingCertificates(c.fs, metaConfig.CertDirectory, clusterID)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			if len(paths) > 0 {
+				cmd.PrintErrf("Not rendering cluster %#q, certificate files %v are missing\n", clusterID, paths)
+				missing[clusterID] = true
+			}
+		}
+
+		services = prometheus.FilterClusters(services, missing)
+	}
+
+	data, err := prometheus.RenderConfig(string(base), services, metaConfig)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = c.stdout.Write(data)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// readServices reads the Service manifests from the given path, which is
+// either a file, a directory of YAML and JSON files, or stdin.
+func (c *Command) readServices(p string) ([]v1.Service, error) {
+	if p == stdin {
+		return decodeServices(c.stdin)
+	}
+
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var paths []string
+	if info.IsDir() {
+		for _, pattern := range []string{"*.yaml", "*.yml", "*.json"} {
+			matches, err := filepath.Glob(filepath.Join(p, pattern))
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			paths = append(paths, matches...)
+		}
+	} else {
+		paths = []string{p}
+	}
+
+	var services []v1.Service
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		s, err := decodeServices(bytes.NewReader(data))
+		if err != nil {
+			return nil, microerror.Maskf(invalidFlagError, "failed to decode %#q: %s", path, err)
+		}
+		services = append(services, s...)
+	}
+
+	return services, nil
+}
@@ -0,0 +1,314 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	pccflag "github.com/giantswarm/prometheus-config-controller/flag"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/etcd"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/resource/configmap"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/scrapeconfig"
+	"github.com/giantswarm/prometheus-config-controller/service/dryrun"
+)
+
+var update = flag.Bool("update", false, "update .golden render file")
+
+// fakeClusterSource is a cluster source returning a fixed list of clusters.
+type fakeClusterSource struct {
+	services []v1.Service
+}
+
+func (s *fakeClusterSource) Clusters(ctx context.Context) ([]v1.Service, error) {
+	return s.services, nil
+}
+
+func (s *fakeClusterSource) NewRuntimeObject() runtime.Object {
+	return new(v1.Service)
+}
+
+func (s *fakeClusterSource) Selector() labels.Selector {
+	return labels.Everything()
+}
+
+// Test_Render_Command_Golden tests that the render command renders the same
+// Prometheus configuration as the daemon writes into the ConfigMap, with the
+// default flags of both.
+//
+// It uses a golden file as reference and when changes to it are intentional,
+// it can be updated by providing -update flag for go test.
+//
+//	go test ./command/render -run Test_Render_Command_Golden -update
+func Test_Render_Command_Golden(t *testing.T) {
+	base, err := ioutil.ReadFile(filepath.Join("testdata", "base.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rendered []byte
+	{
+		services, err := readTestServices()
+		if err != nil {
+			t.Fatalf("error returned reading services: %s\n", err)
+		}
+
+		fs, err := newCertFs(services)
+		if err != nil {
+			t.Fatalf("error returned writing certificates: %s\n", err)
+		}
+
+		var stdout bytes.Buffer
+
+		c, err := New(Config{
+			Flag: pccflag.New(),
+			Fs:   fs,
+
+			Stdin:  &bytes.Buffer{},
+			Stdout: &stdout,
+		})
+		if err != nil {
+			t.Fatalf("error returned creating render command: %s\n", err)
+		}
+
+		cmd := c.CobraCommand()
+		cmd.SetArgs([]string{
+			"--base", filepath.Join("testdata", "base.yml"),
+			"--services", filepath.Join("testdata", "services.yaml"),
+			"--service.prometheus.provider", "aws",
+		})
+
+		err = cmd.Execute()
+		if err != nil {
+			t.Fatalf("error returned executing render command: %s\n", err)
+		}
+
+		rendered = stdout.Bytes()
+	}
+
+	var written []byte
+	{
+		services, err := readTestServices()
+		if err != nil {
+			t.Fatalf("error returned reading services: %s\n", err)
+		}
+
+		written, err = ensureConfigMap(string(base), services)
+		if err != nil {
+			t.Fatalf("error returned ensuring configmap: %s\n", err)
+		}
+	}
+
+	p := filepath.Join("testdata", "prometheus.golden")
+
+	if *update {
+		err := ioutil.WriteFile(p, rendered, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	goldenFile, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cmp.Equal(rendered, goldenFile) {
+		t.Fatalf("rendered configuration does not match golden file:\n\n%s\n", cmp.Diff(string(goldenFile), string(rendered)))
+	}
+	if !cmp.Equal(written, goldenFile) {
+		t.Fatalf("configuration written by the daemon does not match golden file:\n\n%s\n", cmp.Diff(string(goldenFile), string(written)))
+	}
+}
+
+// newCertFs returns a filesystem holding the certificate files of the clusters
+// of the given Services in the default certificate directory.
+func newCertFs(services []v1.Service) (afero.Fs, error) {
+	fs := afero.NewMemMapFs()
+	for _, service := range services {
+		clusterID := service.Annotations["giantswarm.io/prometheus-cluster"]
+		for _, p := range []string{key.CAPath("/certs", clusterID), key.CrtPath("/certs", clusterID), key.KeyPath("/certs", clusterID)} {
+			err := afero.WriteFile(fs, p, []byte("foo"), 0600)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return fs, nil
+}
+
+// Test_Render_Command_MissingCertificates tests that the render command leaves
+// out clusters whose certificate files are missing, like the daemon, unless
+// the certificate check is disabled.
+func Test_Render_Command_MissingCertificates(t *testing.T) {
+	tests := []struct {
+		args []string
+
+		expectedRendered bool
+	}{
+		// 0. Test that a cluster with missing certificate files is left out.
+		{
+			args: nil,
+
+			expectedRendered: false,
+		},
+
+		// 1. Test that a cluster with missing certificate files is rendered
+		// when the certificate check is disabled.
+		{
+			args: []string{"--check-certificates=false"},
+
+			expectedRendered: true,
+		},
+	}
+
+	for index, test := range tests {
+		services, err := readTestServices()
+		if err != nil {
+			t.Fatalf("%d: error returned reading services: %s\n", index, err)
+		}
+
+		// Only write the certificate files of the first cluster.
+		fs, err := newCertFs(services[:1])
+		if err != nil {
+			t.Fatalf("%d: error returned writing certificates: %s\n", index, err)
+		}
+
+		var stdout bytes.Buffer
+
+		c, err := New(Config{
+			Flag: pccflag.New(),
+			Fs:   fs,
+
+			Stdin:  &bytes.Buffer{},
+			Stdout: &stdout,
+		})
+		if err != nil {
+			t.Fatalf("%d: error returned creating render command: %s\n", index, err)
+		}
+
+		cmd := c.CobraCommand()
+		cmd.SetErr(ioutil.Discard)
+		cmd.SetArgs(append([]string{
+			"--base", filepath.Join("testdata", "base.yml"),
+			"--services", filepath.Join("testdata", "services.yaml"),
+			"--service.prometheus.provider", "aws",
+		}, test.args...))
+
+		err = cmd.Execute()
+		if err != nil {
+			t.Fatalf("%d: error returned executing render command: %s\n", index, err)
+		}
+
+		if !strings.Contains(stdout.String(), "workload-cluster-xa5ly-") {
+			t.Fatalf("%d: expected cluster %#q to be rendered", index, "xa5ly")
+		}
+		if rendered := strings.Contains(stdout.String(), "workload-cluster-0ba9v-"); rendered != test.expectedRendered {
+			t.Fatalf("%d: expected cluster %#q rendered to be %t, got %t", index, "0ba9v", test.expectedRendered, rendered)
+		}
+	}
+}
+
+// readTestServices reads the Service manifests the golden file is rendered
+// from.
+func readTestServices() ([]v1.Service, error) {
+	c := &Command{}
+
+	return c.readServices(filepath.Join("testdata", "services.yaml"))
+}
+
+// ensureConfigMap runs the ConfigMap resource of the daemon, with the default
+// flags of the daemon, against a ConfigMap holding the given base
+// configuration, and returns the configuration it writes.
+func ensureConfigMap(base string, services []v1.Service) ([]byte, error) {
+	fs, err := newCertFs(services)
+	if err != nil {
+		return nil, err
+	}
+
+	k8sClient := fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "prometheus",
+			Namespace: "monitoring",
+		},
+		Data: map[string]string{
+			"prometheus.yml": base,
+		},
+	})
+
+	etcdProber, err := etcd.NewProber(etcd.ProberConfig{
+		Fs:     fs,
+		Logger: microloggertest.New(),
+
+		CertDirectory: "/certs",
+		Timeout:       time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	builder, err := scrapeconfig.NewBuilder(scrapeconfig.BuilderConfig{
+		ClusterSource: &fakeClusterSource{services: services},
+		EtcdProber:    etcdProber,
+		EventRecorder: record.NewFakeRecorder(10),
+		Fs:            fs,
+		Logger:        microloggertest.New(),
+
+		CertDirectory:   "/certs",
+		EtcdScrapeDelay: 30 * time.Minute,
+		EtcdScrapeMode:  "delay",
+		Provider:        "aws",
+		ShardCount:      1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dryRun, err := dryrun.New(dryrun.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := configmap.New(configmap.Config{
+		Builder:       builder,
+		DryRun:        dryRun,
+		EventRecorder: record.NewFakeRecorder(10),
+		Fs:            fs,
+		K8sClient:     k8sClient,
+		Logger:        microloggertest.New(),
+
+		ConfigMapKey:       "prometheus.yml",
+		ConfigMapName:      "prometheus",
+		ConfigMapNamespace: "monitoring",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.EnsureCreated(context.TODO(), v1.Service{})
+	if err != nil {
+		return nil, err
+	}
+
+	cm, err := k8sClient.CoreV1().ConfigMaps("monitoring").Get(context.TODO(), "prometheus", metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(cm.Data["prometheus.yml"]), nil
+}
@@ -0,0 +1,102 @@
+package render
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/giantswarm/microerror"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// listKind is the kind of generic lists, as printed by kubectl.
+	listKind = "List"
+	// serviceKind is the kind of the manifests decoded, manifests of other
+	// kinds are skipped.
+	serviceKind = "Service"
+	// serviceListKind is the kind of lists of Services.
+	serviceListKind = "ServiceList"
+)
+
+// decodeServices decodes all Service manifests of the given stream of YAML
+// or JSON documents. Lists are expanded, and manifests of other kinds are
+// skipped.
+func decodeServices(r io.Reader) ([]v1.Service, error) {
+	reader := k8syaml.NewYAMLReader(bufio.NewReader(r))
+
+	var services []v1.Service
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		s, err := decodeDocument(doc)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		services = append(services, s...)
+	}
+
+	return services, nil
+}
+
+// decodeDocument decodes the Services of a single YAML or JSON document.
+func decodeDocument(doc []byte) ([]v1.Service, error) {
+	var typeMeta metav1.TypeMeta
+	err := yaml.Unmarshal(doc, &typeMeta)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	switch typeMeta.Kind {
+	case serviceKind:
+		var service v1.Service
+		err := yaml.Unmarshal(doc, &service)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return []v1.Service{service}, nil
+
+	case listKind, serviceListKind:
+		var list struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		err := yaml.Unmarshal(doc, &list)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		var services []v1.Service
+		for _, item := range list.Items {
+			// Items of a ServiceList do not carry a kind.
+			if typeMeta.Kind == serviceListKind {
+				var service v1.Service
+				err := json.Unmarshal(item, &service)
+				if err != nil {
+					return nil, microerror.Mask(err)
+				}
+
+				services = append(services, service)
+				continue
+			}
+
+			s, err := decodeDocument(item)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			services = append(services, s...)
+		}
+
+		return services, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,90 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test_Render_decodeServices tests the decodeServices function.
+func Test_Render_decodeServices(t *testing.T) {
+	tests := []struct {
+		manifests string
+
+		expectedNames []string
+	}{
+		// 0. Test that an empty stream has no Services.
+		{
+			manifests: "",
+
+			expectedNames: nil,
+		},
+
+		// 1. Test that multiple YAML documents are decoded, and other kinds
+		// are skipped.
+		{
+			manifests: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: master
+  namespace: xa5ly
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: xa5ly-prometheus
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: master
+  namespace: 0ba9v
+`,
+
+			expectedNames: []string{"xa5ly/master", "0ba9v/master"},
+		},
+
+		// 2. Test that lists, as printed by kubectl, are expanded.
+		{
+			manifests: `
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: master
+    namespace: xa5ly
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: foo
+`,
+
+			expectedNames: []string{"xa5ly/master"},
+		},
+
+		// 3. Test that JSON Service lists are decoded.
+		{
+			manifests: `{"apiVersion": "v1", "kind": "ServiceList", "items": [{"metadata": {"name": "master", "namespace": "xa5ly"}}]}`,
+
+			expectedNames: []string{"xa5ly/master"},
+		},
+	}
+
+	for index, test := range tests {
+		services, err := decodeServices(strings.NewReader(test.manifests))
+		if err != nil {
+			t.Fatalf("%d: error returned decoding services: %s\n", index, err)
+		}
+
+		var names []string
+		for _, s := range services {
+			names = append(names, s.Namespace+"/"+s.Name)
+		}
+
+		if strings.Join(names, ",") != strings.Join(test.expectedNames, ",") {
+			t.Fatalf("%d: expected services %#v, got %#v\n", index, test.expectedNames, names)
+		}
+	}
+}
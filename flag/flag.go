@@ -1,7 +1,10 @@
 package flag
 
 import (
+	"time"
+
 	"github.com/giantswarm/microkit/flag"
+	"github.com/spf13/pflag"
 
 	"github.com/giantswarm/prometheus-config-controller/flag/service"
 )
@@ -16,3 +19,20 @@ func New() *Flag {
 
 	return f
 }
+
+// AddScrapeConfigFlags adds the flags affecting the generated scrape configs
+// to the given flag set. They are shared by the daemon and render commands,
+// so that both generate the same configuration by default.
+func (f *Flag) AddScrapeConfigFlags(fs *pflag.FlagSet) {
+	fs.String(f.Service.Prometheus.DiscoveryURL, "", "URL of the /targets endpoint of this controller as reachable by Prometheus, e.g. http://prometheus-config-controller.monitoring:8000/targets. When set, etcd jobs discover their targets from it over HTTP service discovery, so that changes of etcd members do not require a reload. All other jobs are still written into the configuration, so adding or removing clusters still reloads Prometheus. Requires --service.prometheus.version to be 2.28.0 or later.")
+	fs.Duration(f.Service.Prometheus.Etcd.ScrapeDelay, 30*time.Minute, "Minimum age of a workload cluster before its etcd is scraped, when the etcd scrape mode is delay.")
+	fs.String(f.Service.Prometheus.Etcd.ScrapeMode, "delay", "How scraping etcd of a workload cluster is enabled, either delay to wait for the etcd scrape delay, annotation to wait for the giantswarm.io/prometheus-etcd-ready annotation, or probe to wait for etcd to answer probes.")
+	fs.String(f.Service.Prometheus.ExporterCatalog, "", "Path of the YAML exporter catalog to generate workload cluster jobs from. When empty the built-in catalog is used.")
+	fs.String(f.Service.Prometheus.Provider, "", "The name of the provider where Prometheus is running. Used for workload clusters not specifying their own provider.")
+	fs.String(f.Service.Prometheus.SampleLimits, "", "Default sample limits of workload cluster jobs by job type, e.g. managed-app=50000,workload=50000. Job types without limit are not limited. Overridable per cluster with the giantswarm.io/prometheus-sample-limit annotation.")
+	fs.Int(f.Service.Prometheus.ShardCount, 1, "Number of Prometheus shards workload clusters are distributed across.")
+	fs.Int(f.Service.Prometheus.ShardIndex, 0, "Index of the Prometheus shard, starting at 0.")
+	fs.String(f.Service.Prometheus.Version, "", "Version of Prometheus the configuration is generated for, e.g. 2.28.1. Only used to check that it supports the HTTP service discovery configured with --service.prometheus.discoveryURL.")
+
+	fs.String(f.Service.Resource.Certificate.Directory, "/certs", "Directory in which to store certificates.")
+}
@@ -0,0 +1,10 @@
+package leaderelection
+
+type LeaderElection struct {
+	Enabled       string
+	LeaseDuration string
+	Name          string
+	Namespace     string
+	RenewDeadline string
+	RetryPeriod   string
+}
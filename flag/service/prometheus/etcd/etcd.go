@@ -0,0 +1,6 @@
+package etcd
+
+type Etcd struct {
+	ScrapeDelay string
+	ScrapeMode  string
+}
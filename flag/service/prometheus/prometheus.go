@@ -1,6 +1,22 @@
 package prometheus
 
+import (
+	"github.com/giantswarm/prometheus-config-controller/flag/service/prometheus/etcd"
+	"github.com/giantswarm/prometheus-config-controller/flag/service/prometheus/readiness"
+	"github.com/giantswarm/prometheus-config-controller/flag/service/prometheus/reload"
+)
+
 type Prometheus struct {
-	Address  string
-	Provider string
+	Address         string
+	ClusterSource   string
+	DiscoveryURL    string
+	Etcd            etcd.Etcd
+	ExporterCatalog string
+	Provider        string
+	Version         string
+	Readiness       readiness.Readiness
+	Reload          reload.Reload
+	SampleLimits    string
+	ShardCount      string
+	ShardIndex      string
 }
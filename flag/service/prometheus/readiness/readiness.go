@@ -0,0 +1,6 @@
+package readiness
+
+type Readiness struct {
+	MaxInterval string
+	Strategy    string
+}
@@ -0,0 +1,14 @@
+package reload
+
+import (
+	"github.com/giantswarm/prometheus-config-controller/flag/service/prometheus/reload/verify"
+)
+
+type Reload struct {
+	Mode      string
+	Namespace string
+	Port      string
+	Selector  string
+	Service   string
+	Verify    verify.Verify
+}
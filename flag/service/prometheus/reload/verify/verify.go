@@ -0,0 +1,6 @@
+package verify
+
+type Verify struct {
+	Interval string
+	Timeout  string
+}
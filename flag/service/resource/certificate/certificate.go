@@ -1,8 +1,20 @@
 package certificate
 
+import (
+	"github.com/giantswarm/prometheus-config-controller/flag/service/resource/certificate/projection"
+	"github.com/giantswarm/prometheus-config-controller/flag/service/resource/certificate/secret"
+	"github.com/giantswarm/prometheus-config-controller/flag/service/resource/certificate/vault"
+)
+
 type Certificate struct {
-	ComponentName string
-	Directory     string
-	Namespace     string
-	Permission    string
+	ComponentName       string
+	Directory           string
+	ExpiryWarningWindow string
+	Namespace           string
+	Output              string
+	Permission          string
+	Projection          projection.Projection
+	Secret              secret.Secret
+	Source              string
+	Vault               vault.Vault
 }
@@ -0,0 +1,7 @@
+package projection
+
+type Projection struct {
+	Name      string
+	Namespace string
+	Secrets   string
+}
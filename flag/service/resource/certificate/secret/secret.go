@@ -0,0 +1,10 @@
+package secret
+
+type Secret struct {
+	CAKey             string
+	CrtKey            string
+	KeyKey            string
+	NameTemplate      string
+	NamespaceTemplate string
+	SelectorTemplate  string
+}
@@ -0,0 +1,10 @@
+package vault
+
+type Vault struct {
+	Address            string
+	CommonNameTemplate string
+	MountTemplate      string
+	Role               string
+	TokenFile          string
+	TTL                string
+}
@@ -3,10 +3,14 @@ package resource
 import (
 	"github.com/giantswarm/prometheus-config-controller/flag/service/resource/certificate"
 	"github.com/giantswarm/prometheus-config-controller/flag/service/resource/configmap"
+	"github.com/giantswarm/prometheus-config-controller/flag/service/resource/secret"
 )
 
 type Resource struct {
+	Backend     string
 	Certificate certificate.Certificate
 	ConfigMap   configmap.ConfigMap
+	DryRun      string
 	Retries     string
+	Secret      secret.Secret
 }
@@ -0,0 +1,7 @@
+package secret
+
+type Secret struct {
+	Key       string
+	Name      string
+	Namespace string
+}
@@ -13,14 +13,21 @@ require (
 	github.com/giantswarm/micrologger v0.3.1
 	github.com/giantswarm/operatorkit/v2 v2.0.0
 	github.com/giantswarm/versionbundle v0.2.0
+	github.com/go-kit/kit v0.10.0
 	github.com/google/go-cmp v0.5.1
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/prometheus/client_golang v1.7.1
 	github.com/prometheus/common v0.11.1
 	github.com/prometheus/prometheus v2.20.1+incompatible
-	github.com/spf13/afero v1.2.2
+	github.com/spf13/afero v1.3.2
+	github.com/spf13/cobra v0.0.6
+	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.6.2
 	gopkg.in/yaml.v2 v2.3.0
 	k8s.io/api v0.18.5
 	k8s.io/apimachinery v0.18.5
 	k8s.io/client-go v0.18.5
+	sigs.k8s.io/cluster-api v0.3.7
+	sigs.k8s.io/controller-runtime v0.6.1
+	sigs.k8s.io/yaml v1.2.0
 )
@@ -2,13 +2,17 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/microkit/command"
 	microserver "github.com/giantswarm/microkit/server"
 	"github.com/giantswarm/micrologger"
+	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 
+	"github.com/giantswarm/prometheus-config-controller/command/render"
 	"github.com/giantswarm/prometheus-config-controller/flag"
 	"github.com/giantswarm/prometheus-config-controller/pkg/project"
 	"github.com/giantswarm/prometheus-config-controller/server"
@@ -113,6 +117,8 @@ func mainError() error {
 
 	daemonCommand := newCommand.DaemonCommand().CobraCommand()
 
+	f.AddScrapeConfigFlags(daemonCommand.PersistentFlags())
+
 	daemonCommand.PersistentFlags().String(f.Service.Kubernetes.Address, "http://127.0.0.1:6443", "Address used to connect to Kubernetes. When empty in-cluster config is created.")
 	daemonCommand.PersistentFlags().Bool(f.Service.Kubernetes.InCluster, false, "Whether to use the in-cluster config to authenticate with Kubernetes.")
 	daemonCommand.PersistentFlags().String(f.Service.Kubernetes.KubeConfig, "", "KubeConfig used to connect to Kubernetes. When empty other settings are used.")
@@ -120,20 +126,81 @@ func mainError() error {
 	daemonCommand.PersistentFlags().String(f.Service.Kubernetes.TLS.CrtFile, "", "Certificate file path to use to authenticate with Kubernetes.")
 	daemonCommand.PersistentFlags().String(f.Service.Kubernetes.TLS.KeyFile, "", "Key file path to use to authenticate with Kubernetes.")
 
-	daemonCommand.PersistentFlags().String(f.Service.Prometheus.Address, "http://127.0.0.1:9090", "Address of Prometheus to reload.")
-	daemonCommand.PersistentFlags().String(f.Service.Prometheus.Provider, "", "The name of the provider where Prometheus is running.")
+	daemonCommand.PersistentFlags().Bool(f.Service.LeaderElection.Enabled, false, "Whether to elect a leader among multiple replicas using a Lease. Only the leader runs the controller.")
+	daemonCommand.PersistentFlags().Duration(f.Service.LeaderElection.LeaseDuration, 15*time.Second, "Duration standby replicas wait before taking over the Lease of a leader that stopped renewing it.")
+	daemonCommand.PersistentFlags().String(f.Service.LeaderElection.Name, "prometheus-config-controller", "Name of the Lease used for leader election.")
+	daemonCommand.PersistentFlags().String(f.Service.LeaderElection.Namespace, "monitoring", "Namespace of the Lease used for leader election.")
+	daemonCommand.PersistentFlags().Duration(f.Service.LeaderElection.RenewDeadline, 10*time.Second, "Duration the leader retries renewing the Lease before giving up the leadership.")
+	daemonCommand.PersistentFlags().Duration(f.Service.LeaderElection.RetryPeriod, 2*time.Second, "Duration between attempts to acquire or renew the Lease.")
 
+	daemonCommand.PersistentFlags().String(f.Service.Prometheus.Address, "http://127.0.0.1:9090", "Address of Prometheus to reload.")
+	daemonCommand.PersistentFlags().String(f.Service.Prometheus.ClusterSource, "service", "Source workload clusters are discovered from, either service for master Services or capi for Cluster API Cluster objects.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Prometheus.Readiness.MaxInterval, 30*time.Second, "Maximum interval between two requests checking whether Prometheus is ready.")
+	daemonCommand.PersistentFlags().String(f.Service.Prometheus.Readiness.Strategy, "ready", "How to wait for Prometheus to be ready before running the controller, either ready to wait for /-/ready, config to wait for /api/v1/status/config, or none to not wait.")
+	daemonCommand.PersistentFlags().String(f.Service.Prometheus.Reload.Mode, "address", "Which Prometheus instances to reload, either address for the single Prometheus at the Prometheus address, selector for every ready Pod matching the reload selector, or service for every ready address of the Endpoints of the reload Service.")
+	daemonCommand.PersistentFlags().String(f.Service.Prometheus.Reload.Namespace, "monitoring", "Namespace of the Pods or Service to reload, when the reload mode is selector or service.")
+	daemonCommand.PersistentFlags().Int(f.Service.Prometheus.Reload.Port, 9090, "Port Prometheus listens on in the Pods to reload, when the reload mode is selector or service.")
+	daemonCommand.PersistentFlags().String(f.Service.Prometheus.Reload.Selector, "", "Label selector of the Prometheus Pods to reload, when the reload mode is selector.")
+	daemonCommand.PersistentFlags().String(f.Service.Prometheus.Reload.Service, "", "Name of the Service whose Endpoints are reloaded, when the reload mode is service.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Prometheus.Reload.Verify.Interval, 5*time.Second, "Time waited before reloading a Prometheus instance again when the configuration it loaded does not match the ConfigMap yet.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Prometheus.Reload.Verify.Timeout, 2*time.Minute, "Time after which reloading a Prometheus instance is given up when the configuration it loaded does not match the ConfigMap.")
+
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Backend, "configmap", "Output backend for scrape configs, either configmap to manage the Prometheus configmap, or secret to manage a Prometheus Operator additionalScrapeConfigs secret.")
+	daemonCommand.PersistentFlags().Bool(f.Service.Resource.DryRun, false, "Whether to only compute and log the diff of the Prometheus configmap, without writing it, writing certificates or reloading Prometheus. The diff is served on /diff.")
 	daemonCommand.PersistentFlags().Int(f.Service.Resource.Retries, 3, "Number of times to retry resources.")
 
 	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.ComponentName, "prometheus", "Component name label for certificates.")
-	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Directory, "/certs", "Directory in which to store certificates.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Resource.Certificate.ExpiryWarningWindow, 7*24*time.Hour, "Time before the expiry of a certificate from which on a warning is logged and a Warning Event is recorded.")
 	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Namespace, "default", "Namespace for certificates.")
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Output, "directory", "Where certificates are written to, either directory to write them into the certificate directory shared with Prometheus, or projection to write them into Secrets which Prometheus mounts as a projected volume at the certificate directory.")
 	daemonCommand.PersistentFlags().Int(f.Service.Resource.Certificate.Permission, 0600, "File permission for certificates.")
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Projection.Name, "prometheus-certificates", "Name prefix of the projected certificate Secrets, which are named <name>-<index>, used by the projection output.")
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Projection.Namespace, "monitoring", "Namespace of the projected certificate Secrets, used by the projection output.")
+	daemonCommand.PersistentFlags().Int(f.Service.Resource.Certificate.Projection.Secrets, 4, "Number of projected certificate Secrets certificates are distributed across, each holding up to 1MiB, used by the projection output. All of them are managed, so that Prometheus can project all of them.")
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Source, "legacy", "Source certificates are taken from, either legacy for Secrets labelled with the component name and cluster ID in the certificate namespace, secret for Secrets in a templated namespace, or vault to issue certificates from a Vault PKI.")
+
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Secret.CAKey, "ca.crt", "Data key of certificate Secrets holding the CA, used by the secret certificate source.")
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Secret.CrtKey, "tls.crt", "Data key of certificate Secrets holding the client certificate, used by the secret certificate source.")
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Secret.KeyKey, "tls.key", "Data key of certificate Secrets holding the key, used by the secret certificate source.")
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Secret.NameTemplate, "", "Template of the name of certificate Secrets, e.g. {{ .ClusterID }}-prometheus, used by the secret certificate source. When empty, Secrets are selected with the selector template.")
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Secret.NamespaceTemplate, "{{ .ClusterID }}", "Template of the namespace of certificate Secrets, used by the secret certificate source.")
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Secret.SelectorTemplate, "", "Template of the label selector of certificate Secrets, e.g. giantswarm.io/cluster={{ .ClusterID }}, used by the secret certificate source.")
+
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Vault.Address, "", "Address of Vault, used by the vault certificate source.")
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Vault.CommonNameTemplate, "prometheus.{{ .ClusterID }}", "Template of the common name of certificates issued by Vault.")
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Vault.MountTemplate, "pki-{{ .ClusterID }}", "Template of the mount path of the Vault PKI secrets engine of a cluster.")
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Vault.Role, "", "Vault PKI role certificates are issued with.")
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Certificate.Vault.TokenFile, "", "Path of the file holding the token to authenticate with Vault, read on every request. When empty, the token is taken from the VAULT_TOKEN environment variable.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Resource.Certificate.Vault.TTL, 24*time.Hour, "Requested lifetime of certificates issued by Vault. Certificates are issued again after two thirds of their lifetime.")
 
 	daemonCommand.PersistentFlags().String(f.Service.Resource.ConfigMap.Key, "prometheus.yml", "Key in configmap under which prometheus configuration is held.")
 	daemonCommand.PersistentFlags().String(f.Service.Resource.ConfigMap.Name, "prometheus", "Name of prometheus configmap to control.")
 	daemonCommand.PersistentFlags().String(f.Service.Resource.ConfigMap.Namespace, "monitoring", "Namespace of prometheus configmap to control.")
 
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Secret.Key, "prometheus-additional.yaml", "Key in secret under which additional scrape configs are held.")
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Secret.Name, "prometheus-additional-scrape-configs", "Name of additional scrape configs secret to control.")
+	daemonCommand.PersistentFlags().String(f.Service.Resource.Secret.Namespace, "monitoring", "Namespace of additional scrape configs secret to control.")
+
+	// Create the render command, generating the Prometheus configuration from
+	// manifests the same way the daemon does.
+	var renderCommand *render.Command
+	{
+		c := render.Config{
+			Flag: f,
+			Fs:   afero.NewOsFs(),
+
+			Stdin:  os.Stdin,
+			Stdout: os.Stdout,
+		}
+
+		renderCommand, err = render.New(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	newCommand.CobraCommand().AddCommand(renderCommand.CobraCommand())
+
 	newCommand.CobraCommand().Execute()
 
 	return nil
@@ -5,5 +5,12 @@ const (
 )
 
 const (
-	Cluster = "giantswarm.io/cluster"
+	Cluster  = "giantswarm.io/cluster"
+	Provider = "giantswarm.io/provider"
+)
+
+// Labels of the certificate Secrets of workload clusters.
+const (
+	ClusterComponent = "clusterComponent"
+	ClusterID        = "clusterID"
 )
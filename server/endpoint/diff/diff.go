@@ -0,0 +1,117 @@
+// Package diff implements an endpoint serving the diff between the current
+// and desired Prometheus configuration computed in dry-run mode.
+package diff
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/giantswarm/prometheus-config-controller/service/dryrun"
+)
+
+const (
+	// Method is the HTTP method this endpoint is registered for.
+	Method = "GET"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "diff"
+	// Path is the HTTP request path this endpoint is registered for.
+	Path = "/diff"
+)
+
+type Config struct {
+	Logger  micrologger.Logger
+	Service *dryrun.Service
+}
+
+type Endpoint struct {
+	logger  micrologger.Logger
+	service *dryrun.Service
+}
+
+type response struct {
+	Diff    string
+	Updated time.Time
+}
+
+func New(config Config) (*Endpoint, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Service == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Service must not be empty", config)
+	}
+
+	e := &Endpoint{
+		logger:  config.Logger,
+		service: config.Service,
+	}
+
+	return e, nil
+}
+
+func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		return nil, nil
+	}
+}
+
+func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r interface{}) error {
+		res := r.(response)
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		switch {
+		case !e.service.Enabled():
+			w.WriteHeader(http.StatusNotFound)
+			_, err := fmt.Fprintln(w, "dry-run mode is disabled")
+			return err
+		case res.Updated.IsZero():
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, err := fmt.Fprintln(w, "no diff computed yet")
+			return err
+		}
+
+		w.Header().Set("Last-Modified", res.Updated.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+
+		_, err := fmt.Fprint(w, res.Diff)
+		return err
+	}
+}
+
+func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, r interface{}) (interface{}, error) {
+		diff, updated := e.service.Diff()
+
+		res := response{
+			Diff:    diff,
+			Updated: updated,
+		}
+
+		return res, nil
+	}
+}
+
+func (e *Endpoint) Method() string {
+	return Method
+}
+
+func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
+	return []kitendpoint.Middleware{}
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
@@ -1,11 +1,14 @@
 package endpoint
 
 import (
-	"github.com/giantswarm/microendpoint/endpoint/healthz"
 	"github.com/giantswarm/microendpoint/endpoint/version"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 
+	"github.com/giantswarm/prometheus-config-controller/server/endpoint/diff"
+	"github.com/giantswarm/prometheus-config-controller/server/endpoint/healthz"
+	"github.com/giantswarm/prometheus-config-controller/server/endpoint/readyz"
+	"github.com/giantswarm/prometheus-config-controller/server/endpoint/targets"
 	"github.com/giantswarm/prometheus-config-controller/service"
 )
 
@@ -15,17 +18,34 @@ type Config struct {
 }
 
 type Endpoint struct {
+	Diff    *diff.Endpoint
 	Healthz *healthz.Endpoint
+	Readyz  *readyz.Endpoint
+	Targets *targets.Endpoint
 	Version *version.Endpoint
 }
 
 func New(config Config) (*Endpoint, error) {
 	var err error
 
+	var diffEndpoint *diff.Endpoint
+	{
+		c := diff.Config{
+			Logger:  config.Logger,
+			Service: config.Service.DryRun,
+		}
+
+		diffEndpoint, err = diff.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var healthzEndpoint *healthz.Endpoint
 	{
 		c := healthz.Config{
-			Logger: config.Logger,
+			Logger:  config.Logger,
+			Service: config.Service.Leader,
 		}
 
 		healthzEndpoint, err = healthz.New(c)
@@ -34,6 +54,32 @@ func New(config Config) (*Endpoint, error) {
 		}
 	}
 
+	var readyzEndpoint *readyz.Endpoint
+	{
+		c := readyz.Config{
+			Logger:  config.Logger,
+			Service: config.Service.Readiness,
+		}
+
+		readyzEndpoint, err = readyz.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var targetsEndpoint *targets.Endpoint
+	{
+		c := targets.Config{
+			Logger:  config.Logger,
+			Service: config.Service.Discovery,
+		}
+
+		targetsEndpoint, err = targets.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var versionEndpoint *version.Endpoint
 	{
 		c := version.Config{
@@ -48,7 +94,10 @@ func New(config Config) (*Endpoint, error) {
 	}
 
 	e := &Endpoint{
+		Diff:    diffEndpoint,
 		Healthz: healthzEndpoint,
+		Readyz:  readyzEndpoint,
+		Targets: targetsEndpoint,
 		Version: versionEndpoint,
 	}
 
@@ -0,0 +1,97 @@
+// Package readyz implements the readiness endpoint, reporting whether this
+// replica still waits for Prometheus, waits for leadership, or runs the
+// controller.
+package readyz
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/giantswarm/prometheus-config-controller/service/readiness"
+)
+
+const (
+	// Method is the HTTP method this endpoint is registered for.
+	Method = "GET"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "readyz"
+	// Path is the HTTP request path this endpoint is registered for.
+	Path = "/readyz"
+)
+
+type Config struct {
+	Logger  micrologger.Logger
+	Service *readiness.Service
+}
+
+type Endpoint struct {
+	logger  micrologger.Logger
+	service *readiness.Service
+}
+
+func New(config Config) (*Endpoint, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Service == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Service must not be empty", config)
+	}
+
+	e := &Endpoint{
+		logger:  config.Logger,
+		service: config.Service,
+	}
+
+	return e, nil
+}
+
+func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		return nil, nil
+	}
+}
+
+func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		state := response.(readiness.State)
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		if state.Ready() {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		_, err := fmt.Fprintln(w, state)
+		return err
+	}
+}
+
+func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, r interface{}) (interface{}, error) {
+		return e.service.State(), nil
+	}
+}
+
+func (e *Endpoint) Method() string {
+	return Method
+}
+
+func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
+	return []kitendpoint.Middleware{}
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
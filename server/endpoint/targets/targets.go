@@ -0,0 +1,131 @@
+// Package targets implements an endpoint serving the targets of all workload
+// clusters in the format of the Prometheus HTTP service discovery.
+package targets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+	"github.com/giantswarm/prometheus-config-controller/service/discovery"
+)
+
+const (
+	// Method is the HTTP method this endpoint is registered for.
+	Method = "GET"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "targets"
+	// Path is the HTTP request path this endpoint is registered for.
+	Path = "/targets"
+
+	// clusterIDQueryParameter is the query parameter restricting the served
+	// target groups to a single cluster, e.g. "/targets?cluster_id=xa5ly".
+	clusterIDQueryParameter = "cluster_id"
+	// jobTypeQueryParameter is the query parameter restricting the served
+	// target groups to a single job type, e.g. "/targets?job_type=etcd".
+	jobTypeQueryParameter = "job_type"
+)
+
+type Config struct {
+	Logger  micrologger.Logger
+	Service *discovery.Service
+}
+
+type Endpoint struct {
+	logger  micrologger.Logger
+	service *discovery.Service
+}
+
+type request struct {
+	ClusterID string
+	JobType   string
+}
+
+type response struct {
+	// Synced is false on replicas which did not build a snapshot of the
+	// targets yet, e.g. standby replicas.
+	Synced       bool
+	TargetGroups []prometheus.TargetGroup
+}
+
+func New(config Config) (*Endpoint, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Service == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Service must not be empty", config)
+	}
+
+	e := &Endpoint{
+		logger:  config.Logger,
+		service: config.Service,
+	}
+
+	return e, nil
+}
+
+func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		req := request{
+			ClusterID: r.URL.Query().Get(clusterIDQueryParameter),
+			JobType:   r.URL.Query().Get(jobTypeQueryParameter),
+		}
+
+		return req, nil
+	}
+}
+
+func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r interface{}) error {
+		res := r.(response)
+
+		// Prometheus keeps the targets of its last successful refresh when
+		// a refresh fails, while an empty list would drop all of them.
+		if !res.Synced {
+			http.Error(w, "targets not synced yet", http.StatusServiceUnavailable)
+			return nil
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		return json.NewEncoder(w).Encode(res.TargetGroups)
+	}
+}
+
+func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, r interface{}) (interface{}, error) {
+		req := r.(request)
+
+		targetGroups, synced := e.service.TargetGroups(req.ClusterID, req.JobType)
+
+		res := response{
+			Synced:       synced,
+			TargetGroups: targetGroups,
+		}
+
+		return res, nil
+	}
+}
+
+func (e *Endpoint) Method() string {
+	return Method
+}
+
+func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
+	return []kitendpoint.Middleware{}
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
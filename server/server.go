@@ -77,7 +77,10 @@ func New(config Config) (*Server, error) {
 			Viper:       config.Viper,
 
 			Endpoints: []microserver.Endpoint{
+				endpointCollection.Diff,
 				endpointCollection.Healthz,
+				endpointCollection.Readyz,
+				endpointCollection.Targets,
 				endpointCollection.Version,
 			},
 			ErrorEncoder: errorEncoder,
@@ -0,0 +1,78 @@
+package certificatesource
+
+import (
+	"context"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/inventory"
+)
+
+const (
+	legacyCAKey  = "ca"
+	legacyCrtKey = "crt"
+	legacyKeyKey = "key"
+)
+
+type LegacyConfig struct {
+	Inventory *inventory.Inventory
+
+	// ComponentName is the clusterComponent label value of the certificate
+	// Secrets.
+	ComponentName string
+}
+
+// Legacy looks certificates up as Secrets in the certificate namespace of the
+// inventory, labelled with clusterComponent=<ComponentName> and
+// clusterID=<cluster ID>, holding the ca, crt and key data keys.
+type Legacy struct {
+	inventory *inventory.Inventory
+
+	componentName string
+}
+
+func NewLegacy(config LegacyConfig) (*Legacy, error) {
+	if config.Inventory == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Inventory must not be empty", config)
+	}
+
+	if config.ComponentName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ComponentName must not be empty", config)
+	}
+
+	l := &Legacy{
+		inventory: config.Inventory,
+
+		componentName: config.ComponentName,
+	}
+
+	return l, nil
+}
+
+func (l *Legacy) Certificate(ctx context.Context, clusterID string) (*Certificate, error) {
+	secrets, err := l.inventory.Secrets(l.componentName, clusterID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(secrets) == 0 {
+		return nil, nil
+	}
+	secret := secrets[0]
+
+	c := &Certificate{
+		Name:   secret.Name,
+		Object: &secret,
+
+		CA:  secret.Data[legacyCAKey],
+		Crt: secret.Data[legacyCrtKey],
+		Key: secret.Data[legacyKeyKey],
+	}
+
+	return c, nil
+}
+
+// Prune is a no-op, since the inventory keeps track of deleted Secrets.
+func (l *Legacy) Prune(ctx context.Context, clusterIDs []string) error {
+	return nil
+}
@@ -0,0 +1,259 @@
+package certificatesource
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+type SecretConfig struct {
+	K8sClient kubernetes.Interface
+
+	// CAKey, CrtKey and KeyKey are the data keys of the Secret holding the
+	// CA, client certificate and key, e.g. ca.crt, tls.crt and tls.key for
+	// cert-manager Certificate Secrets.
+	CAKey  string
+	CrtKey string
+	KeyKey string
+	// NameTemplate is the template of the name of the Secret. When empty,
+	// the Secret is selected with SelectorTemplate.
+	NameTemplate string
+	// NamespaceTemplate is the template of the namespace of the Secret, e.g.
+	// "{{ .ClusterID }}".
+	NamespaceTemplate string
+	// SelectorTemplate is the template of the label selector of the Secret,
+	// e.g. "giantswarm.io/cluster={{ .ClusterID }}". Of multiple matching
+	// Secrets, the first one by name is used.
+	SelectorTemplate string
+	// ResyncPeriod is the period the informers resync their cache in.
+	ResyncPeriod time.Duration
+}
+
+// Secret looks certificates up as Secrets, named or selected by templates
+// executed with the cluster ID, in a templated namespace, mapping their data
+// keys onto the CA, client certificate and key.
+//
+// Secrets are served from informers, one per namespace, which are started on
+// the first lookup in their namespace and stopped once no cluster uses their
+// namespace anymore.
+type Secret struct {
+	k8sClient kubernetes.Interface
+
+	caKey        string
+	crtKey       string
+	keyKey       string
+	name         *template.Template
+	namespace    *template.Template
+	resyncPeriod time.Duration
+	selector     *template.Template
+
+	mutex     sync.Mutex
+	informers map[string]*secretInformer
+}
+
+// secretInformer caches the Secrets of a single namespace.
+type secretInformer struct {
+	lister corelisters.SecretNamespaceLister
+	stop   chan struct{}
+}
+
+func NewSecret(config SecretConfig) (*Secret, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+
+	if config.CAKey == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CAKey must not be empty", config)
+	}
+	if config.CrtKey == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CrtKey must not be empty", config)
+	}
+	if config.KeyKey == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.KeyKey must not be empty", config)
+	}
+	if config.NameTemplate == "" && config.SelectorTemplate == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.NameTemplate or %T.SelectorTemplate must not be empty", config, config)
+	}
+
+	var err error
+
+	var name *template.Template
+	if config.NameTemplate != "" {
+		name, err = parseTemplate(fmt.Sprintf("%T.NameTemplate", config), config.NameTemplate)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+	namespace, err := parseTemplate(fmt.Sprintf("%T.NamespaceTemplate", config), config.NamespaceTemplate)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	var selector *template.Template
+	if config.SelectorTemplate != "" {
+		selector, err = parseTemplate(fmt.Sprintf("%T.SelectorTemplate", config), config.SelectorTemplate)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	s := &Secret{
+		k8sClient: config.K8sClient,
+
+		caKey:        config.CAKey,
+		crtKey:       config.CrtKey,
+		keyKey:       config.KeyKey,
+		name:         name,
+		namespace:    namespace,
+		resyncPeriod: config.ResyncPeriod,
+		selector:     selector,
+
+		informers: map[string]*secretInformer{},
+	}
+
+	return s, nil
+}
+
+func (s *Secret) Certificate(ctx context.Context, clusterID string) (*Certificate, error) {
+	secret, err := s.secret(ctx, clusterID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if secret == nil {
+		return nil, nil
+	}
+
+	c := &Certificate{
+		Name:   secret.Name,
+		Object: secret,
+
+		CA:  secret.Data[s.caKey],
+		Crt: secret.Data[s.crtKey],
+		Key: secret.Data[s.keyKey],
+	}
+
+	return c, nil
+}
+
+// Prune stops the informers of namespaces none of the given clusters uses.
+func (s *Secret) Prune(ctx context.Context, clusterIDs []string) error {
+	namespaces := map[string]bool{}
+	for _, clusterID := range clusterIDs {
+		namespace, err := executeTemplate(s.namespace, clusterID)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		namespaces[namespace] = true
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for namespace, informer := range s.informers {
+		if !namespaces[namespace] {
+			close(informer.stop)
+			delete(s.informers, namespace)
+		}
+	}
+
+	return nil
+}
+
+// secret returns the Secret holding the certificate of the given cluster, or
+// nil if it does not exist.
+func (s *Secret) secret(ctx context.Context, clusterID string) (*v1.Secret, error) {
+	namespace, err := executeTemplate(s.namespace, clusterID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	lister, err := s.lister(ctx, namespace)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if s.name != nil {
+		name, err := executeTemplate(s.name, clusterID)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		secret, err := lister.Get(name)
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return secret.DeepCopy(), nil
+	}
+
+	selector, err := executeTemplate(s.selector, clusterID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	labelSelector, err := labels.Parse(selector)
+	if err != nil {
+		return nil, microerror.Maskf(executionFailedError, "failed to parse label selector %#q: %s", selector, err)
+	}
+
+	list, err := lister.List(labelSelector)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list[0].DeepCopy(), nil
+}
+
+// lister returns the lister of the Secrets of the given namespace, starting
+// its informer and waiting for its cache to sync on first use.
+func (s *Secret) lister(ctx context.Context, namespace string) (corelisters.SecretNamespaceLister, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if informer, ok := s.informers[namespace]; ok {
+		return informer.lister, nil
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(
+		s.k8sClient,
+		s.resyncPeriod,
+		informers.WithNamespace(namespace),
+	)
+	secrets := factory.Core().V1().Secrets()
+	informer := secrets.Informer()
+
+	stop := make(chan struct{})
+	factory.Start(stop)
+
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		close(stop)
+		return nil, microerror.Maskf(executionFailedError, "Secret cache of namespace %#q did not sync", namespace)
+	}
+
+	s.informers[namespace] = &secretInformer{
+		lister: secrets.Lister().Secrets(namespace),
+		stop:   stop,
+	}
+
+	return s.informers[namespace].lister, nil
+}
@@ -0,0 +1,262 @@
+package certificatesource
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newCertManagerSecret(name, namespace, clusterID string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"giantswarm.io/cluster": clusterID,
+			},
+		},
+		Data: map[string][]byte{
+			"ca.crt":  []byte(name + "-ca"),
+			"tls.crt": []byte(name + "-crt"),
+			"tls.key": []byte(name + "-key"),
+		},
+	}
+}
+
+// Test_CertificateSource_Secret tests the Certificate method of the Secret
+// certificate source.
+func Test_CertificateSource_Secret(t *testing.T) {
+	tests := []struct {
+		objects []runtime.Object
+		config  SecretConfig
+
+		expectedCertificate *Certificate
+	}{
+		// Test that the Secret is selected in the cluster namespace, and its
+		// data keys are mapped onto the certificate.
+		{
+			objects: []runtime.Object{
+				newCertManagerSecret("xa5ly-prometheus", "xa5ly", "xa5ly"),
+				newCertManagerSecret("al9qy-prometheus", "al9qy", "al9qy"),
+			},
+			config: SecretConfig{
+				NamespaceTemplate: "{{ .ClusterID }}",
+				SelectorTemplate:  "giantswarm.io/cluster={{ .ClusterID }}",
+			},
+
+			expectedCertificate: &Certificate{
+				Name: "xa5ly-prometheus",
+				CA:   []byte("xa5ly-prometheus-ca"),
+				Crt:  []byte("xa5ly-prometheus-crt"),
+				Key:  []byte("xa5ly-prometheus-key"),
+			},
+		},
+
+		// Test that of multiple matching Secrets the first one by name is
+		// used.
+		{
+			objects: []runtime.Object{
+				newCertManagerSecret("xa5ly-prometheus-b", "org-acme", "xa5ly"),
+				newCertManagerSecret("xa5ly-prometheus-a", "org-acme", "xa5ly"),
+			},
+			config: SecretConfig{
+				NamespaceTemplate: "org-acme",
+				SelectorTemplate:  "giantswarm.io/cluster={{ .ClusterID }}",
+			},
+
+			expectedCertificate: &Certificate{
+				Name: "xa5ly-prometheus-a",
+				CA:   []byte("xa5ly-prometheus-a-ca"),
+				Crt:  []byte("xa5ly-prometheus-a-crt"),
+				Key:  []byte("xa5ly-prometheus-a-key"),
+			},
+		},
+
+		// Test that the Secret is looked up by its templated name.
+		{
+			objects: []runtime.Object{
+				newCertManagerSecret("xa5ly-prometheus", "xa5ly", "other"),
+			},
+			config: SecretConfig{
+				NameTemplate:      "{{ .ClusterID }}-prometheus",
+				NamespaceTemplate: "{{ .ClusterID }}",
+			},
+
+			expectedCertificate: &Certificate{
+				Name: "xa5ly-prometheus",
+				CA:   []byte("xa5ly-prometheus-ca"),
+				Crt:  []byte("xa5ly-prometheus-crt"),
+				Key:  []byte("xa5ly-prometheus-key"),
+			},
+		},
+
+		// Test that no certificate is returned when the named Secret does
+		// not exist.
+		{
+			objects: nil,
+			config: SecretConfig{
+				NameTemplate:      "{{ .ClusterID }}-prometheus",
+				NamespaceTemplate: "{{ .ClusterID }}",
+			},
+
+			expectedCertificate: nil,
+		},
+
+		// Test that no certificate is returned when no Secret matches the
+		// selector.
+		{
+			objects: []runtime.Object{
+				newCertManagerSecret("al9qy-prometheus", "xa5ly", "al9qy"),
+			},
+			config: SecretConfig{
+				NamespaceTemplate: "{{ .ClusterID }}",
+				SelectorTemplate:  "giantswarm.io/cluster={{ .ClusterID }}",
+			},
+
+			expectedCertificate: nil,
+		},
+	}
+
+	for index, test := range tests {
+		test.config.K8sClient = fake.NewSimpleClientset(test.objects...)
+		test.config.CAKey = "ca.crt"
+		test.config.CrtKey = "tls.crt"
+		test.config.KeyKey = "tls.key"
+
+		s, err := NewSecret(test.config)
+		if err != nil {
+			t.Fatalf("%d: error returned creating certificate source: %s\n", index, err)
+		}
+
+		certificate, err := s.Certificate(context.TODO(), "xa5ly")
+		if err != nil {
+			t.Fatalf("%d: error returned getting certificate: %s\n", index, err)
+		}
+
+		if test.expectedCertificate == nil {
+			if certificate != nil {
+				t.Fatalf("%d: expected no certificate, got %#q", index, certificate.Name)
+			}
+			continue
+		}
+		if certificate == nil {
+			t.Fatalf("%d: expected certificate %#q, got none", index, test.expectedCertificate.Name)
+		}
+
+		if certificate.Name != test.expectedCertificate.Name {
+			t.Fatalf("%d: expected certificate %#q, got %#q", index, test.expectedCertificate.Name, certificate.Name)
+		}
+		if certificate.Object == nil {
+			t.Fatalf("%d: expected certificate to reference its Secret", index)
+		}
+		if string(certificate.CA) != string(test.expectedCertificate.CA) || string(certificate.Crt) != string(test.expectedCertificate.Crt) || string(certificate.Key) != string(test.expectedCertificate.Key) {
+			t.Fatalf("%d: expected certificate data %q, %q, %q, got %q, %q, %q", index, test.expectedCertificate.CA, test.expectedCertificate.Crt, test.expectedCertificate.Key, certificate.CA, certificate.Crt, certificate.Key)
+		}
+	}
+}
+
+// Test_CertificateSource_Secret_Prune tests that the informers of namespaces
+// no cluster uses anymore are stopped.
+func Test_CertificateSource_Secret_Prune(t *testing.T) {
+	s, err := NewSecret(SecretConfig{
+		K8sClient: fake.NewSimpleClientset(
+			newCertManagerSecret("xa5ly-prometheus", "xa5ly", "xa5ly"),
+			newCertManagerSecret("al9qy-prometheus", "al9qy", "al9qy"),
+		),
+
+		CAKey:             "ca.crt",
+		CrtKey:            "tls.crt",
+		KeyKey:            "tls.key",
+		NamespaceTemplate: "{{ .ClusterID }}",
+		SelectorTemplate:  "giantswarm.io/cluster={{ .ClusterID }}",
+	})
+	if err != nil {
+		t.Fatalf("error returned creating certificate source: %s\n", err)
+	}
+
+	for _, clusterID := range []string{"xa5ly", "al9qy"} {
+		_, err = s.Certificate(context.TODO(), clusterID)
+		if err != nil {
+			t.Fatalf("error returned getting certificate: %s\n", err)
+		}
+	}
+	if len(s.informers) != 2 {
+		t.Fatalf("expected %d informers, got %d", 2, len(s.informers))
+	}
+
+	err = s.Prune(context.TODO(), []string{"al9qy"})
+	if err != nil {
+		t.Fatalf("error returned pruning: %s\n", err)
+	}
+	if _, ok := s.informers["al9qy"]; !ok || len(s.informers) != 1 {
+		t.Fatalf("expected only informer of namespace %#q, got %d informers", "al9qy", len(s.informers))
+	}
+}
+
+// Test_CertificateSource_NewSecret tests the NewSecret function.
+func Test_CertificateSource_NewSecret(t *testing.T) {
+	tests := []struct {
+		config SecretConfig
+
+		expectedErrorHandler func(error) bool
+	}{
+		// Test that a valid config creates the certificate source.
+		{
+			config: SecretConfig{
+				K8sClient:         fake.NewSimpleClientset(),
+				CAKey:             "ca.crt",
+				CrtKey:            "tls.crt",
+				KeyKey:            "tls.key",
+				NamespaceTemplate: "{{ .ClusterID }}",
+				SelectorTemplate:  "giantswarm.io/cluster={{ .ClusterID }}",
+			},
+
+			expectedErrorHandler: nil,
+		},
+
+		// Test that either the name or the selector template must be set.
+		{
+			config: SecretConfig{
+				K8sClient:         fake.NewSimpleClientset(),
+				CAKey:             "ca.crt",
+				CrtKey:            "tls.crt",
+				KeyKey:            "tls.key",
+				NamespaceTemplate: "{{ .ClusterID }}",
+			},
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// Test that templates must parse.
+		{
+			config: SecretConfig{
+				K8sClient:         fake.NewSimpleClientset(),
+				CAKey:             "ca.crt",
+				CrtKey:            "tls.crt",
+				KeyKey:            "tls.key",
+				NamespaceTemplate: "{{ .ClusterID",
+				SelectorTemplate:  "giantswarm.io/cluster={{ .ClusterID }}",
+			},
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+	}
+
+	for index, test := range tests {
+		_, err := NewSecret(test.config)
+
+		if err != nil && test.expectedErrorHandler == nil {
+			t.Fatalf("%d: unexpected error returned creating certificate source: %s\n", index, err)
+		}
+		if err != nil && !test.expectedErrorHandler(err) {
+			t.Fatalf("%d: incorrect error returned creating certificate source: %s\n", index, err)
+		}
+		if err == nil && test.expectedErrorHandler != nil {
+			t.Fatalf("%d: expected error not returned creating certificate source\n", index)
+		}
+	}
+}
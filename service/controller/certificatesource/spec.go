@@ -0,0 +1,48 @@
+// Package certificatesource provides the CA, client certificate and key used
+// to scrape workload clusters. Certificates are looked up in different
+// layouts of Secrets, or issued by a Vault PKI.
+package certificatesource
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// KindLegacy looks certificates up as Secrets in a single namespace,
+	// labelled with the cluster component and cluster ID, holding the ca,
+	// crt and key data keys.
+	KindLegacy = "legacy"
+	// KindSecret looks certificates up as Secrets in a templated namespace,
+	// e.g. cert-manager Certificate Secrets in the cluster namespace.
+	KindSecret = "secret"
+	// KindVault issues certificates from a Vault PKI secrets engine.
+	KindVault = "vault"
+)
+
+// Certificate is the CA, client certificate and key of a workload cluster, all
+// PEM encoded. Missing parts are nil.
+type Certificate struct {
+	// Name identifies the certificate in logs and Events, e.g. by the name
+	// of the Secret holding it.
+	Name string
+	// Object is the object holding the certificate, which Events about the
+	// certificate are recorded on. It is nil for certificates not held by
+	// Kubernetes objects.
+	Object runtime.Object
+
+	CA  []byte
+	Crt []byte
+	Key []byte
+}
+
+// Interface is a source of workload cluster certificates.
+type Interface interface {
+	// Certificate returns the certificate of the given cluster, or nil if the
+	// cluster has no certificate yet.
+	Certificate(ctx context.Context, clusterID string) (*Certificate, error)
+	// Prune drops whatever the source keeps for clusters other than the
+	// given ones, e.g. issued certificates of deleted clusters.
+	Prune(ctx context.Context, clusterIDs []string) error
+}
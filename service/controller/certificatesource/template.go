@@ -0,0 +1,39 @@
+package certificatesource
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/giantswarm/microerror"
+)
+
+// templateData is the data templates of certificate sources are executed
+// with, e.g. "{{ .ClusterID }}".
+type templateData struct {
+	ClusterID string
+}
+
+// parseTemplate parses the given template, which must not be empty.
+func parseTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%s must not be empty", name)
+	}
+
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "%s must be a valid template: %s", name, err)
+	}
+
+	return t, nil
+}
+
+// executeTemplate executes the given template for the given cluster.
+func executeTemplate(t *template.Template, clusterID string) (string, error) {
+	var b bytes.Buffer
+	err := t.Execute(&b, templateData{ClusterID: clusterID})
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,280 @@
+package certificatesource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/afero"
+)
+
+const (
+	// vaultRenewFraction is the fraction of the lifetime of an issued
+	// certificate after which a new certificate is issued.
+	vaultRenewFraction = 2.0 / 3.0
+)
+
+type VaultConfig struct {
+	// Fs is the file system TokenFile is read from. It defaults to the OS
+	// file system.
+	Fs afero.Fs
+	// HTTPClient is the client requests to Vault are sent with. It defaults
+	// to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Address is the address of Vault, e.g. "https://vault:8200".
+	Address string
+	// CommonNameTemplate is the template of the common name of issued
+	// certificates.
+	CommonNameTemplate string
+	// MountTemplate is the template of the mount path of the PKI secrets
+	// engine, e.g. "pki-{{ .ClusterID }}".
+	MountTemplate string
+	// Role is the PKI role certificates are issued with.
+	Role string
+	// Token is the token to authenticate with Vault, used when TokenFile is
+	// empty.
+	Token string
+	// TokenFile is the path of the file holding the token to authenticate
+	// with Vault. It is read on every request, so that rotated tokens are
+	// picked up.
+	TokenFile string
+	// TTL is the requested lifetime of issued certificates.
+	TTL time.Duration
+}
+
+// Vault issues certificates from a Vault PKI secrets engine, mounted per
+// cluster. Issued certificates are kept in memory and issued again once two
+// thirds of their lifetime passed. Clusters whose mount does not exist have
+// no certificate.
+type Vault struct {
+	fs         afero.Fs
+	httpClient *http.Client
+
+	address    string
+	commonName *template.Template
+	mount      *template.Template
+	role       string
+	token      string
+	tokenFile  string
+	ttl        time.Duration
+
+	mutex  sync.Mutex
+	issued map[string]vaultCertificate
+}
+
+// vaultCertificate is a certificate issued by Vault.
+type vaultCertificate struct {
+	certificate Certificate
+	issuedAt    time.Time
+	expiresAt   time.Time
+}
+
+// vaultIssueResponse is the response of the issue endpoint of the PKI secrets
+// engine.
+type vaultIssueResponse struct {
+	Data struct {
+		Certificate string   `json:"certificate"`
+		IssuingCA   string   `json:"issuing_ca"`
+		CAChain     []string `json:"ca_chain"`
+		PrivateKey  string   `json:"private_key"`
+		Expiration  int64    `json:"expiration"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+func NewVault(config VaultConfig) (*Vault, error) {
+	if config.Fs == nil {
+		config.Fs = afero.NewOsFs()
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+
+	if config.Address == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Address must not be empty", config)
+	}
+	if config.Role == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Role must not be empty", config)
+	}
+	if config.Token == "" && config.TokenFile == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Token or %T.TokenFile must not be empty", config, config)
+	}
+	if config.TTL <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.TTL must be positive", config)
+	}
+
+	commonName, err := parseTemplate(fmt.Sprintf("%T.CommonNameTemplate", config), config.CommonNameTemplate)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	mount, err := parseTemplate(fmt.Sprintf("%T.MountTemplate", config), config.MountTemplate)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	v := &Vault{
+		fs:         config.Fs,
+		httpClient: config.HTTPClient,
+
+		address:    strings.TrimSuffix(config.Address, "/"),
+		commonName: commonName,
+		mount:      mount,
+		role:       config.Role,
+		token:      config.Token,
+		tokenFile:  config.TokenFile,
+		ttl:        config.TTL,
+
+		issued: map[string]vaultCertificate{},
+	}
+
+	return v, nil
+}
+
+func (v *Vault) Certificate(ctx context.Context, clusterID string) (*Certificate, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	now := time.Now()
+
+	if issued, ok := v.issued[clusterID]; ok {
+		renewAt := issued.issuedAt.Add(time.Duration(float64(issued.expiresAt.Sub(issued.issuedAt)) * vaultRenewFraction))
+		if now.Before(renewAt) {
+			c := issued.certificate
+			return &c, nil
+		}
+	}
+
+	issued, err := v.issue(ctx, clusterID, now)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if issued == nil {
+		delete(v.issued, clusterID)
+		return nil, nil
+	}
+
+	v.issued[clusterID] = *issued
+
+	c := issued.certificate
+	return &c, nil
+}
+
+// Prune drops the issued certificates of clusters other than the given ones.
+func (v *Vault) Prune(ctx context.Context, clusterIDs []string) error {
+	keep := map[string]bool{}
+	for _, clusterID := range clusterIDs {
+		keep[clusterID] = true
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for clusterID := range v.issued {
+		if !keep[clusterID] {
+			delete(v.issued, clusterID)
+		}
+	}
+
+	return nil
+}
+
+// getToken returns the token to authenticate with Vault, read from the token
+// file if one is configured.
+func (v *Vault) getToken() (string, error) {
+	if v.tokenFile == "" {
+		return v.token, nil
+	}
+
+	b, err := afero.ReadFile(v.fs, v.tokenFile)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", microerror.Maskf(executionFailedError, "token file %#q is empty", v.tokenFile)
+	}
+
+	return token, nil
+}
+
+// issue issues a certificate for the given cluster. It returns nil if the
+// mount of the cluster does not exist.
+func (v *Vault) issue(ctx context.Context, clusterID string, now time.Time) (*vaultCertificate, error) {
+	mount, err := executeTemplate(v.mount, clusterID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	commonName, err := executeTemplate(v.commonName, clusterID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	token, err := v.getToken()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"common_name": commonName,
+		"ttl":         v.ttl.String(),
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	u := fmt.Sprintf("%s/v1/%s/issue/%s", v.address, strings.Trim(mount, "/"), v.role)
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", token)
+
+	res, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	var issueResponse vaultIssueResponse
+	err = json.NewDecoder(res.Body).Decode(&issueResponse)
+	if err != nil {
+		return nil, microerror.Maskf(executionFailedError, "failed to decode response of %#q with status %d: %s", u, res.StatusCode, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, microerror.Maskf(executionFailedError, "failed to issue certificate at %#q with status %d: %s", u, res.StatusCode, strings.Join(issueResponse.Errors, ", "))
+	}
+
+	ca := issueResponse.Data.IssuingCA
+	if len(issueResponse.Data.CAChain) > 0 {
+		ca = strings.Join(issueResponse.Data.CAChain, "\n")
+	}
+
+	issued := &vaultCertificate{
+		certificate: Certificate{
+			Name: commonName,
+
+			CA:  []byte(ca),
+			Crt: []byte(issueResponse.Data.Certificate),
+			Key: []byte(issueResponse.Data.PrivateKey),
+		},
+		issuedAt:  now,
+		expiresAt: time.Unix(issueResponse.Data.Expiration, 0),
+	}
+
+	return issued, nil
+}
@@ -0,0 +1,217 @@
+package certificatesource
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// fakeVault is a local stand-in of the issue endpoint of Vault PKI secrets
+// engines, mounted at pki-xa5ly.
+type fakeVault struct {
+	issued    int
+	lifetime  time.Duration
+	lastBody  map[string]string
+	lastToken string
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/v1/pki-xa5ly/issue/prometheus" {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+		return
+	}
+
+	f.lastToken = r.Header.Get("X-Vault-Token")
+	_ = json.NewDecoder(r.Body).Decode(&f.lastBody)
+	f.issued++
+
+	var res vaultIssueResponse
+	res.Data.Certificate = "crt"
+	res.Data.IssuingCA = "ca"
+	res.Data.PrivateKey = "key"
+	res.Data.Expiration = time.Now().Add(f.lifetime).Unix()
+
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// Test_CertificateSource_Vault tests the Certificate method of the Vault
+// certificate source.
+func Test_CertificateSource_Vault(t *testing.T) {
+	tests := []struct {
+		clusterID string
+		lifetime  time.Duration
+
+		expectedCertificate bool
+		expectedIssued      int
+	}{
+		// Test that a certificate is issued once and kept while it is fresh.
+		{
+			clusterID: "xa5ly",
+			lifetime:  time.Hour,
+
+			expectedCertificate: true,
+			expectedIssued:      1,
+		},
+
+		// Test that a certificate past two thirds of its lifetime is issued
+		// again.
+		{
+			clusterID: "xa5ly",
+			lifetime:  0,
+
+			expectedCertificate: true,
+			expectedIssued:      2,
+		},
+
+		// Test that clusters without PKI mount have no certificate.
+		{
+			clusterID: "al9qy",
+			lifetime:  time.Hour,
+
+			expectedCertificate: false,
+			expectedIssued:      0,
+		},
+	}
+
+	for index, test := range tests {
+		f := &fakeVault{lifetime: test.lifetime}
+		server := httptest.NewServer(f)
+
+		v, err := NewVault(VaultConfig{
+			Address:            server.URL,
+			CommonNameTemplate: "prometheus.{{ .ClusterID }}",
+			MountTemplate:      "pki-{{ .ClusterID }}",
+			Role:               "prometheus",
+			Token:              "token",
+			TTL:                time.Hour,
+		})
+		if err != nil {
+			t.Fatalf("%d: error returned creating certificate source: %s\n", index, err)
+		}
+
+		var certificate *Certificate
+		for i := 0; i < 2; i++ {
+			certificate, err = v.Certificate(context.TODO(), test.clusterID)
+			if err != nil {
+				t.Fatalf("%d: error returned getting certificate: %s\n", index, err)
+			}
+		}
+		server.Close()
+
+		if test.expectedCertificate && certificate == nil {
+			t.Fatalf("%d: expected certificate, got none", index)
+		}
+		if !test.expectedCertificate && certificate != nil {
+			t.Fatalf("%d: expected no certificate, got %#q", index, certificate.Name)
+		}
+		if f.issued != test.expectedIssued {
+			t.Fatalf("%d: expected %d issued certificates, got %d", index, test.expectedIssued, f.issued)
+		}
+
+		if certificate != nil {
+			if certificate.Name != "prometheus.xa5ly" || string(certificate.CA) != "ca" || string(certificate.Crt) != "crt" || string(certificate.Key) != "key" {
+				t.Fatalf("%d: unexpected certificate %#v", index, certificate)
+			}
+			if f.lastToken != "token" {
+				t.Fatalf("%d: expected token %#q, got %#q", index, "token", f.lastToken)
+			}
+			if f.lastBody["common_name"] != "prometheus.xa5ly" || f.lastBody["ttl"] != "1h0m0s" {
+				t.Fatalf("%d: unexpected request %#v", index, f.lastBody)
+			}
+		}
+	}
+}
+
+// Test_CertificateSource_Vault_TokenFile tests that the token is read from the
+// token file on every request, so that rotated tokens are picked up.
+func Test_CertificateSource_Vault_TokenFile(t *testing.T) {
+	f := &fakeVault{lifetime: 0}
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+
+	v, err := NewVault(VaultConfig{
+		Fs: fs,
+
+		Address:            server.URL,
+		CommonNameTemplate: "prometheus.{{ .ClusterID }}",
+		MountTemplate:      "pki-{{ .ClusterID }}",
+		Role:               "prometheus",
+		TokenFile:          "/vault/token",
+		TTL:                time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("error returned creating certificate source: %s\n", err)
+	}
+
+	_, err = v.Certificate(context.TODO(), "xa5ly")
+	if err == nil {
+		t.Fatalf("expected error for missing token file, got none")
+	}
+
+	for _, token := range []string{"token-a", "token-b"} {
+		err = afero.WriteFile(fs, "/vault/token", []byte(token+"\n"), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = v.Certificate(context.TODO(), "xa5ly")
+		if err != nil {
+			t.Fatalf("error returned getting certificate: %s\n", err)
+		}
+		if f.lastToken != token {
+			t.Fatalf("expected token %#q, got %#q", token, f.lastToken)
+		}
+	}
+}
+
+// Test_CertificateSource_Vault_Prune tests that certificates of pruned
+// clusters are issued again.
+func Test_CertificateSource_Vault_Prune(t *testing.T) {
+	f := &fakeVault{lifetime: time.Hour}
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	v, err := NewVault(VaultConfig{
+		Address:            server.URL,
+		CommonNameTemplate: "prometheus.{{ .ClusterID }}",
+		MountTemplate:      "pki-{{ .ClusterID }}",
+		Role:               "prometheus",
+		Token:              "token",
+		TTL:                time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("error returned creating certificate source: %s\n", err)
+	}
+
+	steps := []struct {
+		keep []string
+
+		expectedIssued int
+	}{
+		{keep: []string{"xa5ly", "al9qy"}, expectedIssued: 1},
+		{keep: []string{"al9qy"}, expectedIssued: 2},
+	}
+
+	for index, step := range steps {
+		err = v.Prune(context.TODO(), step.keep)
+		if err != nil {
+			t.Fatalf("%d: error returned pruning: %s\n", index, err)
+		}
+
+		_, err = v.Certificate(context.TODO(), "xa5ly")
+		if err != nil {
+			t.Fatalf("%d: error returned getting certificate: %s\n", index, err)
+		}
+		if f.issued != step.expectedIssued {
+			t.Fatalf("%d: expected %d issued certificates, got %d", index, step.expectedIssued, f.issued)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package clustersource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	capiv1alpha3 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/prometheus-config-controller/pkg/label"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+)
+
+type CAPIConfig struct {
+	CtrlClient client.Client
+}
+
+// CAPI discovers clusters from Cluster API Cluster objects. The cluster ID is
+// taken from the giantswarm.io/cluster label, falling back to the object's
+// namespace and name joined with a dash, e.g. "org-acme-prod", so that
+// Clusters of the same name in different namespaces get distinct IDs. The API
+// server address is taken from spec.controlPlaneEndpoint. Unless set on the
+// Cluster, the provider is derived from the kind of spec.infrastructureRef,
+// e.g. "aws" for AWSCluster. Clusters without control plane endpoint are not
+// yet provisioned and skipped.
+type CAPI struct {
+	ctrlClient client.Client
+}
+
+func NewCAPI(config CAPIConfig) (*CAPI, error) {
+	if config.CtrlClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlClient must not be empty", config)
+	}
+
+	c := &CAPI{
+		ctrlClient: config.CtrlClient,
+	}
+
+	return c, nil
+}
+
+func (c *CAPI) Clusters(ctx context.Context) ([]v1.Service, error) {
+	var list capiv1alpha3.ClusterList
+	err := c.ctrlClient.List(ctx, &list)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var services []v1.Service
+	for _, cluster := range list.Items {
+		if cluster.Spec.ControlPlaneEndpoint.Host == "" {
+			continue
+		}
+
+		services = append(services, newService(cluster))
+	}
+
+	return services, nil
+}
+
+func (c *CAPI) NewRuntimeObject() runtime.Object {
+	return new(capiv1alpha3.Cluster)
+}
+
+func (c *CAPI) Selector() labels.Selector {
+	return labels.Everything()
+}
+
+// newService returns the Service representing the given Cluster. The Service
+// is named after the Cluster and placed in the Cluster's namespace, so that
+// Events about it are recorded in a namespace which exists.
+func newService(cluster capiv1alpha3.Cluster) v1.Service {
+	clusterID := fmt.Sprintf("%s-%s", cluster.GetNamespace(), cluster.GetName())
+	if id, ok := cluster.GetLabels()[label.Cluster]; ok && id != "" {
+		clusterID = id
+	}
+
+	annotations := map[string]string{}
+	for k, v := range cluster.GetAnnotations() {
+		annotations[k] = v
+	}
+	annotations[prometheus.ClusterAnnotation] = clusterID
+	if _, ok := annotations[prometheus.ProviderAnnotation]; !ok && cluster.GetLabels()[label.Provider] == "" {
+		if provider := getProvider(cluster); provider != "" {
+			annotations[prometheus.ProviderAnnotation] = provider
+		}
+	}
+	annotations[prometheus.APIEndpointAnnotation] = cluster.Spec.ControlPlaneEndpoint.Host
+	if cluster.Spec.ControlPlaneEndpoint.Port != 0 {
+		annotations[prometheus.APIEndpointAnnotation] = fmt.Sprintf("%s:%d", cluster.Spec.ControlPlaneEndpoint.Host, cluster.Spec.ControlPlaneEndpoint.Port)
+	}
+
+	return v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              cluster.GetName(),
+			Namespace:         cluster.GetNamespace(),
+			Labels:            cluster.GetLabels(),
+			Annotations:       annotations,
+			CreationTimestamp: cluster.GetCreationTimestamp(),
+			DeletionTimestamp: cluster.GetDeletionTimestamp(),
+		},
+	}
+}
+
+// getProvider returns the provider of the given Cluster, derived from the kind
+// of its infrastructure reference.
+func getProvider(cluster capiv1alpha3.Cluster) string {
+	if cluster.Spec.InfrastructureRef == nil {
+		return ""
+	}
+
+	return strings.ToLower(strings.TrimSuffix(cluster.Spec.InfrastructureRef.Kind, "Cluster"))
+}
@@ -0,0 +1,106 @@
+package clustersource
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiv1alpha3 "sigs.k8s.io/cluster-api/api/v1alpha3"
+
+	"github.com/giantswarm/prometheus-config-controller/pkg/label"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+)
+
+// Test_ClusterSource_newService tests the newService function.
+func Test_ClusterSource_newService(t *testing.T) {
+	tests := []struct {
+		cluster capiv1alpha3.Cluster
+
+		expectedClusterID   string
+		expectedAPIEndpoint string
+		expectedProvider    string
+	}{
+		// 0. Test that the cluster ID is taken from the object namespace and
+		// name.
+		{
+			cluster: capiv1alpha3.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "xa5ly",
+					Namespace: "org-acme",
+				},
+				Spec: capiv1alpha3.ClusterSpec{
+					ControlPlaneEndpoint: capiv1alpha3.APIEndpoint{
+						Host: "api.xa5ly.example.com",
+						Port: 443,
+					},
+					InfrastructureRef: &corev1.ObjectReference{
+						Kind: "AzureCluster",
+					},
+				},
+			},
+
+			expectedClusterID:   "org-acme-xa5ly",
+			expectedAPIEndpoint: "api.xa5ly.example.com:443",
+			expectedProvider:    "azure",
+		},
+
+		// 1. Test that the cluster ID is taken from the cluster label when set.
+		{
+			cluster: capiv1alpha3.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "acme-prod",
+					Namespace: "org-acme",
+					Labels: map[string]string{
+						label.Cluster: "0ba9v",
+					},
+				},
+				Spec: capiv1alpha3.ClusterSpec{
+					ControlPlaneEndpoint: capiv1alpha3.APIEndpoint{
+						Host: "api.0ba9v.example.com",
+					},
+				},
+			},
+
+			expectedClusterID:   "0ba9v",
+			expectedAPIEndpoint: "api.0ba9v.example.com",
+			expectedProvider:    "",
+		},
+
+		// 2. Test that Clusters of the same name in different namespaces get
+		// distinct cluster IDs.
+		{
+			cluster: capiv1alpha3.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "xa5ly",
+					Namespace: "org-example",
+				},
+				Spec: capiv1alpha3.ClusterSpec{
+					ControlPlaneEndpoint: capiv1alpha3.APIEndpoint{
+						Host: "api.xa5ly.example.org",
+					},
+				},
+			},
+
+			expectedClusterID:   "org-example-xa5ly",
+			expectedAPIEndpoint: "api.xa5ly.example.org",
+			expectedProvider:    "",
+		},
+	}
+
+	for index, test := range tests {
+		service := newService(test.cluster)
+
+		if prometheus.GetClusterID(service) != test.expectedClusterID {
+			t.Fatalf("%d: expected cluster ID %#q, got %#q\n", index, test.expectedClusterID, prometheus.GetClusterID(service))
+		}
+		if service.Namespace != test.cluster.Namespace {
+			t.Fatalf("%d: expected namespace %#q, got %#q\n", index, test.cluster.Namespace, service.Namespace)
+		}
+		if service.Annotations[prometheus.APIEndpointAnnotation] != test.expectedAPIEndpoint {
+			t.Fatalf("%d: expected API endpoint %#q, got %#q\n", index, test.expectedAPIEndpoint, service.Annotations[prometheus.APIEndpointAnnotation])
+		}
+		if service.Annotations[prometheus.ProviderAnnotation] != test.expectedProvider {
+			t.Fatalf("%d: expected provider %#q, got %#q\n", index, test.expectedProvider, service.Annotations[prometheus.ProviderAnnotation])
+		}
+	}
+}
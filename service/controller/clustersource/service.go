@@ -0,0 +1,52 @@
+package clustersource
+
+import (
+	"context"
+
+	"github.com/giantswarm/microerror"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/inventory"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+)
+
+type ServiceConfig struct {
+	Inventory *inventory.Inventory
+}
+
+// Service discovers clusters from master Services annotated with
+// prometheus.ClusterAnnotation, read from the inventory cache.
+type Service struct {
+	inventory *inventory.Inventory
+}
+
+func NewService(config ServiceConfig) (*Service, error) {
+	if config.Inventory == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Inventory must not be empty", config)
+	}
+
+	s := &Service{
+		inventory: config.Inventory,
+	}
+
+	return s, nil
+}
+
+func (s *Service) Clusters(ctx context.Context) ([]v1.Service, error) {
+	services, err := s.inventory.Services()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return services, nil
+}
+
+func (s *Service) NewRuntimeObject() runtime.Object {
+	return new(v1.Service)
+}
+
+func (s *Service) Selector() labels.Selector {
+	return key.LabelSelectorService()
+}
@@ -0,0 +1,34 @@
+// Package clustersource provides the workload clusters to generate scrape
+// configs for. Clusters are discovered from different kinds of objects, which
+// are all represented as Services understood by the scrape config generator.
+package clustersource
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// KindService discovers clusters from master Services.
+	KindService = "service"
+	// KindCAPI discovers clusters from Cluster API Cluster objects.
+	KindCAPI = "capi"
+)
+
+// Interface is a source of workload clusters.
+type Interface interface {
+	// Clusters returns all workload clusters as Services, holding the
+	// cluster ID in prometheus.ClusterAnnotation. Clusters whose API server
+	// is not reachable through the Service's name and namespace hold its
+	// address in prometheus.APIEndpointAnnotation.
+	Clusters(ctx context.Context) ([]v1.Service, error)
+	// NewRuntimeObject returns a new object of the kind clusters are
+	// discovered from, to be watched by the controller.
+	NewRuntimeObject() runtime.Object
+	// Selector returns the label selector matching the objects clusters are
+	// discovered from.
+	Selector() labels.Selector
+}
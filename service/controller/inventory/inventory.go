@@ -0,0 +1,227 @@
+// Package inventory provides a shared informer backed cache of the master
+// Services and certificate Secrets of workload clusters, so that resources do
+// not list them from the Kubernetes API on every reconciliation.
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/giantswarm/prometheus-config-controller/pkg/label"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+)
+
+const (
+	// clusterIndex is the index of Secrets by their cluster component and
+	// cluster ID labels.
+	clusterIndex = "cluster"
+
+	resourceSecrets  = "secrets"
+	resourceServices = "services"
+)
+
+type Config struct {
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+
+	// CertNamespace is the namespace of the certificate Secrets.
+	CertNamespace string
+	// ResyncPeriod is the period the informers resync their cache in.
+	ResyncPeriod time.Duration
+}
+
+// Inventory caches the master Services of all workload clusters, see
+// key.LabelSelectorService, and the Secrets of the certificate namespace.
+type Inventory struct {
+	logger micrologger.Logger
+
+	secretFactory   informers.SharedInformerFactory
+	secretInformer  cache.SharedIndexInformer
+	serviceFactory  informers.SharedInformerFactory
+	serviceInformer cache.SharedIndexInformer
+	serviceLister   corelisters.ServiceLister
+
+	startOnce sync.Once
+}
+
+func New(config Config) (*Inventory, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.CertNamespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CertNamespace must not be empty", config)
+	}
+
+	secretFactory := informers.NewSharedInformerFactoryWithOptions(
+		config.K8sClient,
+		config.ResyncPeriod,
+		informers.WithNamespace(config.CertNamespace),
+	)
+	serviceFactory := informers.NewSharedInformerFactoryWithOptions(
+		config.K8sClient,
+		config.ResyncPeriod,
+		informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.LabelSelector = key.LabelSelectorService().String()
+		}),
+	)
+
+	secretInformer := secretFactory.Core().V1().Secrets().Informer()
+	err := secretInformer.AddIndexers(cache.Indexers{
+		clusterIndex: indexByCluster,
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	secretInformer.AddEventHandler(newSizeHandler(secretInformer.GetStore(), resourceSecrets))
+
+	services := serviceFactory.Core().V1().Services()
+	serviceInformer := services.Informer()
+	serviceInformer.AddEventHandler(newSizeHandler(serviceInformer.GetStore(), resourceServices))
+
+	i := &Inventory{
+		logger: config.Logger,
+
+		secretFactory:   secretFactory,
+		secretInformer:  secretInformer,
+		serviceFactory:  serviceFactory,
+		serviceInformer: serviceInformer,
+		serviceLister:   services.Lister(),
+	}
+
+	return i, nil
+}
+
+// Start starts the informers, and waits for their caches to sync. The
+// informers are stopped when the given context is done.
+func (i *Inventory) Start(ctx context.Context) error {
+	i.startOnce.Do(func() {
+		i.secretFactory.Start(ctx.Done())
+		i.serviceFactory.Start(ctx.Done())
+	})
+
+	cachedInformers := map[string]cache.SharedIndexInformer{
+		resourceSecrets:  i.secretInformer,
+		resourceServices: i.serviceInformer,
+	}
+
+	for resource, informer := range cachedInformers {
+		i.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("waiting for %s cache to sync", resource))
+
+		start := time.Now()
+		if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+			return microerror.Maskf(cacheNotSyncedError, "%s cache did not sync", resource)
+		}
+		cacheSyncDuration.WithLabelValues(resource).Set(time.Since(start).Seconds())
+		cacheSynced.WithLabelValues(resource).Set(1)
+
+		i.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("waited for %s cache to sync", resource))
+	}
+
+	return nil
+}
+
+// Services returns the master Services of all workload clusters, ordered by
+// namespace and name.
+func (i *Inventory) Services() ([]v1.Service, error) {
+	if !i.serviceInformer.HasSynced() {
+		return nil, microerror.Maskf(cacheNotSyncedError, "%s cache did not sync", resourceServices)
+	}
+
+	list, err := i.serviceLister.List(labels.Everything())
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var services []v1.Service
+	for _, s := range list {
+		services = append(services, *s.DeepCopy())
+	}
+
+	sort.Slice(services, func(a, b int) bool {
+		if services[a].Namespace != services[b].Namespace {
+			return services[a].Namespace < services[b].Namespace
+		}
+		return services[a].Name < services[b].Name
+	})
+
+	return services, nil
+}
+
+// Secrets returns the Secrets of the certificate namespace labelled with the
+// given cluster component and cluster ID, ordered by name.
+func (i *Inventory) Secrets(clusterComponent, clusterID string) ([]v1.Secret, error) {
+	if !i.secretInformer.HasSynced() {
+		return nil, microerror.Maskf(cacheNotSyncedError, "%s cache did not sync", resourceSecrets)
+	}
+
+	objects, err := i.secretInformer.GetIndexer().ByIndex(clusterIndex, clusterIndexKey(clusterComponent, clusterID))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var secrets []v1.Secret
+	for _, o := range objects {
+		secrets = append(secrets, *o.(*v1.Secret).DeepCopy())
+	}
+
+	sort.Slice(secrets, func(a, b int) bool {
+		return secrets[a].Name < secrets[b].Name
+	})
+
+	return secrets, nil
+}
+
+// clusterIndexKey returns the key of the cluster index.
+func clusterIndexKey(clusterComponent, clusterID string) string {
+	return fmt.Sprintf("%s/%s", clusterComponent, clusterID)
+}
+
+// indexByCluster indexes Secrets by their cluster component and cluster ID
+// labels. Secrets missing either label are not indexed.
+func indexByCluster(obj interface{}) ([]string, error) {
+	secret, ok := obj.(*v1.Secret)
+	if !ok {
+		return nil, nil
+	}
+
+	clusterComponent, ok := secret.Labels[label.ClusterComponent]
+	if !ok {
+		return nil, nil
+	}
+	clusterID, ok := secret.Labels[label.ClusterID]
+	if !ok {
+		return nil, nil
+	}
+
+	return []string{clusterIndexKey(clusterComponent, clusterID)}, nil
+}
+
+// newSizeHandler returns an event handler exposing the number of objects in
+// the given store.
+func newSizeHandler(store cache.Store, resource string) cache.ResourceEventHandler {
+	update := func() {
+		cacheSize.WithLabelValues(resource).Set(float64(len(store.ListKeys())))
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { update() },
+		DeleteFunc: func(obj interface{}) { update() },
+	}
+}
@@ -0,0 +1,131 @@
+package inventory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/prometheus-config-controller/pkg/label"
+)
+
+func newSecret(namespace, name string, labels map[string]string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+	}
+}
+
+func newService(namespace string, labels map[string]string) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "master",
+			Namespace: namespace,
+			Labels:    labels,
+		},
+	}
+}
+
+// Test_Inventory tests the Services and Secrets methods.
+func Test_Inventory(t *testing.T) {
+	objects := []runtime.Object{
+		newService("xa5ly", map[string]string{"app": "master", label.Cluster: "xa5ly"}),
+		newService("al9qy", map[string]string{"app": "master", label.Cluster: "al9qy"}),
+		newService("default", map[string]string{"app": "nginx"}),
+
+		newSecret("default", "xa5ly-prometheus-b", map[string]string{label.ClusterComponent: "prometheus", label.ClusterID: "xa5ly"}),
+		newSecret("default", "xa5ly-prometheus-a", map[string]string{label.ClusterComponent: "prometheus", label.ClusterID: "xa5ly"}),
+		newSecret("default", "xa5ly-etcd", map[string]string{label.ClusterComponent: "etcd", label.ClusterID: "xa5ly"}),
+		newSecret("default", "unlabelled", nil),
+		newSecret("other", "al9qy-prometheus", map[string]string{label.ClusterComponent: "prometheus", label.ClusterID: "al9qy"}),
+	}
+
+	i, err := New(Config{
+		K8sClient: fake.NewSimpleClientset(objects...),
+		Logger:    microloggertest.New(),
+
+		CertNamespace: "default",
+	})
+	if err != nil {
+		t.Fatalf("error returned creating inventory: %s\n", err)
+	}
+
+	_, err = i.Services()
+	if !IsCacheNotSynced(err) {
+		t.Fatalf("expected cache not synced error before start, got %#v", err)
+	}
+
+	err = i.Start(context.TODO())
+	if err != nil {
+		t.Fatalf("error returned starting inventory: %s\n", err)
+	}
+
+	services, err := i.Services()
+	if err != nil {
+		t.Fatalf("error returned getting services: %s\n", err)
+	}
+	var serviceNamespaces []string
+	for _, s := range services {
+		serviceNamespaces = append(serviceNamespaces, s.Namespace)
+	}
+	if expected := []string{"al9qy", "xa5ly"}; !reflect.DeepEqual(expected, serviceNamespaces) {
+		t.Fatalf("expected services in namespaces %v, got %v", expected, serviceNamespaces)
+	}
+
+	tests := []struct {
+		clusterComponent string
+		clusterID        string
+
+		expectedNames []string
+	}{
+		// Test that the Secrets of a cluster component are returned ordered
+		// by name.
+		{
+			clusterComponent: "prometheus",
+			clusterID:        "xa5ly",
+
+			expectedNames: []string{"xa5ly-prometheus-a", "xa5ly-prometheus-b"},
+		},
+
+		// Test that Secrets of other cluster components are not returned.
+		{
+			clusterComponent: "etcd",
+			clusterID:        "xa5ly",
+
+			expectedNames: []string{"xa5ly-etcd"},
+		},
+
+		// Test that Secrets outside the certificate namespace are not
+		// returned.
+		{
+			clusterComponent: "prometheus",
+			clusterID:        "al9qy",
+
+			expectedNames: nil,
+		},
+	}
+
+	for index, test := range tests {
+		secrets, err := i.Secrets(test.clusterComponent, test.clusterID)
+		if err != nil {
+			t.Fatalf("%d: error returned getting secrets: %s\n", index, err)
+		}
+
+		var names []string
+		for _, s := range secrets {
+			names = append(names, s.Name)
+		}
+
+		if !reflect.DeepEqual(test.expectedNames, names) {
+			t.Fatalf("%d: expected secrets %v, got %v", index, test.expectedNames, names)
+		}
+	}
+}
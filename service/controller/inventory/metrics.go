@@ -0,0 +1,48 @@
+package inventory
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	prometheusNamespace = "prometheus_config_controller"
+	prometheusSubsystem = "inventory"
+)
+
+var (
+	cacheSize = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "cache_size",
+			Help:      "Number of objects held in the inventory cache.",
+		},
+		[]string{"resource"},
+	)
+
+	cacheSynced = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "cache_synced",
+			Help:      "Whether the inventory cache has synced.",
+		},
+		[]string{"resource"},
+	)
+
+	cacheSyncDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "cache_sync_duration_seconds",
+			Help:      "Time it took the inventory cache to sync initially.",
+		},
+		[]string{"resource"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(cacheSize)
+	prometheus.MustRegister(cacheSynced)
+	prometheus.MustRegister(cacheSyncDuration)
+}
@@ -1,32 +1,81 @@
 package controller
 
 import (
+	"time"
+
 	"github.com/giantswarm/k8sclient/v4/pkg/k8sclient"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/giantswarm/operatorkit/v2/pkg/controller"
 	"github.com/giantswarm/operatorkit/v2/pkg/resource"
-	corev1 "k8s.io/api/core/v1"
+	"github.com/spf13/afero"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/giantswarm/prometheus-config-controller/pkg/project"
-	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/certificatesource"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/clustersource"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
 	controllerresource "github.com/giantswarm/prometheus-config-controller/service/controller/v1/resource"
+	"github.com/giantswarm/prometheus-config-controller/service/discovery"
+	"github.com/giantswarm/prometheus-config-controller/service/dryrun"
 )
 
 type PrometheusConfig struct {
+	// CertFs is the filesystem holding the certificates of clusters. It is
+	// an in-memory mirror of the projected Secrets when CertOutput is
+	// certificate.OutputProjection.
+	CertFs afero.Fs
+	// CertificateSource provides the certificates of clusters.
+	CertificateSource certificatesource.Interface
+	// ClusterSource is the source workload clusters are discovered from. The
+	// controller watches the objects clusters are discovered from.
+	ClusterSource clustersource.Interface
+	// Discovery is updated with the targets of all clusters on every
+	// reconciliation, to be served over HTTP service discovery.
+	Discovery *discovery.Service
+	// DryRun holds whether the dry-run mode is enabled, in which the
+	// Prometheus configmap is neither written nor reloaded.
+	DryRun *dryrun.Service
+	// Exporters is the exporter catalog. When nil, the built-in catalog is
+	// used.
+	Exporters []prometheus.Exporter
 	K8sClient k8sclient.Interface
 	Logger    micrologger.Logger
+	// SampleLimits are the default sample limits by job type, see
+	// prometheus.Config.SampleLimits.
+	SampleLimits map[string]uint
 
+	Backend            string
 	ConfigMapKey       string
 	ConfigMapName      string
 	ConfigMapNamespace string
-	CertComponentName  string
 	CertDirectory      string
-	CertNamespace      string
-	CertPermission     int
-	PrometheusAddress  string
-	Provider           string
+	CertExpiryWarning  time.Duration
+	// CertOutput is where certificates are written to, either the
+	// certificate directory or projected Secrets.
+	CertOutput              string
+	CertPermission          int
+	CertProjectionName      string
+	CertProjectionNamespace string
+	CertProjectionSecrets   int
+	DiscoveryURL            string
+	EtcdScrapeDelay         time.Duration
+	EtcdScrapeMode          string
+	PrometheusVersion       string
+	PrometheusAddress       string
+	Provider                string
+	ReloadMode              string
+	ReloadNamespace         string
+	ReloadPort              int
+	ReloadSelector          string
+	ReloadService           string
+	ReloadVerifyInterval    time.Duration
+	ReloadVerifyTimeout     time.Duration
+	SecretKey               string
+	SecretName              string
+	SecretNamespace         string
+	ShardCount              int
+	ShardIndex              int
 }
 
 type Prometheus struct {
@@ -34,6 +83,21 @@ type Prometheus struct {
 }
 
 func NewPrometheus(config PrometheusConfig) (*Prometheus, error) {
+	if config.CertFs == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CertFs must not be empty", config)
+	}
+	if config.CertificateSource == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CertificateSource must not be empty", config)
+	}
+	if config.ClusterSource == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ClusterSource must not be empty", config)
+	}
+	if config.Discovery == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Discovery must not be empty", config)
+	}
+	if config.DryRun == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.DryRun must not be empty", config)
+	}
 	if config.K8sClient == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
 	}
@@ -50,42 +114,76 @@ func NewPrometheus(config PrometheusConfig) (*Prometheus, error) {
 	if config.ConfigMapNamespace == "" {
 		return nil, microerror.Maskf(invalidConfigError, "%T.ConfigMapNamespace must not be empty", config)
 	}
-	if config.CertComponentName == "" {
-		return nil, microerror.Maskf(invalidConfigError, "%T.CertComponentName must not be empty", config)
-	}
 	if config.CertDirectory == "" {
 		return nil, microerror.Maskf(invalidConfigError, "%T.CertDirectory must not be empty", config)
 	}
-	if config.CertNamespace == "" {
-		return nil, microerror.Maskf(invalidConfigError, "%T.CertNamespace must not be empty", config)
-	}
 	if config.CertPermission == 0 {
 		return nil, microerror.Maskf(invalidConfigError, "%T.CertPermission must not be empty", config)
 	}
+	if config.EtcdScrapeDelay < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.EtcdScrapeDelay must not be negative", config)
+	}
+	switch config.EtcdScrapeMode {
+	case prometheus.EtcdScrapeModeDelay, prometheus.EtcdScrapeModeAnnotation, prometheus.EtcdScrapeModeProbe:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "%T.EtcdScrapeMode must be one of %#q, %#q, %#q", config, prometheus.EtcdScrapeModeDelay, prometheus.EtcdScrapeModeAnnotation, prometheus.EtcdScrapeModeProbe)
+	}
 	if config.PrometheusAddress == "" {
 		return nil, microerror.Maskf(invalidConfigError, "%T.PrometheusAddress must not be empty", config)
 	}
 	if config.Provider == "" {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Provider must not be empty", config)
 	}
+	if config.ShardCount < 1 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ShardCount must be at least 1", config)
+	}
+	if config.ShardIndex < 0 || config.ShardIndex >= config.ShardCount {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ShardIndex must be between 0 and %T.ShardCount - 1", config, config)
+	}
 
 	var err error
 
 	var resources []resource.Interface
 	{
 		c := controllerresource.Config{
-
-			K8sClient:          config.K8sClient.K8sClient(),
-			Logger:             config.Logger,
-			ConfigMapKey:       config.ConfigMapKey,
-			ConfigMapName:      config.ConfigMapName,
-			ConfigMapNamespace: config.ConfigMapNamespace,
-			CertComponentName:  config.CertComponentName,
-			CertDirectory:      config.CertDirectory,
-			CertNamespace:      config.CertNamespace,
-			CertPermission:     config.CertPermission,
-			PrometheusAddress:  config.PrometheusAddress,
-			Provider:           config.Provider,
+			CertFs:                  config.CertFs,
+			CertificateSource:       config.CertificateSource,
+			ClusterSource:           config.ClusterSource,
+			Discovery:               config.Discovery,
+			DryRun:                  config.DryRun,
+			Exporters:               config.Exporters,
+			K8sClient:               config.K8sClient.K8sClient(),
+			Logger:                  config.Logger,
+			SampleLimits:            config.SampleLimits,
+			Backend:                 config.Backend,
+			ConfigMapKey:            config.ConfigMapKey,
+			ConfigMapName:           config.ConfigMapName,
+			ConfigMapNamespace:      config.ConfigMapNamespace,
+			CertDirectory:           config.CertDirectory,
+			CertExpiryWarning:       config.CertExpiryWarning,
+			CertOutput:              config.CertOutput,
+			CertPermission:          config.CertPermission,
+			CertProjectionName:      config.CertProjectionName,
+			CertProjectionNamespace: config.CertProjectionNamespace,
+			CertProjectionSecrets:   config.CertProjectionSecrets,
+			DiscoveryURL:            config.DiscoveryURL,
+			EtcdScrapeDelay:         config.EtcdScrapeDelay,
+			EtcdScrapeMode:          config.EtcdScrapeMode,
+			PrometheusVersion:       config.PrometheusVersion,
+			PrometheusAddress:       config.PrometheusAddress,
+			Provider:                config.Provider,
+			ReloadMode:              config.ReloadMode,
+			ReloadNamespace:         config.ReloadNamespace,
+			ReloadPort:              config.ReloadPort,
+			ReloadSelector:          config.ReloadSelector,
+			ReloadService:           config.ReloadService,
+			ReloadVerifyInterval:    config.ReloadVerifyInterval,
+			ReloadVerifyTimeout:     config.ReloadVerifyTimeout,
+			SecretKey:               config.SecretKey,
+			SecretName:              config.SecretName,
+			SecretNamespace:         config.SecretNamespace,
+			ShardCount:              config.ShardCount,
+			ShardIndex:              config.ShardIndex,
 		}
 		resources, err = controllerresource.New(c)
 		if err != nil {
@@ -98,11 +196,11 @@ func NewPrometheus(config PrometheusConfig) (*Prometheus, error) {
 		c := controller.Config{
 			K8sClient: config.K8sClient,
 			NewRuntimeObjectFunc: func() runtime.Object {
-				return new(corev1.Service)
+				return config.ClusterSource.NewRuntimeObject()
 			},
 			Logger:    config.Logger,
 			Resources: resources,
-			Selector:  key.LabelSelectorService(),
+			Selector:  config.ClusterSource.Selector(),
 			Name:      project.Name(),
 		}
 
@@ -0,0 +1,21 @@
+package etcd
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var probeFailedError = &microerror.Error{
+	Kind: "probeFailedError",
+}
+
+// IsProbeFailed asserts probeFailedError.
+func IsProbeFailed(err error) bool {
+	return microerror.Cause(err) == probeFailedError
+}
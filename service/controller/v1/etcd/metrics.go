@@ -0,0 +1,44 @@
+package etcd
+
+import (
+	prometheusclient "github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+)
+
+const (
+	prometheusNamespace = "prometheus_config_controller"
+	prometheusSubsystem = "etcd"
+)
+
+var (
+	scrapePending = prometheusclient.NewGaugeVec(
+		prometheusclient.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "scrape_pending",
+			Help:      "Whether etcd of a cluster is not scraped yet, and the reason why.",
+		},
+		[]string{"cluster_id", "reason"},
+	)
+)
+
+func init() {
+	prometheusclient.MustRegister(scrapePending)
+}
+
+// UpdateMetrics sets the etcd scrape pending metric of the given Services,
+// which are assumed to be filtered already.
+func UpdateMetrics(services []v1.Service, metaConfig prometheus.Config) {
+	scrapePending.Reset()
+
+	for _, service := range services {
+		ok, reason := prometheus.GetEtcdScrapeStatus(service, metaConfig)
+		if ok || reason == "" {
+			continue
+		}
+
+		scrapePending.WithLabelValues(prometheus.GetClusterID(service), reason).Set(1)
+	}
+}
@@ -0,0 +1,231 @@
+// Package etcd probes the etcd endpoints of workload clusters, to enable
+// scraping etcd only once it is reachable.
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/afero"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+)
+
+const (
+	// healthPath is the etcd endpoint probed.
+	healthPath = "/health"
+	// maxConcurrentProbes is the maximum number of clusters probed
+	// concurrently.
+	maxConcurrentProbes = 10
+)
+
+type ProberConfig struct {
+	// Fs is the filesystem the certificates are read from.
+	Fs     afero.Fs
+	Logger micrologger.Logger
+
+	CertDirectory string
+	// Timeout is the timeout of a single probe.
+	Timeout time.Duration
+}
+
+// Prober probes the etcd endpoints of clusters over HTTPS, using the
+// certificates written to the certificate directory by the certificate
+// resource. Probes only gate adding the etcd job of a cluster: once etcd of a
+// cluster was healthy, it is not probed again, so that an etcd outage does
+// not remove the very job alerting on it.
+type Prober struct {
+	fs     afero.Fs
+	logger micrologger.Logger
+
+	certDirectory string
+	timeout       time.Duration
+
+	mutex sync.Mutex
+	ready map[string]bool
+}
+
+func NewProber(config ProberConfig) (*Prober, error) {
+	if config.Fs == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Fs must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.CertDirectory == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CertDirectory must not be empty", config)
+	}
+	if config.Timeout == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Timeout must not be empty", config)
+	}
+
+	p := &Prober{
+		fs:     config.Fs,
+		logger: config.Logger,
+
+		certDirectory: config.CertDirectory,
+		timeout:       config.Timeout,
+
+		ready: map[string]bool{},
+	}
+
+	return p, nil
+}
+
+// Probe probes etcd of all given Services' clusters having etcd endpoints, and
+// returns the results by cluster ID, see prometheus.Config.EtcdProbeErrors.
+// etcd of a cluster is ready as soon as one of its members is healthy, and
+// stays ready without being probed again until its cluster is gone from the
+// given Services. Clusters are probed concurrently, at most
+// maxConcurrentProbes at a time.
+func (p *Prober) Probe(ctx context.Context, services []v1.Service) map[string]error {
+	results := map[string]error{}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentProbes)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	ready := map[string]bool{}
+
+	for _, service := range services {
+		if len(prometheus.GetEtcdEndpoints(service)) == 0 && prometheus.GetEtcdSRV(service) == "" {
+			continue
+		}
+
+		clusterID := prometheus.GetClusterID(service)
+		if p.ready[clusterID] {
+			ready[clusterID] = true
+			results[clusterID] = nil
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(service v1.Service) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			clusterID := prometheus.GetClusterID(service)
+
+			err := p.probeMembers(ctx, service)
+			if err != nil {
+				p.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("etcd of cluster %#q is not ready", clusterID), "reason", err.Error())
+			}
+
+			mutex.Lock()
+			results[clusterID] = err
+			if err == nil {
+				ready[clusterID] = true
+			}
+			mutex.Unlock()
+		}(service)
+	}
+
+	wg.Wait()
+
+	p.ready = ready
+
+	return results
+}
+
+// probeMembers probes the etcd members of the given Service's cluster one
+// after another, until one of them is healthy. All members are probed with a
+// single client, whose connections are closed once done.
+func (p *Prober) probeMembers(ctx context.Context, service v1.Service) error {
+	endpoints, err := ResolveEndpoints(ctx, service)
+	if err != nil {
+		return microerror.Maskf(probeFailedError, "failed to resolve etcd members: %s", err)
+	}
+	if len(endpoints) == 0 {
+		return microerror.Maskf(probeFailedError, "no etcd members found")
+	}
+
+	client, err := p.newClient(prometheus.GetClusterID(service))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer client.CloseIdleConnections()
+
+	for _, e := range endpoints {
+		err = p.probe(ctx, client, e)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return microerror.Mask(err)
+}
+
+// newClient returns a client authenticating with the certificates of the
+// given cluster. Probes are rare, so connections are not kept alive.
+func (p *Prober) newClient(clusterID string) (*http.Client, error) {
+	crt, err := afero.ReadFile(p.fs, key.CrtPath(p.certDirectory, clusterID))
+	if err != nil {
+		return nil, microerror.Maskf(probeFailedError, "failed to load certificate: %s", err)
+	}
+	k, err := afero.ReadFile(p.fs, key.KeyPath(p.certDirectory, clusterID))
+	if err != nil {
+		return nil, microerror.Maskf(probeFailedError, "failed to load certificate: %s", err)
+	}
+	certificate, err := tls.X509KeyPair(crt, k)
+	if err != nil {
+		return nil, microerror.Maskf(probeFailedError, "failed to load certificate: %s", err)
+	}
+
+	ca, err := afero.ReadFile(p.fs, key.CAPath(p.certDirectory, clusterID))
+	if err != nil {
+		return nil, microerror.Maskf(probeFailedError, "failed to load CA: %s", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, microerror.Maskf(probeFailedError, "failed to parse CA")
+	}
+
+	client := &http.Client{
+		Timeout: p.timeout,
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			TLSClientConfig: &tls.Config{
+				Certificates: []tls.Certificate{certificate},
+				RootCAs:      pool,
+			},
+		},
+	}
+
+	return client, nil
+}
+
+func (p *Prober) probe(ctx context.Context, client *http.Client, domain string) error {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://%s%s", domain, healthPath), nil)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return microerror.Maskf(probeFailedError, "request failed: %s", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return microerror.Maskf(probeFailedError, "expected 2xx response but got %d", res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,271 @@
+package etcd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/spf13/afero"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+)
+
+// writeCertificates writes a client certificate and the CA of the given test
+// server for the given cluster to the given filesystem.
+func writeCertificates(t *testing.T, fs afero.Fs, clusterID string, server *httptest.Server) {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error returned generating key: %s\n", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "prometheus"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	crt, err := x509.CreateCertificate(rand.Reader, template, template, &k.PublicKey, k)
+	if err != nil {
+		t.Fatalf("error returned creating certificate: %s\n", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(k)
+	if err != nil {
+		t.Fatalf("error returned marshalling key: %s\n", err)
+	}
+
+	files := map[string][]byte{
+		key.CAPath("/certs", clusterID):  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}),
+		key.CrtPath("/certs", clusterID): pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: crt}),
+		key.KeyPath("/certs", clusterID): pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+	for p, data := range files {
+		err := afero.WriteFile(fs, p, data, 0600)
+		if err != nil {
+			t.Fatalf("error returned writing %#q: %s\n", p, err)
+		}
+	}
+}
+
+// Test_Etcd_Prober_Probe tests that etcd of a cluster is ready as soon as one
+// of its members is healthy.
+func Test_Etcd_Prober_Probe(t *testing.T) {
+	healthy := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+
+	unhealthy := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	// Both test servers share the same certificate, so that a single CA
+	// verifies them.
+	fs := afero.NewMemMapFs()
+	writeCertificates(t, fs, "xa5ly", healthy)
+
+	member := func(s *httptest.Server) string {
+		return strings.TrimPrefix(s.URL, "https://")
+	}
+
+	tests := []struct {
+		members []string
+
+		expectedReady bool
+	}{
+		// 0. Test that etcd is ready when the first member fails and the
+		// second is healthy.
+		{
+			members: []string{member(unhealthy), member(healthy)},
+
+			expectedReady: true,
+		},
+
+		// 1. Test that etcd is ready when the first member is healthy and the
+		// second fails.
+		{
+			members: []string{member(healthy), member(unhealthy)},
+
+			expectedReady: true,
+		},
+
+		// 2. Test that etcd is not ready when all members fail.
+		{
+			members: []string{member(unhealthy), member(unhealthy)},
+
+			expectedReady: false,
+		},
+	}
+
+	for index, test := range tests {
+		p, err := NewProber(ProberConfig{
+			Fs:     fs,
+			Logger: microloggertest.New(),
+
+			CertDirectory: "/certs",
+			Timeout:       5 * time.Second,
+		})
+		if err != nil {
+			t.Fatalf("%d: error returned creating prober: %s\n", index, err)
+		}
+
+		services := []v1.Service{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "master",
+					Namespace: "xa5ly",
+					Annotations: map[string]string{
+						prometheus.ClusterAnnotation: "xa5ly",
+						key.AnnotationEtcdDomain:     strings.Join(test.members, ","),
+					},
+				},
+			},
+		}
+
+		results := p.Probe(context.TODO(), services)
+
+		err, ok := results["xa5ly"]
+		if !ok {
+			t.Fatalf("%d: expected probe result for cluster %#q, got none\n", index, "xa5ly")
+		}
+		if test.expectedReady && err != nil {
+			t.Fatalf("%d: expected etcd to be ready, got %s\n", index, err)
+		}
+		if !test.expectedReady && !IsProbeFailed(err) {
+			t.Fatalf("%d: expected probe failed error, got %#v\n", index, err)
+		}
+	}
+}
+
+// Test_Etcd_Prober_Probe_Ready tests that etcd of a cluster which was healthy
+// once stays ready without being probed again, until its cluster is gone.
+func Test_Etcd_Prober_Probe_Ready(t *testing.T) {
+	var probes int
+	var healthy bool
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes++
+		if healthy {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	writeCertificates(t, fs, "xa5ly", server)
+
+	p, err := NewProber(ProberConfig{
+		Fs:     fs,
+		Logger: microloggertest.New(),
+
+		CertDirectory: "/certs",
+		Timeout:       5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("error returned creating prober: %s\n", err)
+	}
+
+	service := v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "master",
+			Namespace: "xa5ly",
+			Annotations: map[string]string{
+				prometheus.ClusterAnnotation: "xa5ly",
+				key.AnnotationEtcdDomain:     strings.TrimPrefix(server.URL, "https://"),
+			},
+		},
+	}
+
+	steps := []struct {
+		healthy  bool
+		services []v1.Service
+
+		expectedReady  bool
+		expectedProbes int
+	}{
+		// 0. Test that etcd is not ready while it is unhealthy.
+		{
+			healthy:  false,
+			services: []v1.Service{service},
+
+			expectedReady:  false,
+			expectedProbes: 1,
+		},
+
+		// 1. Test that etcd is ready once it is healthy.
+		{
+			healthy:  true,
+			services: []v1.Service{service},
+
+			expectedReady:  true,
+			expectedProbes: 2,
+		},
+
+		// 2. Test that etcd stays ready without being probed again when it
+		// becomes unhealthy.
+		{
+			healthy:  false,
+			services: []v1.Service{service},
+
+			expectedReady:  true,
+			expectedProbes: 2,
+		},
+
+		// 3. Test that a cluster which is gone is forgotten.
+		{
+			healthy:  false,
+			services: nil,
+
+			expectedProbes: 2,
+		},
+
+		// 4. Test that etcd of a cluster which is back is probed again.
+		{
+			healthy:  false,
+			services: []v1.Service{service},
+
+			expectedReady:  false,
+			expectedProbes: 3,
+		},
+	}
+
+	for index, step := range steps {
+		healthy = step.healthy
+
+		results := p.Probe(context.TODO(), step.services)
+
+		if probes != step.expectedProbes {
+			t.Fatalf("%d: expected %d probes, got %d\n", index, step.expectedProbes, probes)
+		}
+		if len(step.services) == 0 {
+			continue
+		}
+
+		err, ok := results["xa5ly"]
+		if !ok {
+			t.Fatalf("%d: expected probe result for cluster %#q, got none\n", index, "xa5ly")
+		}
+		if step.expectedReady && err != nil {
+			t.Fatalf("%d: expected etcd to be ready, got %s\n", index, err)
+		}
+		if !step.expectedReady && !IsProbeFailed(err) {
+			t.Fatalf("%d: expected probe failed error, got %#v\n", index, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+)
+
+const (
+	// maxConcurrentResolutions is the maximum number of etcd SRV names
+	// resolved concurrently.
+	maxConcurrentResolutions = 10
+)
+
+// ResolveEndpoints returns the etcd endpoints of the given Service's cluster.
+// Endpoints listed in the etcd domain annotation take precedence, otherwise
+// the etcd SRV name is resolved.
+func ResolveEndpoints(ctx context.Context, service v1.Service) ([]string, error) {
+	endpoints := prometheus.GetEtcdEndpoints(service)
+	if len(endpoints) > 0 {
+		return endpoints, nil
+	}
+
+	name := prometheus.GetEtcdSRV(service)
+	if name == "" {
+		return nil, nil
+	}
+
+	_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	for _, r := range records {
+		endpoints = append(endpoints, fmt.Sprintf("%s:%d", strings.TrimSuffix(r.Target, "."), r.Port))
+	}
+
+	return endpoints, nil
+}
+
+// ResolveServices returns the given Services, with the etcd SRV names of their
+// clusters resolved into the etcd domain annotation. This allows consumers
+// that can not resolve SRV names themselves, like the HTTP service discovery,
+// to emit one target per etcd member. Services whose SRV name can not be
+// resolved are returned unchanged. SRV names are resolved concurrently, at
+// most maxConcurrentResolutions at a time.
+func ResolveServices(ctx context.Context, services []v1.Service) []v1.Service {
+	resolved := make([]v1.Service, len(services))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentResolutions)
+
+	for i, service := range services {
+		resolved[i] = service
+
+		if len(prometheus.GetEtcdEndpoints(service)) > 0 || prometheus.GetEtcdSRV(service) == "" {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, service v1.Service) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			endpoints, err := ResolveEndpoints(ctx, service)
+			if err == nil && len(endpoints) > 0 {
+				service = *service.DeepCopy()
+				service.Annotations[key.AnnotationEtcdDomain] = strings.Join(endpoints, ",")
+				resolved[i] = service
+			}
+		}(i, service)
+	}
+
+	wg.Wait()
+
+	return resolved
+}
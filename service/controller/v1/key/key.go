@@ -43,7 +43,12 @@ const (
 	PrefixMaster    = "master"
 	PrefixApiServer = "apiserver"
 
+	// AnnotationEtcdDomain holds the etcd endpoint of a cluster, or a comma
+	// separated list of the endpoints of all etcd members.
 	AnnotationEtcdDomain = "giantswarm.io/etcd-domain"
+	// AnnotationEtcdSRV holds a DNS SRV name resolving to the etcd members
+	// of a cluster.
+	AnnotationEtcdSRV = "giantswarm.io/etcd-srv"
 )
 
 func certPath(certificateDirectory, clusterID, suffix string) string {
@@ -62,8 +67,27 @@ func KeyPath(certificateDirectory, clusterID string) string {
 	return certPath(certificateDirectory, clusterID, "key")
 }
 
+// CertClusterID returns the ID of the cluster the certificate file at the
+// given path belongs to, as named by CAPath, CrtPath and KeyPath. It returns
+// an empty string for other files.
+func CertClusterID(p string) string {
+	name := path.Base(p)
+	for _, suffix := range []string{"ca", "crt", "key"} {
+		clusterID := strings.TrimSuffix(name, fmt.Sprintf("-%s.pem", suffix))
+		if clusterID != name && clusterID != "" {
+			return clusterID
+		}
+	}
+
+	return ""
+}
+
 func APIProxyPodMetricsPath(namespace, port string) string {
-	return fmt.Sprintf("/api/v1/namespaces/%s/pods/${1}:%s/proxy/metrics", namespace, port)
+	return APIProxyPodPath(namespace, port, "metrics")
+}
+
+func APIProxyPodPath(namespace, port, path string) string {
+	return fmt.Sprintf("/api/v1/namespaces/%s/pods/${1}:%s/proxy/%s", namespace, port, strings.TrimPrefix(path, "/"))
 }
 
 func APIServiceHost(prefix string, clusterID string) string {
@@ -105,6 +129,14 @@ func PrometheusURLConfig(address string) string {
 	return u + "/api/v1/status/config"
 }
 
+// PrometheusURLReady returns the Prometheus URL that reports whether
+// Prometheus is ready to serve traffic. It assumes that address is a valid HTTP
+// URL.
+func PrometheusURLReady(address string) string {
+	u := strings.TrimSuffix(address, "/")
+	return u + "/-/ready"
+}
+
 // PrometheusURLReload returns the Prometheus API URL that reloads the
 // configuration. It assumes that address is a valid HTTP URL.
 func PrometheusURLReload(address string) string {
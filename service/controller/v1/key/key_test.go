@@ -134,6 +134,54 @@ func Test_Key_KeyPath(t *testing.T) {
 	}
 }
 
+// Test_Key_CertClusterID tests the CertClusterID function.
+func Test_Key_CertClusterID(t *testing.T) {
+	tests := []struct {
+		path string
+
+		expectedClusterID string
+	}{
+		{
+			path: "/certs/xa5ly-ca.pem",
+
+			expectedClusterID: "xa5ly",
+		},
+		{
+			path: "/certs/fah0a-crt.pem",
+
+			expectedClusterID: "fah0a",
+		},
+		{
+			path: "/certificates/cluster-fah0a-key.pem",
+
+			expectedClusterID: "cluster-fah0a",
+		},
+		{
+			path: "/certs/prometheus.yml",
+
+			expectedClusterID: "",
+		},
+		{
+			path: "/certs/-ca.pem",
+
+			expectedClusterID: "",
+		},
+	}
+
+	for index, test := range tests {
+		clusterID := CertClusterID(test.path)
+
+		if !reflect.DeepEqual(test.expectedClusterID, clusterID) {
+			t.Fatalf(
+				"%d: expected cluster ID does not match returned cluster ID\nexpected: %s\nreturned: %s\n",
+				index,
+				spew.Sdump(test.expectedClusterID),
+				spew.Sdump(clusterID),
+			)
+		}
+	}
+}
+
 func Test_LabelSelectorConfigMap(t *testing.T) {
 	testCases := []struct {
 		name                   string
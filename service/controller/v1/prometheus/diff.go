@@ -0,0 +1,184 @@
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// diffContext is the number of context lines of the unified diff.
+	diffContext = 3
+
+	// scrapeConfigsKey is the key of the scrape configs in the Prometheus
+	// configuration.
+	scrapeConfigsKey = "scrape_configs"
+)
+
+// DiffConfig returns a readable unified diff between the current and desired
+// Prometheus configuration. Scrape configs are diffed per job, so that added,
+// removed and changed jobs are listed separately, followed by the diff of
+// the rest of the configuration. An empty string is returned when both are
+// equal.
+func DiffConfig(current, desired string) (string, error) {
+	currentJobs, currentRest, err := splitConfig(current)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	desiredJobs, desiredRest, err := splitConfig(desired)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	var jobNames []string
+	for jobName := range currentJobs {
+		jobNames = append(jobNames, jobName)
+	}
+	for jobName := range desiredJobs {
+		if _, ok := currentJobs[jobName]; !ok {
+			jobNames = append(jobNames, jobName)
+		}
+	}
+	sort.Strings(jobNames)
+
+	var diffs []string
+
+	for _, jobName := range jobNames {
+		c, inCurrent := currentJobs[jobName]
+		d, inDesired := desiredJobs[jobName]
+
+		var header string
+		switch {
+		case !inCurrent:
+			header = fmt.Sprintf("job %#q added", jobName)
+		case !inDesired:
+			header = fmt.Sprintf("job %#q removed", jobName)
+		case c != d:
+			header = fmt.Sprintf("job %#q changed", jobName)
+		default:
+			continue
+		}
+
+		diff, err := unifiedDiff(jobName, c, d)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		diffs = append(diffs, fmt.Sprintf("# %s\n%s", header, diff))
+	}
+
+	if currentRest != desiredRest {
+		diff, err := unifiedDiff("config", currentRest, desiredRest)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		diffs = append(diffs, fmt.Sprintf("# config changed\n%s", diff))
+	}
+
+	return strings.Join(diffs, "\n"), nil
+}
+
+// DiffScrapeConfigs returns a readable unified diff between the current and
+// desired list of scrape configs marshalled to YAML, as written to the
+// Prometheus Operator's additionalScrapeConfigs, like DiffConfig does.
+func DiffScrapeConfigs(current, desired string) (string, error) {
+	currentConfig, err := wrapScrapeConfigs(current)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	desiredConfig, err := wrapScrapeConfigs(desired)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	diff, err := DiffConfig(currentConfig, desiredConfig)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return diff, nil
+}
+
+// splitConfig splits the given Prometheus configuration into its scrape
+// configs in YAML by job name, and the rest of the configuration in YAML.
+func splitConfig(data string) (map[string]string, string, error) {
+	var tree yaml.MapSlice
+	err := yaml.Unmarshal([]byte(data), &tree)
+	if err != nil {
+		return nil, "", microerror.Mask(err)
+	}
+
+	jobs := map[string]string{}
+	var rest yaml.MapSlice
+
+	for _, item := range tree {
+		if item.Key != scrapeConfigsKey {
+			rest = append(rest, item)
+			continue
+		}
+
+		scrapeConfigs, _ := item.Value.([]interface{})
+		for _, s := range scrapeConfigs {
+			m, _ := s.(yaml.MapSlice)
+
+			b, err := yaml.Marshal(m)
+			if err != nil {
+				return nil, "", microerror.Mask(err)
+			}
+
+			jobs[fmt.Sprintf("%v", getMapValue(m, "job_name"))] = string(b)
+		}
+	}
+
+	var restData string
+	if len(rest) > 0 {
+		b, err := yaml.Marshal(rest)
+		if err != nil {
+			return nil, "", microerror.Mask(err)
+		}
+		restData = string(b)
+	}
+
+	return jobs, restData, nil
+}
+
+// wrapScrapeConfigs returns the given list of scrape configs marshalled to
+// YAML as the scrape configs of a Prometheus configuration.
+func wrapScrapeConfigs(data string) (string, error) {
+	var scrapeConfigs []interface{}
+	err := yaml.Unmarshal([]byte(data), &scrapeConfigs)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	b, err := yaml.Marshal(yaml.MapSlice{{Key: scrapeConfigsKey, Value: scrapeConfigs}})
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return string(b), nil
+}
+
+// unifiedDiff returns the unified diff between a and b, named after the given
+// name.
+func unifiedDiff(name, a, b string) (string, error) {
+	diff := difflib.UnifiedDiff{
+		A:        difflib.SplitLines(a),
+		B:        difflib.SplitLines(b),
+		FromFile: "current/" + name,
+		ToFile:   "desired/" + name,
+		Context:  diffContext,
+	}
+
+	s, err := difflib.GetUnifiedDiffString(diff)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return s, nil
+}
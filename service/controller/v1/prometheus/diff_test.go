@@ -0,0 +1,137 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test_Prometheus_DiffConfig tests the DiffConfig function.
+func Test_Prometheus_DiffConfig(t *testing.T) {
+	tests := []struct {
+		current string
+		desired string
+
+		expectedHeaders []string
+	}{
+		// 0. Test that equal configs have no diff.
+		{
+			current: `
+global:
+  scrape_interval: 1m
+scrape_configs:
+- job_name: workload-cluster-xa5ly-apiserver
+  scheme: https
+`,
+			desired: `
+global:
+  scrape_interval: 1m
+scrape_configs:
+- job_name: workload-cluster-xa5ly-apiserver
+  scheme: https
+`,
+
+			expectedHeaders: nil,
+		},
+
+		// 1. Test that added, removed and changed jobs are listed separately,
+		// ordered by job name.
+		{
+			current: `
+scrape_configs:
+- job_name: workload-cluster-0ba9v-apiserver
+  scheme: https
+- job_name: workload-cluster-xa5ly-apiserver
+  scheme: http
+`,
+			desired: `
+scrape_configs:
+- job_name: workload-cluster-xa5ly-apiserver
+  scheme: https
+- job_name: workload-cluster-xa5ly-etcd
+  scheme: https
+`,
+
+			expectedHeaders: []string{
+				"# job `workload-cluster-0ba9v-apiserver` removed",
+				"# job `workload-cluster-xa5ly-apiserver` changed",
+				"# job `workload-cluster-xa5ly-etcd` added",
+			},
+		},
+
+		// 2. Test that changes outside of scrape configs are listed.
+		{
+			current: `
+global:
+  scrape_interval: 1m
+`,
+			desired: `
+global:
+  scrape_interval: 30s
+`,
+
+			expectedHeaders: []string{
+				"# config changed",
+			},
+		},
+	}
+
+	for index, test := range tests {
+		diff, err := DiffConfig(test.current, test.desired)
+		if err != nil {
+			t.Fatalf("%d: error returned diffing config: %s\n", index, err)
+		}
+
+		var headers []string
+		for _, line := range strings.Split(diff, "\n") {
+			if strings.HasPrefix(line, "# ") {
+				headers = append(headers, line)
+			}
+		}
+
+		if strings.Join(headers, "\n") != strings.Join(test.expectedHeaders, "\n") {
+			t.Fatalf("%d: expected headers %#v, got %#v\n", index, test.expectedHeaders, headers)
+		}
+		if len(test.expectedHeaders) > 0 && !strings.Contains(diff, "+++ desired/") {
+			t.Fatalf("%d: expected unified diff, got:\n%s\n", index, diff)
+		}
+	}
+}
+
+// Test_Prometheus_DiffScrapeConfigs tests the DiffScrapeConfigs function.
+func Test_Prometheus_DiffScrapeConfigs(t *testing.T) {
+	current := `
+- job_name: workload-cluster-xa5ly-apiserver
+  scheme: http
+`
+	desired := `
+- job_name: workload-cluster-xa5ly-apiserver
+  scheme: https
+- job_name: workload-cluster-xa5ly-etcd
+  scheme: https
+`
+
+	diff, err := DiffScrapeConfigs(current, desired)
+	if err != nil {
+		t.Fatalf("error returned diffing scrape configs: %s\n", err)
+	}
+
+	for _, header := range []string{
+		"# job `workload-cluster-xa5ly-apiserver` changed",
+		"# job `workload-cluster-xa5ly-etcd` added",
+	} {
+		if !strings.Contains(diff, header) {
+			t.Fatalf("expected header %#q in diff, got:\n%s\n", header, diff)
+		}
+	}
+	if strings.Contains(diff, "# config changed") {
+		t.Fatalf("expected no config change in diff, got:\n%s\n", diff)
+	}
+
+	diff, err = DiffScrapeConfigs(desired, desired)
+	if err != nil {
+		t.Fatalf("error returned diffing scrape configs: %s\n", err)
+	}
+	if diff != "" {
+		t.Fatalf("expected no diff for equal scrape configs, got:\n%s\n", diff)
+	}
+}
@@ -0,0 +1,84 @@
+package prometheus
+
+import (
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// JobTypeMetaLabel is the label of target groups served over HTTP service
+	// discovery that holds the job type the targets belong to. Being a meta
+	// label, it is dropped by Prometheus after relabeling.
+	JobTypeMetaLabel = "__meta_giantswarm_job_type"
+)
+
+// TargetGroup is a group of targets sharing a set of labels, in the format of
+// the Prometheus HTTP service discovery.
+type TargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// GetTargetGroups takes a list of Kubernetes Services, and returns the target
+// groups of all clusters, ordered by cluster ID and job type. When jobType is
+// not empty, only target groups of that job type are returned.
+func GetTargetGroups(services []v1.Service, metaConfig Config, jobType string) []TargetGroup {
+	filteredServices := FilterInvalidServices(services)
+	filteredServices = FilterShardServices(filteredServices, metaConfig.ShardIndex, metaConfig.ShardCount)
+
+	sort.Slice(filteredServices, func(i, j int) bool {
+		return GetClusterID(filteredServices[i]) < GetClusterID(filteredServices[j])
+	})
+
+	targetGroups := []TargetGroup{}
+	for _, service := range filteredServices {
+		for _, g := range getTargetGroups(service, metaConfig) {
+			if jobType != "" && g.Labels[JobTypeMetaLabel] != jobType {
+				continue
+			}
+
+			targetGroups = append(targetGroups, g)
+		}
+	}
+
+	return targetGroups
+}
+
+// getTargetGroups takes a Service, and returns the target groups of its
+// cluster. It is assumed that filtering has already taken place, and the
+// cluster annotation exists.
+func getTargetGroups(service v1.Service, metaConfig Config) []TargetGroup {
+	labels := func(jobType string) map[string]string {
+		return map[string]string{
+			ClusterIDLabel:   GetClusterID(service),
+			ClusterTypeLabel: WorkloadClusterType,
+			ProviderLabel:    GetProvider(service, metaConfig),
+			JobTypeMetaLabel: jobType,
+		}
+	}
+
+	targetGroups := []TargetGroup{
+		{
+			Targets: []string{getTargetHost(service)},
+			Labels:  labels(APIServerJobType),
+		},
+	}
+
+	// etcd members are emitted as separate target groups, so that each
+	// target has its member label. SRV names are expected to be resolved into
+	// endpoints by the caller.
+	if ok, _ := GetEtcdScrapeStatus(service, metaConfig); ok {
+		for _, e := range GetEtcdEndpoints(service) {
+			etcdLabels := labels(EtcdJobType)
+			etcdLabels[MemberLabel] = getEtcdMember(e)
+
+			targetGroups = append(targetGroups, TargetGroup{
+				Targets: []string{e},
+				Labels:  etcdLabels,
+			})
+		}
+	}
+
+	return targetGroups
+}
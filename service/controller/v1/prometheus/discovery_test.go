@@ -0,0 +1,103 @@
+package prometheus
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+)
+
+// Test_Prometheus_GetTargetGroups tests the GetTargetGroups function.
+func Test_Prometheus_GetTargetGroups(t *testing.T) {
+	services := []v1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "apiserver",
+				Namespace: "xa5ly",
+				Annotations: map[string]string{
+					ClusterAnnotation:        "xa5ly",
+					key.AnnotationEtcdDomain: "etcd1.xa5ly:2379, etcd2.xa5ly:2379",
+				},
+				CreationTimestamp: metav1.Time{Time: time.Now().Add(-time.Hour)},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "apiserver",
+				Namespace: "0ba9v",
+				Annotations: map[string]string{
+					ClusterAnnotation: "0ba9v",
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+			},
+		},
+	}
+
+	labels := func(clusterID, jobType string) map[string]string {
+		return map[string]string{
+			ClusterIDLabel:   clusterID,
+			ClusterTypeLabel: WorkloadClusterType,
+			ProviderLabel:    "aws-test",
+			JobTypeMetaLabel: jobType,
+		}
+	}
+	etcdLabels := func(clusterID, member string) map[string]string {
+		l := labels(clusterID, EtcdJobType)
+		l[MemberLabel] = member
+		return l
+	}
+
+	tests := []struct {
+		jobType string
+
+		expectedTargetGroups []TargetGroup
+	}{
+		// 0. Test that target groups of all job types are returned, ordered by
+		// cluster ID.
+		{
+			jobType: "",
+
+			expectedTargetGroups: []TargetGroup{
+				{Targets: []string{"apiserver.0ba9v"}, Labels: labels("0ba9v", APIServerJobType)},
+				{Targets: []string{"apiserver.xa5ly"}, Labels: labels("xa5ly", APIServerJobType)},
+				{Targets: []string{"etcd1.xa5ly:2379"}, Labels: etcdLabels("xa5ly", "etcd1.xa5ly")},
+				{Targets: []string{"etcd2.xa5ly:2379"}, Labels: etcdLabels("xa5ly", "etcd2.xa5ly")},
+			},
+		},
+
+		// 1. Test that target groups are filtered by job type, and that
+		// there is one etcd target group per member.
+		{
+			jobType: EtcdJobType,
+
+			expectedTargetGroups: []TargetGroup{
+				{Targets: []string{"etcd1.xa5ly:2379"}, Labels: etcdLabels("xa5ly", "etcd1.xa5ly")},
+				{Targets: []string{"etcd2.xa5ly:2379"}, Labels: etcdLabels("xa5ly", "etcd2.xa5ly")},
+			},
+		},
+
+		// 2. Test that an unknown job type returns no target groups.
+		{
+			jobType: "foo",
+
+			expectedTargetGroups: []TargetGroup{},
+		},
+	}
+
+	for index, test := range tests {
+		targetGroups := GetTargetGroups(services, Config{Provider: "aws-test"}, test.jobType)
+
+		if !reflect.DeepEqual(targetGroups, test.expectedTargetGroups) {
+			t.Fatalf("%d: expected target groups %#v, got %#v\n", index, test.expectedTargetGroups, targetGroups)
+		}
+	}
+}
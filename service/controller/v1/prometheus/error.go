@@ -21,3 +21,21 @@ var invalidConfigError = &microerror.Error{
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
+
+var invalidScrapeOverrideError = &microerror.Error{
+	Kind: "invalidScrapeOverrideError",
+}
+
+// IsInvalidScrapeOverride asserts invalidScrapeOverrideError.
+func IsInvalidScrapeOverride(err error) bool {
+	return microerror.Cause(err) == invalidScrapeOverrideError
+}
+
+var invalidPrometheusConfigError = &microerror.Error{
+	Kind: "invalidPrometheusConfigError",
+}
+
+// IsInvalidPrometheusConfig asserts invalidPrometheusConfigError.
+func IsInvalidPrometheusConfig(err error) bool {
+	return microerror.Cause(err) == invalidPrometheusConfigError
+}
@@ -0,0 +1,112 @@
+package prometheus
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+)
+
+const (
+	// EtcdScrapeModeDelay enables scraping etcd once the cluster is older
+	// than the configured etcd scrape delay.
+	EtcdScrapeModeDelay = "delay"
+	// EtcdScrapeModeAnnotation enables scraping etcd once the cluster is
+	// annotated with EtcdReadyAnnotation set to "true".
+	EtcdScrapeModeAnnotation = "annotation"
+	// EtcdScrapeModeProbe enables scraping etcd once the etcd endpoint
+	// answers a probe made with the cluster's certificates.
+	EtcdScrapeModeProbe = "probe"
+
+	// EtcdReadyAnnotation is the Kubernetes annotation marking the cluster's
+	// etcd as ready to be scraped, used with EtcdScrapeModeAnnotation.
+	EtcdReadyAnnotation = "giantswarm.io/prometheus-etcd-ready"
+)
+
+// Reasons for etcd of a cluster with etcd domain not being scraped yet.
+const (
+	// EtcdPendingReasonCreationDelay is the reason for clusters younger than
+	// the etcd scrape delay.
+	EtcdPendingReasonCreationDelay = "creation_delay"
+	// EtcdPendingReasonNotReady is the reason for clusters not annotated as
+	// ready.
+	EtcdPendingReasonNotReady = "not_ready"
+	// EtcdPendingReasonProbeFailed is the reason for clusters whose etcd
+	// endpoint was not probed successfully.
+	EtcdPendingReasonProbeFailed = "probe_failed"
+)
+
+// GetEtcdEndpoints returns the etcd endpoints of the given Service's cluster,
+// one per etcd member.
+func GetEtcdEndpoints(service v1.Service) []string {
+	var endpoints []string
+
+	for _, e := range strings.Split(service.Annotations[key.AnnotationEtcdDomain], ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+
+		endpoints = append(endpoints, e)
+	}
+
+	return endpoints
+}
+
+// getEtcdMember returns the member name of the given etcd endpoint, which is
+// its host, see HostPortRegexp.
+func getEtcdMember(endpoint string) string {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return endpoint
+	}
+
+	return host
+}
+
+// GetEtcdSRV returns the DNS SRV name of the given Service's cluster's etcd
+// members.
+func GetEtcdSRV(service v1.Service) string {
+	return strings.TrimSpace(service.Annotations[key.AnnotationEtcdSRV])
+}
+
+// hasEtcd returns whether the given Service's cluster specifies its etcd
+// members.
+func hasEtcd(service v1.Service) bool {
+	return len(GetEtcdEndpoints(service)) > 0 || GetEtcdSRV(service) != ""
+}
+
+// GetEtcdScrapeStatus returns whether etcd of the given Service's cluster is
+// to be scraped. Clusters with etcd endpoints whose etcd is not scraped yet
+// are pending, and the reason is returned.
+func GetEtcdScrapeStatus(service v1.Service, metaConfig Config) (bool, string) {
+	if !hasEtcd(service) {
+		return false, ""
+	}
+
+	switch metaConfig.EtcdScrapeMode {
+	case EtcdScrapeModeAnnotation:
+		if service.Annotations[EtcdReadyAnnotation] != "true" {
+			return false, EtcdPendingReasonNotReady
+		}
+	case EtcdScrapeModeProbe:
+		err, ok := metaConfig.EtcdProbeErrors[GetClusterID(service)]
+		if !ok || err != nil {
+			return false, EtcdPendingReasonProbeFailed
+		}
+	default:
+		now := metaConfig.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+
+		if now.Sub(service.CreationTimestamp.Time) < metaConfig.EtcdScrapeDelay {
+			return false, EtcdPendingReasonCreationDelay
+		}
+	}
+
+	return true, ""
+}
@@ -0,0 +1,204 @@
+package prometheus
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+)
+
+// Test_Prometheus_GetEtcdScrapeStatus tests the GetEtcdScrapeStatus function.
+func Test_Prometheus_GetEtcdScrapeStatus(t *testing.T) {
+	now := time.Date(2021, 2, 3, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		annotations  map[string]string
+		creationTime time.Time
+		metaConfig   Config
+
+		expectedScrape bool
+		expectedReason string
+	}{
+		// 0. Test that a cluster without etcd domain is not scraped, and not
+		// pending.
+		{
+			annotations:  nil,
+			creationTime: now.Add(-time.Hour),
+			metaConfig:   Config{},
+
+			expectedScrape: false,
+			expectedReason: "",
+		},
+
+		// 1. Test that a cluster younger than the delay is pending.
+		{
+			annotations: map[string]string{
+				key.AnnotationEtcdDomain: "etcd.xa5ly:2379",
+			},
+			creationTime: now.Add(-10 * time.Minute),
+			metaConfig:   Config{EtcdScrapeMode: EtcdScrapeModeDelay, EtcdScrapeDelay: 30 * time.Minute},
+
+			expectedScrape: false,
+			expectedReason: EtcdPendingReasonCreationDelay,
+		},
+
+		// 2. Test that a cluster older than the delay is scraped.
+		{
+			annotations: map[string]string{
+				key.AnnotationEtcdDomain: "etcd.xa5ly:2379",
+			},
+			creationTime: now.Add(-time.Hour),
+			metaConfig:   Config{EtcdScrapeMode: EtcdScrapeModeDelay, EtcdScrapeDelay: 30 * time.Minute},
+
+			expectedScrape: true,
+			expectedReason: "",
+		},
+
+		// 3. Test that a cluster not annotated as ready is pending.
+		{
+			annotations: map[string]string{
+				key.AnnotationEtcdDomain: "etcd.xa5ly:2379",
+			},
+			creationTime: now.Add(-time.Hour),
+			metaConfig:   Config{EtcdScrapeMode: EtcdScrapeModeAnnotation},
+
+			expectedScrape: false,
+			expectedReason: EtcdPendingReasonNotReady,
+		},
+
+		// 4. Test that a cluster annotated as ready is scraped regardless of
+		// its age.
+		{
+			annotations: map[string]string{
+				key.AnnotationEtcdDomain: "etcd.xa5ly:2379",
+				EtcdReadyAnnotation:      "true",
+			},
+			creationTime: now,
+			metaConfig:   Config{EtcdScrapeMode: EtcdScrapeModeAnnotation, EtcdScrapeDelay: 30 * time.Minute},
+
+			expectedScrape: true,
+			expectedReason: "",
+		},
+
+		// 5. Test that a cluster whose probe failed is pending.
+		{
+			annotations: map[string]string{
+				key.AnnotationEtcdDomain: "etcd.xa5ly:2379",
+			},
+			creationTime: now.Add(-time.Hour),
+			metaConfig: Config{
+				EtcdScrapeMode:  EtcdScrapeModeProbe,
+				EtcdProbeErrors: map[string]error{"xa5ly": errors.New("connection refused")},
+			},
+
+			expectedScrape: false,
+			expectedReason: EtcdPendingReasonProbeFailed,
+		},
+
+		// 6. Test that a cluster whose probe succeeded is scraped.
+		{
+			annotations: map[string]string{
+				key.AnnotationEtcdDomain: "etcd.xa5ly:2379",
+			},
+			creationTime: now,
+			metaConfig: Config{
+				EtcdScrapeMode:  EtcdScrapeModeProbe,
+				EtcdProbeErrors: map[string]error{"xa5ly": nil},
+			},
+
+			expectedScrape: true,
+			expectedReason: "",
+		},
+	}
+
+	for index, test := range tests {
+		annotations := map[string]string{
+			ClusterAnnotation: "xa5ly",
+		}
+		for k, v := range test.annotations {
+			annotations[k] = v
+		}
+
+		service := v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "apiserver",
+				Namespace:         "xa5ly",
+				Annotations:       annotations,
+				CreationTimestamp: metav1.Time{Time: test.creationTime},
+			},
+		}
+
+		metaConfig := test.metaConfig
+		metaConfig.Now = now
+
+		scrape, reason := GetEtcdScrapeStatus(service, metaConfig)
+		if scrape != test.expectedScrape {
+			t.Fatalf("%d: expected scrape %t, got %t\n", index, test.expectedScrape, scrape)
+		}
+		if reason != test.expectedReason {
+			t.Fatalf("%d: expected reason %#q, got %#q\n", index, test.expectedReason, reason)
+		}
+	}
+}
+
+// Test_Prometheus_GetEtcdEndpoints tests the GetEtcdEndpoints function.
+func Test_Prometheus_GetEtcdEndpoints(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+
+		expectedEndpoints []string
+	}{
+		// 0. Test that a cluster without etcd domain has no endpoints.
+		{
+			annotations: nil,
+
+			expectedEndpoints: nil,
+		},
+
+		// 1. Test that a single etcd domain is returned.
+		{
+			annotations: map[string]string{
+				key.AnnotationEtcdDomain: "etcd.xa5ly:2379",
+			},
+
+			expectedEndpoints: []string{"etcd.xa5ly:2379"},
+		},
+
+		// 2. Test that a list of etcd endpoints is split, and that
+		// whitespace and empty entries are ignored.
+		{
+			annotations: map[string]string{
+				key.AnnotationEtcdDomain: "etcd1.xa5ly:2379, etcd2.xa5ly:2379,,etcd3.xa5ly:2379 ",
+			},
+
+			expectedEndpoints: []string{"etcd1.xa5ly:2379", "etcd2.xa5ly:2379", "etcd3.xa5ly:2379"},
+		},
+
+		// 3. Test that an etcd SRV name does not yield endpoints.
+		{
+			annotations: map[string]string{
+				key.AnnotationEtcdSRV: "_etcd-client._tcp.xa5ly",
+			},
+
+			expectedEndpoints: nil,
+		},
+	}
+
+	for index, test := range tests {
+		service := v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: test.annotations,
+			},
+		}
+
+		endpoints := GetEtcdEndpoints(service)
+		if !reflect.DeepEqual(endpoints, test.expectedEndpoints) {
+			t.Fatalf("%d: expected endpoints %#v, got %#v\n", index, test.expectedEndpoints, endpoints)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package prometheus
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"gopkg.in/yaml.v2"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+)
+
+const (
+	// defaultExporterPath is the metrics path used when an exporter does not
+	// specify one.
+	defaultExporterPath = "metrics"
+)
+
+// Exporter describes an exporter running in workload clusters, which is
+// scraped through the Kubernetes API server pod proxy.
+type Exporter struct {
+	// Name identifies the exporter in the catalog. It is used as Service name
+	// and Pod name prefix when Services or PodNameRegexp are not set.
+	Name string `yaml:"name"`
+	// Namespace is the namespace the exporter's Services and Pods run in.
+	Namespace string `yaml:"namespace"`
+	// Services are the names of the exporter's Services. Targets are
+	// discovered through the endpoints of these Services.
+	Services []string `yaml:"services,omitempty"`
+	// PodNameRegexp matches the exporter's Pod names. The first capture group
+	// must hold the full Pod name.
+	PodNameRegexp relabel.Regexp `yaml:"pod_name_regexp,omitempty"`
+	// Port is the port the exporter exposes metrics on.
+	Port string `yaml:"port"`
+	// Path is the metrics path of the exporter, without leading slash.
+	Path string `yaml:"path,omitempty"`
+	// JobType is the job type the exporter is scraped with. Exporters with
+	// the workload job type share the workload job, all others get a job of
+	// their own.
+	JobType string `yaml:"job_type,omitempty"`
+	// MetricKeep is a list of metric name patterns to keep. All other metrics
+	// are dropped. It can only be used with a dedicated job type no other
+	// exporter uses, because the keep rule applies to the whole job.
+	MetricKeep []string `yaml:"metric_keep,omitempty"`
+	// MetricDrop is a list of metric name patterns to drop.
+	MetricDrop []string `yaml:"metric_drop,omitempty"`
+}
+
+// exporterCatalog is the format of an exporter catalog file.
+type exporterCatalog struct {
+	Exporters []Exporter `yaml:"exporters"`
+}
+
+// DefaultExporters is the exporter catalog used when no catalog is
+// configured.
+var DefaultExporters = []Exporter{
+	{
+		Name:          "kube-state-metrics",
+		Namespace:     key.KubeStateMetricsNamespace,
+		PodNameRegexp: KubeStateMetricsPodNameRegexp,
+		Port:          key.KubeStateMetricsPort,
+	},
+	{
+		Name:          "chart-operator",
+		Namespace:     key.ChartOperatorNamespace,
+		PodNameRegexp: ChartOperatorPodNameRegexp,
+		Port:          key.ChartOperatorMetricPort,
+	},
+	{
+		Name:          "cert-exporter",
+		Namespace:     key.CertExporterNamespace,
+		PodNameRegexp: CertExporterPodNameRegexp,
+		Port:          key.CertExporterMetricPort,
+	},
+	{
+		Name:          "cluster-autoscaler",
+		Namespace:     key.ClusterAutoscalerNamespace,
+		PodNameRegexp: ClusterAutoscalerPodNameRegexp,
+		Port:          key.ClusterAutoscalerMetricPort,
+	},
+	{
+		Name:          "coredns",
+		Namespace:     key.CoreDNSNamespace,
+		PodNameRegexp: CoreDNSPodNameRegexp,
+		Port:          key.CoreDNSMetricPort,
+	},
+	{
+		Name:          "elastic-logging-elasticsearch-exporter",
+		Namespace:     key.ElasticLoggingNamespace,
+		PodNameRegexp: ElasticLoggingPodNameRegexp,
+		Port:          key.ElasticLoggingMetricPort,
+	},
+	{
+		Name:          "net-exporter",
+		Namespace:     key.NetExporterNamespace,
+		PodNameRegexp: NetExporterPodNameRegexp,
+		Port:          key.NetExporterMetricPort,
+	},
+	{
+		Name:          "nic-exporter",
+		Namespace:     key.NicExporterNamespace,
+		PodNameRegexp: NicExporterPodNameRegexp,
+		Port:          key.NicExporterMetricPort,
+	},
+	{
+		Name:          "kiam",
+		Namespace:     key.KiamNamespace,
+		Services:      []string{"kiam-agent", "kiam-server"},
+		PodNameRegexp: KiamPodNameRegexp,
+		Port:          key.KiamMetricPort,
+	},
+	{
+		Name:          "vault-exporter",
+		Namespace:     key.VaultExporterNamespace,
+		PodNameRegexp: VaultExporterPodNameRegexp,
+		Port:          key.VaultExporterMetricPort,
+	},
+}
+
+// reservedJobTypes are the job types generated for every cluster, which
+// exporters must not use.
+var reservedJobTypes = []string{
+	APIServerJobType,
+	AWSNodeJobType,
+	CadvisorJobType,
+	CalicoNodeJobType,
+	DockerDaemonJobType,
+	EtcdJobType,
+	IngressJobType,
+	KubeletJobType,
+	KubeProxyJobType,
+	KubeStateManagedAppJobType,
+	ManagedAppJobType,
+	NodeExporterJobType,
+}
+
+// LoadExporters parses an exporter catalog in YAML format, fills in the
+// defaults and validates every exporter.
+func LoadExporters(data []byte) ([]Exporter, error) {
+	var catalog exporterCatalog
+
+	err := yaml.UnmarshalStrict(data, &catalog)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "failed to parse exporter catalog: %s", err)
+	}
+
+	exporters := []Exporter{}
+	names := map[string]bool{}
+	for _, e := range catalog.Exporters {
+		e = withExporterDefaults(e)
+
+		err := validateExporter(e)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if names[e.Name] {
+			return nil, microerror.Maskf(invalidConfigError, "exporter %#q is defined more than once", e.Name)
+		}
+		names[e.Name] = true
+
+		exporters = append(exporters, e)
+	}
+
+	err = validateExporterKeepLists(exporters)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return exporters, nil
+}
+
+// withExporterDefaults returns a copy of the given exporter with all optional
+// fields set.
+func withExporterDefaults(e Exporter) Exporter {
+	if len(e.Services) == 0 && e.Name != "" {
+		e.Services = []string{e.Name}
+	}
+	if e.PodNameRegexp.Regexp == nil && e.Name != "" {
+		e.PodNameRegexp = relabel.MustNewRegexp(fmt.Sprintf("(%s.*)", regexp.QuoteMeta(e.Name)))
+	}
+	if e.Path == "" {
+		e.Path = defaultExporterPath
+	}
+	if e.JobType == "" {
+		e.JobType = WorkloadJobType
+	}
+
+	return e
+}
+
+func validateExporter(e Exporter) error {
+	if e.Name == "" {
+		return microerror.Maskf(invalidConfigError, "exporter name must not be empty")
+	}
+	if e.Namespace == "" {
+		return microerror.Maskf(invalidConfigError, "exporter %#q namespace must not be empty", e.Name)
+	}
+	if e.Port == "" {
+		return microerror.Maskf(invalidConfigError, "exporter %#q port must not be empty", e.Name)
+	}
+	if e.PodNameRegexp.NumSubexp() < 1 {
+		return microerror.Maskf(invalidConfigError, "exporter %#q pod name regexp must have a capture group", e.Name)
+	}
+	for _, jobType := range reservedJobTypes {
+		if e.JobType == jobType {
+			return microerror.Maskf(invalidConfigError, "exporter %#q must not use reserved job type %#q", e.Name, e.JobType)
+		}
+	}
+	if e.JobType == WorkloadJobType && len(e.MetricKeep) > 0 {
+		return microerror.Maskf(invalidConfigError, "exporter %#q metric keep list requires a dedicated job type", e.Name)
+	}
+	for _, p := range append(append([]string{}, e.MetricKeep...), e.MetricDrop...) {
+		_, err := regexp.Compile(p)
+		if err != nil {
+			return microerror.Maskf(invalidConfigError, "exporter %#q metric pattern %#q is invalid: %s", e.Name, p, err)
+		}
+	}
+
+	return nil
+}
+
+// validateExporterKeepLists ensures that exporters with a keep list do not
+// share their job type with other exporters. The keep rule is not scoped to
+// the exporter, so it would drop the metrics of every other exporter of the
+// job.
+func validateExporterKeepLists(exporters []Exporter) error {
+	jobTypes, exportersByJobType := groupExportersByJobType(exporters)
+
+	for _, jobType := range jobTypes {
+		jobExporters := exportersByJobType[jobType]
+		if len(jobExporters) < 2 {
+			continue
+		}
+		for _, e := range jobExporters {
+			if len(e.MetricKeep) > 0 {
+				return microerror.Maskf(invalidConfigError, "exporter %#q metric keep list requires job type %#q not to be shared with other exporters", e.Name, jobType)
+			}
+		}
+	}
+
+	return nil
+}
+
+// getExporters returns the exporter catalog of the given config, falling back
+// to DefaultExporters.
+func getExporters(metaConfig Config) []Exporter {
+	exporters := metaConfig.Exporters
+	if exporters == nil {
+		exporters = DefaultExporters
+	}
+
+	var defaulted []Exporter
+	for _, e := range exporters {
+		defaulted = append(defaulted, withExporterDefaults(e))
+	}
+
+	return defaulted
+}
+
+// groupExportersByJobType groups exporters by their job type, preserving the
+// catalog order of the job types.
+func groupExportersByJobType(exporters []Exporter) ([]string, map[string][]Exporter) {
+	var jobTypes []string
+	grouped := map[string][]Exporter{}
+
+	for _, e := range exporters {
+		if _, ok := grouped[e.JobType]; !ok {
+			jobTypes = append(jobTypes, e.JobType)
+		}
+		grouped[e.JobType] = append(grouped[e.JobType], e)
+	}
+
+	return jobTypes, grouped
+}
+
+// getExporterWhitelistRegexp returns the regular expression matching
+// "<namespace>;<service>" of all the given exporters.
+func getExporterWhitelistRegexp(exporters []Exporter) relabel.Regexp {
+	var namespaces []string
+	services := map[string][]string{}
+
+	for _, e := range exporters {
+		if _, ok := services[e.Namespace]; !ok {
+			namespaces = append(namespaces, e.Namespace)
+		}
+		for _, s := range e.Services {
+			services[e.Namespace] = append(services[e.Namespace], regexp.QuoteMeta(s))
+		}
+	}
+
+	var groups []string
+	for _, n := range namespaces {
+		s := services[n]
+		sort.Strings(s)
+
+		if len(s) == 1 {
+			groups = append(groups, fmt.Sprintf("(%s;%s)", regexp.QuoteMeta(n), s[0]))
+		} else {
+			groups = append(groups, fmt.Sprintf("(%s;(%s))", regexp.QuoteMeta(n), strings.Join(s, "|")))
+		}
+	}
+
+	return relabel.MustNewRegexp(strings.Join(groups, "|"))
+}
+
+// getExporterNamespaces returns the namespaces whose metrics are kept by the
+// workload job, extended by the namespaces of the given exporters.
+func getExporterNamespaces(exporters []Exporter) string {
+	namespaces := []string{"kube-system", "giantswarm.*"}
+
+	for _, e := range exporters {
+		r := relabel.MustNewRegexp(strings.Join(namespaces, "|"))
+		if !r.MatchString(e.Namespace) {
+			namespaces = append(namespaces, regexp.QuoteMeta(e.Namespace))
+		}
+	}
+
+	return strings.Join(namespaces, "|")
+}
+
+// getExporterPathRelabelConfigs returns relabel configs rewriting the metrics
+// path of the given exporters' targets to the API server pod proxy.
+func getExporterPathRelabelConfigs(exporters []Exporter) []*relabel.Config {
+	var relabelConfigs []*relabel.Config
+
+	for _, e := range exporters {
+		relabelConfigs = append(relabelConfigs, &relabel.Config{
+			SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
+			Regex:        e.PodNameRegexp,
+			TargetLabel:  MetricPathLabel,
+			Replacement:  key.APIProxyPodPath(e.Namespace, e.Port, e.Path),
+		})
+	}
+
+	return relabelConfigs
+}
+
+// getExporterMetricRelabelConfigs returns the metric relabel configs applying
+// the keep and drop lists of the given exporters. Drop lists are scoped to the
+// exporter's namespace and Services, so exporters can share a job. Keep lists
+// apply to the whole job, which LoadExporters ensures holds only the
+// exporter defining them.
+func getExporterMetricRelabelConfigs(exporters []Exporter) []*relabel.Config {
+	var relabelConfigs []*relabel.Config
+
+	for _, e := range exporters {
+		if len(e.MetricKeep) > 0 {
+			relabelConfigs = append(relabelConfigs, &relabel.Config{
+				Action:       ActionKeep,
+				SourceLabels: model.LabelNames{MetricNameLabel},
+				Regex:        relabel.MustNewRegexp(fmt.Sprintf("(%s)", strings.Join(e.MetricKeep, "|"))),
+			})
+		}
+		if len(e.MetricDrop) > 0 {
+			var services []string
+			for _, s := range e.Services {
+				services = append(services, regexp.QuoteMeta(s))
+			}
+
+			relabelConfigs = append(relabelConfigs, &relabel.Config{
+				Action:       ActionDrop,
+				SourceLabels: model.LabelNames{model.LabelName(NamespaceLabel), model.LabelName(AppLabel), MetricNameLabel},
+				Regex: relabel.MustNewRegexp(fmt.Sprintf(
+					"%s;(%s);(%s)",
+					regexp.QuoteMeta(e.Namespace),
+					strings.Join(services, "|"),
+					strings.Join(e.MetricDrop, "|"),
+				)),
+			})
+		}
+	}
+
+	return relabelConfigs
+}
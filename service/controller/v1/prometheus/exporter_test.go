@@ -0,0 +1,304 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/config"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test_Prometheus_LoadExporters tests the LoadExporters function.
+func Test_Prometheus_LoadExporters(t *testing.T) {
+	tests := []struct {
+		data string
+
+		expectedExporterNames []string
+		expectedErrorHandler  func(error) bool
+	}{
+		// 0. Test that an empty catalog returns no exporters.
+		{
+			data: ``,
+
+			expectedExporterNames: []string{},
+			expectedErrorHandler:  nil,
+		},
+
+		// 1. Test that a minimal exporter is loaded.
+		{
+			data: `
+exporters:
+- name: foo-exporter
+  namespace: foo
+  port: "9000"
+`,
+
+			expectedExporterNames: []string{"foo-exporter"},
+			expectedErrorHandler:  nil,
+		},
+
+		// 2. Test that an exporter without a port is rejected.
+		{
+			data: `
+exporters:
+- name: foo-exporter
+  namespace: foo
+`,
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 3. Test that an exporter using a reserved job type is rejected.
+		{
+			data: `
+exporters:
+- name: foo-exporter
+  namespace: foo
+  port: "9000"
+  job_type: kubelet
+`,
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 4. Test that a keep list in the shared workload job is rejected.
+		{
+			data: `
+exporters:
+- name: foo-exporter
+  namespace: foo
+  port: "9000"
+  metric_keep:
+  - foo_.*
+`,
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 5. Test that duplicate exporters are rejected.
+		{
+			data: `
+exporters:
+- name: foo-exporter
+  namespace: foo
+  port: "9000"
+- name: foo-exporter
+  namespace: bar
+  port: "9000"
+`,
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 6. Test that unknown fields are rejected.
+		{
+			data: `
+exporters:
+- name: foo-exporter
+  namespace: foo
+  port: "9000"
+  portt: "9001"
+`,
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 7. Test that two exporters with keep lists sharing a job type are
+		// rejected, as each keep list would drop the other's metrics.
+		{
+			data: `
+exporters:
+- name: foo-exporter
+  namespace: foo
+  port: "9000"
+  job_type: shared
+  metric_keep:
+  - foo_.*
+- name: bar-exporter
+  namespace: bar
+  port: "9000"
+  job_type: shared
+  metric_keep:
+  - bar_.*
+`,
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 8. Test that an exporter with a keep list sharing its job type with
+		// an exporter without keep list is rejected.
+		{
+			data: `
+exporters:
+- name: foo-exporter
+  namespace: foo
+  port: "9000"
+  job_type: shared
+- name: bar-exporter
+  namespace: bar
+  port: "9000"
+  job_type: shared
+  metric_keep:
+  - bar_.*
+`,
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 9. Test that exporters with keep lists are loaded when each has a
+		// job type of its own, and that exporters without keep lists can share
+		// a job type.
+		{
+			data: `
+exporters:
+- name: foo-exporter
+  namespace: foo
+  port: "9000"
+  job_type: foo
+  metric_keep:
+  - foo_.*
+- name: bar-exporter
+  namespace: bar
+  port: "9000"
+  job_type: bar
+  metric_keep:
+  - bar_.*
+- name: baz-exporter
+  namespace: baz
+  port: "9000"
+  job_type: shared
+  metric_drop:
+  - baz_.*
+- name: qux-exporter
+  namespace: qux
+  port: "9000"
+  job_type: shared
+`,
+
+			expectedExporterNames: []string{"foo-exporter", "bar-exporter", "baz-exporter", "qux-exporter"},
+			expectedErrorHandler:  nil,
+		},
+	}
+
+	for index, test := range tests {
+		exporters, err := LoadExporters([]byte(test.data))
+		if err != nil && test.expectedErrorHandler == nil {
+			t.Fatalf("%d: unexpected error returned loading exporters: %s\n", index, err)
+		}
+		if err != nil && !test.expectedErrorHandler(err) {
+			t.Fatalf("%d: incorrect error returned loading exporters: %s\n", index, err)
+		}
+		if err == nil && test.expectedErrorHandler != nil {
+			t.Fatalf("%d: expected error not returned loading exporters\n", index)
+		}
+
+		if test.expectedErrorHandler != nil {
+			continue
+		}
+
+		if len(exporters) != len(test.expectedExporterNames) {
+			t.Fatalf("%d: expected %d exporters, got %d\n", index, len(test.expectedExporterNames), len(exporters))
+		}
+		for i, e := range exporters {
+			if e.Name != test.expectedExporterNames[i] {
+				t.Fatalf("%d: expected exporter %#q, got %#q\n", index, test.expectedExporterNames[i], e.Name)
+			}
+		}
+	}
+}
+
+// Test_Prometheus_getExporterWhitelistRegexp tests the
+// getExporterWhitelistRegexp function.
+func Test_Prometheus_getExporterWhitelistRegexp(t *testing.T) {
+	tests := []struct {
+		exporters []Exporter
+
+		expectedRegexp string
+	}{
+		// 0. Test that the default catalog matches the historical whitelist.
+		{
+			exporters: DefaultExporters,
+
+			expectedRegexp: `(kube-system;(cert-exporter|cluster-autoscaler|coredns|kiam-agent|kiam-server|kube-state-metrics|net-exporter|nic-exporter))|(giantswarm;chart-operator)|(giantswarm-elastic-logging;elastic-logging-elasticsearch-exporter)|(vault-exporter;vault-exporter)`,
+		},
+
+		// 1. Test that exporters are grouped by namespace.
+		{
+			exporters: []Exporter{
+				{Name: "b", Namespace: "foo", Port: "1"},
+				{Name: "a", Namespace: "bar", Port: "1"},
+				{Name: "a", Namespace: "foo", Port: "1"},
+			},
+
+			expectedRegexp: `(foo;(a|b))|(bar;a)`,
+		},
+	}
+
+	for index, test := range tests {
+		var exporters []Exporter
+		for _, e := range test.exporters {
+			exporters = append(exporters, withExporterDefaults(e))
+		}
+
+		r := getExporterWhitelistRegexp(exporters)
+
+		if r.String() != test.expectedRegexp {
+			t.Fatalf("%d: expected regexp %#q, got %#q\n", index, test.expectedRegexp, r.String())
+		}
+	}
+}
+
+// Test_Prometheus_GetScrapeConfigs_DedicatedExporterJob tests that exporters
+// with a dedicated job type get a job of their own.
+func Test_Prometheus_GetScrapeConfigs_DedicatedExporterJob(t *testing.T) {
+	metaConfig := Config{
+		CertDirectory: "/certs",
+		Exporters: []Exporter{
+			{
+				Name:       "foo-exporter",
+				Namespace:  "foo",
+				Port:       "9000",
+				JobType:    "foo",
+				MetricKeep: []string{"foo_.*"},
+			},
+		},
+		Provider: "aws-test",
+	}
+
+	services := []v1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "apiserver",
+				Namespace: "xa5ly",
+				Annotations: map[string]string{
+					ClusterAnnotation: "xa5ly",
+				},
+			},
+		},
+	}
+
+	scrapeConfigs, err := GetScrapeConfigs(services, metaConfig)
+	if err != nil {
+		t.Fatalf("error returned creating scrape configs: %s\n", err)
+	}
+
+	var found *config.ScrapeConfig
+	for i, s := range scrapeConfigs {
+		if s.JobName == "workload-cluster-xa5ly-workload" {
+			t.Fatalf("expected no workload job without workload exporters")
+		}
+		if s.JobName == "workload-cluster-xa5ly-foo" {
+			found = &scrapeConfigs[i]
+		}
+	}
+
+	if found == nil {
+		t.Fatalf("expected job %#q to be generated", "workload-cluster-xa5ly-foo")
+	}
+	if found.RelabelConfigs[0].Regex.String() != "(foo;foo-exporter)" {
+		t.Fatalf("expected whitelist %#q, got %#q", "(foo;foo-exporter)", found.RelabelConfigs[0].Regex.String())
+	}
+	if found.MetricRelabelConfigs[0].Regex.String() != "(foo_.*)" {
+		t.Fatalf("expected keep list %#q, got %#q", "(foo_.*)", found.MetricRelabelConfigs[0].Regex.String())
+	}
+}
@@ -1,7 +1,13 @@
 package prometheus
 
 import (
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/afero"
 	"k8s.io/api/core/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
 )
 
 // FilterInvalidServices takes a list of Kubernetes Services,
@@ -29,3 +35,63 @@ func FilterInvalidServices(services []v1.Service) []v1.Service {
 
 	return filteredServices
 }
+
+// FilterShardServices takes a list of valid Kubernetes Services,
+// and returns the Services of clusters assigned to the given shard.
+// When shardCount is less than two, sharding is disabled and all Services are returned.
+func FilterShardServices(services []v1.Service, shardIndex, shardCount int) []v1.Service {
+	if shardCount <= 1 {
+		return services
+	}
+
+	filteredServices := []v1.Service{}
+
+	for _, service := range services {
+		if GetShard(GetClusterID(service), shardCount) != shardIndex {
+			continue
+		}
+
+		filteredServices = append(filteredServices, service)
+	}
+
+	return filteredServices
+}
+
+// FilterClusters takes a list of Kubernetes Services,
+// and returns the Services not belonging to the given clusters.
+func FilterClusters(services []v1.Service, clusterIDs map[string]bool) []v1.Service {
+	filteredServices := []v1.Service{}
+
+	for _, service := range services {
+		if clusterIDs[GetClusterID(service)] {
+			continue
+		}
+
+		filteredServices = append(filteredServices, service)
+	}
+
+	return filteredServices
+}
+
+// MissingCertificates returns the paths of the certificate files of the given
+// cluster which do not exist in the given certificate directory. Clusters with
+// missing certificate files are not scraped, as Prometheus fails to load a
+// configuration referencing them.
+func MissingCertificates(fs afero.Fs, certDirectory string, clusterID string) ([]string, error) {
+	var missing []string
+
+	for _, p := range []string{
+		key.CAPath(certDirectory, clusterID),
+		key.CrtPath(certDirectory, clusterID),
+		key.KeyPath(certDirectory, clusterID),
+	} {
+		_, err := fs.Stat(p)
+		if os.IsNotExist(err) {
+			missing = append(missing, p)
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return missing, nil
+}
@@ -0,0 +1,251 @@
+package prometheus
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/prometheus/prometheus/config"
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// httpSDConfigsKey is the key of the HTTP service discovery configs of a
+	// scrape config. The vendored Prometheus configuration does not support
+	// them, so they are handled on the YAML tree.
+	httpSDConfigsKey = "http_sd_configs"
+	// jobNameKey is the key of the job name of a scrape config.
+	jobNameKey = "job_name"
+
+	// clusterIDQueryParameter and jobTypeQueryParameter are the query
+	// parameters of the discovery endpoint restricting the served target
+	// groups to a single cluster and job type.
+	clusterIDQueryParameter = "cluster_id"
+	jobTypeQueryParameter   = "job_type"
+
+	// minHTTPSDMajorVersion and minHTTPSDMinorVersion form the first
+	// Prometheus version supporting HTTP service discovery.
+	minHTTPSDMajorVersion = 2
+	minHTTPSDMinorVersion = 28
+)
+
+// ValidateDiscovery checks that the given discovery URL can be used for HTTP
+// service discovery by the given version of Prometheus, e.g. 2.28.1. Older
+// versions fail to load configurations holding HTTP service discovery
+// configs.
+func ValidateDiscovery(discoveryURL, version string) error {
+	err := validateHTTPSDURL(discoveryURL)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var major, minor int
+	_, err = fmt.Sscanf(strings.TrimPrefix(version, "v"), "%d.%d", &major, &minor)
+	if err != nil {
+		return microerror.Maskf(invalidConfigError, "Prometheus version %#q must be of the form <major>.<minor>.<patch>", version)
+	}
+
+	if major < minHTTPSDMajorVersion || (major == minHTTPSDMajorVersion && minor < minHTTPSDMinorVersion) {
+		return microerror.Maskf(invalidConfigError, "Prometheus version %#q does not support HTTP service discovery, which requires %d.%d or later", version, minHTTPSDMajorVersion, minHTTPSDMinorVersion)
+	}
+
+	return nil
+}
+
+// GetHTTPSDURL returns the URL of the given discovery endpoint serving the
+// targets of the given job type of the given cluster.
+func GetHTTPSDURL(discoveryURL, clusterID, jobType string) (string, error) {
+	u, err := url.Parse(discoveryURL)
+	if err != nil {
+		return "", microerror.Maskf(invalidConfigError, "failed to parse discovery URL %#q: %s", discoveryURL, err)
+	}
+
+	q := u.Query()
+	q.Set(clusterIDQueryParameter, clusterID)
+	q.Set(jobTypeQueryParameter, jobType)
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// LoadConfig loads the given Prometheus configuration data like config.Load,
+// ignoring the HTTP service discovery configs of managed jobs.
+func LoadConfig(data string) (*config.Config, error) {
+	var tree yaml.MapSlice
+	err := yaml.Unmarshal([]byte(data), &tree)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	found := false
+	if scrapeConfigs, ok := getMapValue(tree, scrapeConfigsKey).([]interface{}); ok {
+		for i, item := range scrapeConfigs {
+			m, ok := item.(yaml.MapSlice)
+			if !ok || !isManagedJobName(m) {
+				continue
+			}
+
+			stripped := yaml.MapSlice{}
+			for _, mapItem := range m {
+				if mapItem.Key == httpSDConfigsKey {
+					err := validateHTTPSDConfigs(mapItem.Value)
+					if err != nil {
+						jobName, _ := getMapValue(m, jobNameKey).(string)
+						return nil, microerror.Maskf(invalidConfigError, "job %#q has invalid %s: %s", jobName, httpSDConfigsKey, err)
+					}
+
+					found = true
+					continue
+				}
+				stripped = append(stripped, mapItem)
+			}
+			scrapeConfigs[i] = stripped
+		}
+	}
+
+	if found {
+		b, err := yaml.Marshal(tree)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		data = string(b)
+	}
+
+	promcfg, err := config.Load(data)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return promcfg, nil
+}
+
+// addHTTPSDConfigs adds HTTP service discovery configs to the given
+// marshalled scrape configs, for the jobs discovering their targets over HTTP
+// service discovery.
+func addHTTPSDConfigs(scrapeConfigs []interface{}, urls map[string]string) {
+	for i, item := range scrapeConfigs {
+		m, ok := item.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+
+		jobName, _ := getMapValue(m, jobNameKey).(string)
+		u, ok := urls[jobName]
+		if !ok {
+			continue
+		}
+
+		scrapeConfigs[i] = append(m, yaml.MapItem{
+			Key: httpSDConfigsKey,
+			Value: []interface{}{
+				yaml.MapSlice{{Key: "url", Value: u}},
+			},
+		})
+	}
+}
+
+// addHTTPSDConfigsToConfig adds HTTP service discovery configs to the scrape
+// configs of the given marshalled Prometheus configuration, see
+// addHTTPSDConfigs.
+func addHTTPSDConfigsToConfig(data []byte, urls map[string]string) ([]byte, error) {
+	if len(urls) == 0 {
+		return data, nil
+	}
+
+	var tree yaml.MapSlice
+	err := yaml.Unmarshal(data, &tree)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if scrapeConfigs, ok := getMapValue(tree, scrapeConfigsKey).([]interface{}); ok {
+		addHTTPSDConfigs(scrapeConfigs, urls)
+	}
+
+	data, err = yaml.Marshal(tree)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return data, nil
+}
+
+// getHTTPSDURLs takes a list of Kubernetes Services, and returns the HTTP
+// service discovery URLs by job name of the jobs discovering their targets
+// over HTTP service discovery. Currently, these are the etcd jobs.
+func getHTTPSDURLs(services []v1.Service, metaConfig Config) (map[string]string, error) {
+	urls := map[string]string{}
+
+	if metaConfig.DiscoveryURL == "" {
+		return urls, nil
+	}
+
+	filteredServices := FilterInvalidServices(services)
+	filteredServices = FilterShardServices(filteredServices, metaConfig.ShardIndex, metaConfig.ShardCount)
+
+	for _, service := range filteredServices {
+		if ok, _ := GetEtcdScrapeStatus(service, metaConfig); !ok {
+			continue
+		}
+
+		u, err := GetHTTPSDURL(metaConfig.DiscoveryURL, GetClusterID(service), EtcdJobType)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		urls[getJobName(service, EtcdJobType)] = u
+	}
+
+	return urls, nil
+}
+
+// validateHTTPSDConfigs checks the given marshalled HTTP service discovery
+// configs of a managed job, which the vendored Prometheus configuration can
+// not load. Each of them must hold a URL Prometheus accepts.
+func validateHTTPSDConfigs(v interface{}) error {
+	sdConfigs, ok := v.([]interface{})
+	if !ok {
+		return microerror.Maskf(invalidConfigError, "expected a list, got %T", v)
+	}
+
+	for _, item := range sdConfigs {
+		sdConfig, ok := item.(yaml.MapSlice)
+		if !ok {
+			return microerror.Maskf(invalidConfigError, "expected a mapping, got %T", item)
+		}
+
+		u, _ := getMapValue(sdConfig, "url").(string)
+		err := validateHTTPSDURL(u)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// validateHTTPSDURL checks that the given URL is an absolute HTTP or HTTPS
+// URL, as Prometheus requires for HTTP service discovery.
+func validateHTTPSDURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return microerror.Maskf(invalidConfigError, "failed to parse discovery URL %#q: %s", u, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return microerror.Maskf(invalidConfigError, "discovery URL %#q must use the http or https scheme", u)
+	}
+	if parsed.Host == "" {
+		return microerror.Maskf(invalidConfigError, "discovery URL %#q must have a host", u)
+	}
+
+	return nil
+}
+
+// isManagedJobName returns true if the given marshalled scrape config is
+// managed by the prometheus-config-controller, see isManaged.
+func isManagedJobName(scrapeConfig yaml.MapSlice) bool {
+	jobName, _ := getMapValue(scrapeConfig, jobNameKey).(string)
+	return strings.HasPrefix(jobName, jobNamePrefix)
+}
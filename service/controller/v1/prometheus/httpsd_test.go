@@ -0,0 +1,216 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+)
+
+// Test_Prometheus_GetHTTPSDURL tests the GetHTTPSDURL function.
+func Test_Prometheus_GetHTTPSDURL(t *testing.T) {
+	tests := []struct {
+		discoveryURL string
+
+		expectedURL string
+	}{
+		// 0. Test that the cluster ID and job type are added as query
+		// parameters.
+		{
+			discoveryURL: "http://prometheus-config-controller:8000/targets",
+
+			expectedURL: "http://prometheus-config-controller:8000/targets?cluster_id=xa5ly&job_type=etcd",
+		},
+
+		// 1. Test that existing query parameters are preserved.
+		{
+			discoveryURL: "http://prometheus-config-controller:8000/targets?foo=bar",
+
+			expectedURL: "http://prometheus-config-controller:8000/targets?cluster_id=xa5ly&foo=bar&job_type=etcd",
+		},
+	}
+
+	for index, test := range tests {
+		u, err := GetHTTPSDURL(test.discoveryURL, "xa5ly", EtcdJobType)
+		if err != nil {
+			t.Fatalf("%d: error returned getting URL: %s\n", index, err)
+		}
+
+		if u != test.expectedURL {
+			t.Fatalf("%d: expected URL %#q, got %#q\n", index, test.expectedURL, u)
+		}
+	}
+}
+
+// Test_Prometheus_RenderScrapeConfigs_HTTPSD tests that etcd jobs discover
+// their targets over HTTP service discovery when a discovery URL is
+// configured, and that the rendered configuration still loads.
+func Test_Prometheus_RenderScrapeConfigs_HTTPSD(t *testing.T) {
+	services := []v1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "master",
+				Namespace: "xa5ly",
+				Annotations: map[string]string{
+					ClusterAnnotation:        "xa5ly",
+					key.AnnotationEtcdDomain: "etcd1.xa5ly:2379",
+				},
+				CreationTimestamp: metav1.Time{Time: time.Now().Add(-time.Hour)},
+			},
+		},
+	}
+
+	metaConfig := Config{
+		CertDirectory:   "/certs",
+		DiscoveryURL:    "http://prometheus-config-controller:8000/targets",
+		EtcdScrapeDelay: time.Minute,
+		Provider:        "aws",
+	}
+
+	data, err := RenderScrapeConfigs(services, metaConfig)
+	if err != nil {
+		t.Fatalf("error returned rendering scrape configs: %s\n", err)
+	}
+
+	var scrapeConfigs []struct {
+		JobName       string `yaml:"job_name"`
+		StaticConfigs []struct {
+			Targets []string `yaml:"targets"`
+		} `yaml:"static_configs"`
+		HTTPSDConfigs []struct {
+			URL string `yaml:"url"`
+		} `yaml:"http_sd_configs"`
+	}
+	err = yaml.Unmarshal(data, &scrapeConfigs)
+	if err != nil {
+		t.Fatalf("error returned parsing scrape configs: %s\n", err)
+	}
+
+	for _, s := range scrapeConfigs {
+		if s.JobName == "workload-cluster-xa5ly-etcd" {
+			if len(s.StaticConfigs) != 0 {
+				t.Fatalf("expected no static configs, got %#v", s.StaticConfigs)
+			}
+			if len(s.HTTPSDConfigs) != 1 || s.HTTPSDConfigs[0].URL != "http://prometheus-config-controller:8000/targets?cluster_id=xa5ly&job_type=etcd" {
+				t.Fatalf("expected HTTP service discovery config, got %#v", s.HTTPSDConfigs)
+			}
+		} else if len(s.HTTPSDConfigs) != 0 {
+			t.Fatalf("expected no HTTP service discovery config for job %#q, got %#v", s.JobName, s.HTTPSDConfigs)
+		}
+	}
+
+	var list []interface{}
+	err = yaml.Unmarshal(data, &list)
+	if err != nil {
+		t.Fatalf("error returned parsing scrape configs: %s\n", err)
+	}
+	configData, err := yaml.Marshal(yaml.MapSlice{{Key: scrapeConfigsKey, Value: list}})
+	if err != nil {
+		t.Fatalf("error returned marshalling config: %s\n", err)
+	}
+
+	promcfg, err := LoadConfig(string(configData))
+	if err != nil {
+		t.Fatalf("error returned loading config: %s\n", err)
+	}
+	if len(promcfg.ScrapeConfigs) != len(scrapeConfigs) {
+		t.Fatalf("expected %d scrape configs, got %d", len(scrapeConfigs), len(promcfg.ScrapeConfigs))
+	}
+}
+
+// Test_Prometheus_LoadConfig tests that LoadConfig ignores the HTTP service
+// discovery configs of managed jobs only.
+func Test_Prometheus_LoadConfig(t *testing.T) {
+	managed := `scrape_configs:
+- job_name: workload-cluster-xa5ly-etcd
+  http_sd_configs:
+  - url: http://prometheus-config-controller:8000/targets?cluster_id=xa5ly&job_type=etcd
+`
+	_, err := LoadConfig(managed)
+	if err != nil {
+		t.Fatalf("expected managed HTTP service discovery configs to be ignored, got %s", err)
+	}
+
+	unmanaged := strings.Replace(managed, "workload-cluster-xa5ly-etcd", "foo", 1)
+	_, err = LoadConfig(unmanaged)
+	if err == nil {
+		t.Fatalf("expected unmanaged HTTP service discovery configs to be loaded, got nil")
+	}
+
+	invalid := strings.Replace(managed, "http://prometheus-config-controller:8000", "prometheus-config-controller:8000", 1)
+	_, err = LoadConfig(invalid)
+	if !IsInvalidConfig(err) {
+		t.Fatalf("expected managed HTTP service discovery configs with invalid URL to be rejected, got %v", err)
+	}
+}
+
+// Test_Prometheus_ValidateDiscovery tests the ValidateDiscovery function.
+func Test_Prometheus_ValidateDiscovery(t *testing.T) {
+	tests := []struct {
+		discoveryURL string
+		version      string
+
+		expectedErrorHandler func(error) bool
+	}{
+		// 0. Test that a Prometheus version supporting HTTP service discovery
+		// is accepted.
+		{
+			discoveryURL: "http://prometheus-config-controller:8000/targets",
+			version:      "2.28.0",
+
+			expectedErrorHandler: nil,
+		},
+
+		// 1. Test that versions with v prefix and later major versions are
+		// accepted.
+		{
+			discoveryURL: "https://prometheus-config-controller:8000/targets",
+			version:      "v3.0.1",
+
+			expectedErrorHandler: nil,
+		},
+
+		// 2. Test that a Prometheus version without HTTP service discovery is
+		// rejected.
+		{
+			discoveryURL: "http://prometheus-config-controller:8000/targets",
+			version:      "2.27.1",
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 3. Test that an unknown Prometheus version is rejected.
+		{
+			discoveryURL: "http://prometheus-config-controller:8000/targets",
+			version:      "",
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 4. Test that a discovery URL without scheme is rejected.
+		{
+			discoveryURL: "prometheus-config-controller:8000/targets",
+			version:      "2.28.0",
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+	}
+
+	for index, test := range tests {
+		err := ValidateDiscovery(test.discoveryURL, test.version)
+		if err != nil && test.expectedErrorHandler == nil {
+			t.Fatalf("%d: unexpected error returned validating discovery: %s\n", index, err)
+		}
+		if err != nil && !test.expectedErrorHandler(err) {
+			t.Fatalf("%d: incorrect error returned validating discovery: %s\n", index, err)
+		}
+		if err == nil && test.expectedErrorHandler != nil {
+			t.Fatalf("%d: expected error not returned validating discovery\n", index)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package prometheus
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+	v1 "k8s.io/api/core/v1"
+)
+
+// scrapeOverride holds the scrape interval and timeout set through Service
+// annotations. Zero values are not overridden.
+type scrapeOverride struct {
+	interval model.Duration
+	timeout  model.Duration
+}
+
+// ValidateScrapeOverrides validates the scrape interval, timeout and sample
+// limit annotations of the given Service. Overrides of Services failing
+// validation are ignored by GetScrapeConfigs.
+func ValidateScrapeOverrides(service v1.Service, metaConfig Config) error {
+	overrides, err := getScrapeOverrides(service, metaConfig)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = getSampleLimitOverrides(service, metaConfig)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, jobType := range getJobTypes(metaConfig) {
+		_, _, _, err := resolveScrapeOverride(overrides, jobType, metaConfig)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// getJobTypes returns all job types generated for a cluster.
+func getJobTypes(metaConfig Config) []string {
+	jobTypes := append([]string{}, reservedJobTypes...)
+
+	exporterJobTypes, _ := groupExportersByJobType(getExporters(metaConfig))
+	jobTypes = append(jobTypes, exporterJobTypes...)
+
+	sort.Strings(jobTypes)
+
+	return jobTypes
+}
+
+// getScrapeOverrides parses the scrape interval and timeout annotations of the
+// given Service. Cluster wide overrides are returned under the empty job type.
+func getScrapeOverrides(service v1.Service, metaConfig Config) (map[string]scrapeOverride, error) {
+	knownJobTypes := map[string]bool{}
+	for _, jobType := range getJobTypes(metaConfig) {
+		knownJobTypes[jobType] = true
+	}
+
+	overrides := map[string]scrapeOverride{}
+
+	for k, v := range service.Annotations {
+		var isInterval bool
+		var jobType string
+		switch {
+		case k == ScrapeIntervalAnnotation:
+			isInterval = true
+		case strings.HasPrefix(k, ScrapeIntervalAnnotation+"."):
+			isInterval = true
+			jobType = strings.TrimPrefix(k, ScrapeIntervalAnnotation+".")
+		case k == ScrapeTimeoutAnnotation:
+		case strings.HasPrefix(k, ScrapeTimeoutAnnotation+"."):
+			jobType = strings.TrimPrefix(k, ScrapeTimeoutAnnotation+".")
+		default:
+			continue
+		}
+
+		if jobType != "" && !knownJobTypes[jobType] {
+			return nil, microerror.Maskf(invalidScrapeOverrideError, "annotation %#q refers to unknown job type %#q", k, jobType)
+		}
+
+		d, err := model.ParseDuration(v)
+		if err != nil {
+			return nil, microerror.Maskf(invalidScrapeOverrideError, "annotation %#q has invalid duration %#q: %s", k, v, err)
+		}
+		if d <= 0 {
+			return nil, microerror.Maskf(invalidScrapeOverrideError, "annotation %#q must be a positive duration", k)
+		}
+
+		o := overrides[jobType]
+		if isInterval {
+			o.interval = d
+		} else {
+			o.timeout = d
+		}
+		overrides[jobType] = o
+	}
+
+	return overrides, nil
+}
+
+// resolveScrapeOverride returns the scrape interval and timeout of the given
+// job type, following the precedence job type override, cluster override and
+// global default. When no override applies, ok is false.
+//
+// As Prometheus rejects configurations where the scrape timeout exceeds the
+// scrape interval, an explicitly set timeout greater than the interval is an
+// error, while an inherited timeout is capped to the interval.
+func resolveScrapeOverride(overrides map[string]scrapeOverride, jobType string, metaConfig Config) (model.Duration, model.Duration, bool, error) {
+	cluster := overrides[""]
+	job := overrides[jobType]
+
+	if cluster.interval == 0 && cluster.timeout == 0 && job.interval == 0 && job.timeout == 0 {
+		return 0, 0, false, nil
+	}
+
+	interval := metaConfig.GlobalScrapeInterval
+	if interval == 0 {
+		interval = config.DefaultGlobalConfig.ScrapeInterval
+	}
+	if cluster.interval != 0 {
+		interval = cluster.interval
+	}
+	if job.interval != 0 {
+		interval = job.interval
+	}
+
+	var explicitTimeout bool
+	timeout := metaConfig.GlobalScrapeTimeout
+	if timeout == 0 {
+		timeout = config.DefaultGlobalConfig.ScrapeTimeout
+	}
+	if cluster.timeout != 0 {
+		explicitTimeout = true
+		timeout = cluster.timeout
+	}
+	if job.timeout != 0 {
+		explicitTimeout = true
+		timeout = job.timeout
+	}
+
+	if timeout > interval {
+		if explicitTimeout {
+			return 0, 0, false, microerror.Maskf(invalidScrapeOverrideError, "scrape timeout %s of job type %#q is greater than scrape interval %s", timeout, jobType, interval)
+		}
+
+		timeout = interval
+	}
+
+	return interval, timeout, true, nil
+}
+
+// applyScrapeOverrides sets the scrape interval and timeout of the given jobs
+// from the Service's annotations. Invalid overrides are ignored, see
+// ValidateScrapeOverrides.
+func applyScrapeOverrides(service v1.Service, metaConfig Config, scrapeConfigs []config.ScrapeConfig) {
+	err := ValidateScrapeOverrides(service, metaConfig)
+	if err != nil {
+		return
+	}
+
+	overrides, err := getScrapeOverrides(service, metaConfig)
+	if err != nil {
+		return
+	}
+
+	for i := range scrapeConfigs {
+		jobType := strings.TrimPrefix(scrapeConfigs[i].JobName, getJobName(service, ""))
+
+		interval, timeout, ok, err := resolveScrapeOverride(overrides, jobType, metaConfig)
+		if err != nil || !ok {
+			continue
+		}
+
+		scrapeConfigs[i].ScrapeInterval = interval
+		scrapeConfigs[i].ScrapeTimeout = timeout
+	}
+}
@@ -0,0 +1,192 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test_Prometheus_ValidateScrapeOverrides tests the ValidateScrapeOverrides
+// function.
+func Test_Prometheus_ValidateScrapeOverrides(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+
+		expectedErrorHandler func(error) bool
+	}{
+		// 0. Test that a Service without overrides is valid.
+		{
+			annotations: nil,
+
+			expectedErrorHandler: nil,
+		},
+
+		// 1. Test that cluster wide and job type overrides are valid.
+		{
+			annotations: map[string]string{
+				ScrapeIntervalAnnotation:                        "2m",
+				ScrapeTimeoutAnnotation + "." + CadvisorJobType: "1m",
+			},
+
+			expectedErrorHandler: nil,
+		},
+
+		// 2. Test that an invalid duration is rejected.
+		{
+			annotations: map[string]string{
+				ScrapeIntervalAnnotation: "2 minutes",
+			},
+
+			expectedErrorHandler: IsInvalidScrapeOverride,
+		},
+
+		// 3. Test that an unknown job type is rejected.
+		{
+			annotations: map[string]string{
+				ScrapeIntervalAnnotation + ".cadvisr": "2m",
+			},
+
+			expectedErrorHandler: IsInvalidScrapeOverride,
+		},
+
+		// 4. Test that a timeout greater than the interval is rejected.
+		{
+			annotations: map[string]string{
+				ScrapeIntervalAnnotation + "." + KubeletJobType: "30s",
+				ScrapeTimeoutAnnotation:                         "45s",
+			},
+
+			expectedErrorHandler: IsInvalidScrapeOverride,
+		},
+
+		// 5. Test that a timeout greater than the global interval is rejected.
+		{
+			annotations: map[string]string{
+				ScrapeTimeoutAnnotation: "2m",
+			},
+
+			expectedErrorHandler: IsInvalidScrapeOverride,
+		},
+	}
+
+	for index, test := range tests {
+		service := v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "apiserver",
+				Namespace:   "xa5ly",
+				Annotations: test.annotations,
+			},
+		}
+
+		err := ValidateScrapeOverrides(service, Config{})
+		if err != nil && test.expectedErrorHandler == nil {
+			t.Fatalf("%d: unexpected error returned validating scrape overrides: %s\n", index, err)
+		}
+		if err != nil && !test.expectedErrorHandler(err) {
+			t.Fatalf("%d: incorrect error returned validating scrape overrides: %s\n", index, err)
+		}
+		if err == nil && test.expectedErrorHandler != nil {
+			t.Fatalf("%d: expected error not returned validating scrape overrides\n", index)
+		}
+	}
+}
+
+// Test_Prometheus_GetScrapeConfigs_ScrapeOverrides tests that scrape
+// overrides are applied to the generated jobs.
+func Test_Prometheus_GetScrapeConfigs_ScrapeOverrides(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+
+		expectedIntervals map[string]model.Duration
+		expectedTimeouts  map[string]model.Duration
+	}{
+		// 0. Test that jobs without overrides inherit the global defaults.
+		{
+			annotations: map[string]string{
+				ClusterAnnotation: "xa5ly",
+			},
+
+			expectedIntervals: map[string]model.Duration{
+				CadvisorJobType: 0,
+				KubeletJobType:  0,
+			},
+			expectedTimeouts: map[string]model.Duration{
+				CadvisorJobType: 0,
+				KubeletJobType:  0,
+			},
+		},
+
+		// 1. Test that job type overrides take precedence over cluster wide
+		// overrides, and inherited timeouts are capped to the interval.
+		{
+			annotations: map[string]string{
+				ClusterAnnotation:                                "xa5ly",
+				ScrapeIntervalAnnotation:                         "5s",
+				ScrapeIntervalAnnotation + "." + CadvisorJobType: "2m",
+				ScrapeTimeoutAnnotation + "." + CadvisorJobType:  "90s",
+			},
+
+			expectedIntervals: map[string]model.Duration{
+				CadvisorJobType: model.Duration(2 * time.Minute),
+				KubeletJobType:  model.Duration(5 * time.Second),
+			},
+			expectedTimeouts: map[string]model.Duration{
+				CadvisorJobType: model.Duration(90 * time.Second),
+				KubeletJobType:  model.Duration(5 * time.Second),
+			},
+		},
+
+		// 2. Test that invalid overrides are ignored.
+		{
+			annotations: map[string]string{
+				ClusterAnnotation:        "xa5ly",
+				ScrapeIntervalAnnotation: "30s",
+				ScrapeTimeoutAnnotation:  "1m",
+			},
+
+			expectedIntervals: map[string]model.Duration{
+				CadvisorJobType: 0,
+				KubeletJobType:  0,
+			},
+			expectedTimeouts: map[string]model.Duration{
+				CadvisorJobType: 0,
+				KubeletJobType:  0,
+			},
+		},
+	}
+
+	for index, test := range tests {
+		services := []v1.Service{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "apiserver",
+					Namespace:   "xa5ly",
+					Annotations: test.annotations,
+				},
+			},
+		}
+
+		scrapeConfigs, err := GetScrapeConfigs(services, Config{CertDirectory: "/certs", Provider: "aws-test"})
+		if err != nil {
+			t.Fatalf("%d: error returned creating scrape configs: %s\n", index, err)
+		}
+
+		for _, s := range scrapeConfigs {
+			for jobType, interval := range test.expectedIntervals {
+				if s.JobName != getJobName(services[0], jobType) {
+					continue
+				}
+
+				if s.ScrapeInterval != interval {
+					t.Fatalf("%d: expected job %#q scrape interval %s, got %s\n", index, s.JobName, interval, s.ScrapeInterval)
+				}
+				if s.ScrapeTimeout != test.expectedTimeouts[jobType] {
+					t.Fatalf("%d: expected job %#q scrape timeout %s, got %s\n", index, s.JobName, test.expectedTimeouts[jobType], s.ScrapeTimeout)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package prometheus
+
+import (
+	"reflect"
+
+	"github.com/giantswarm/microerror"
+	"github.com/prometheus/prometheus/config"
+	"gopkg.in/yaml.v2"
+)
+
+// secretPlaceholder is what Prometheus marshals secrets to, see
+// github.com/prometheus/common/config.Secret.
+const secretPlaceholder = "<secret>"
+
+// sequenceIdentityKeys are the keys identifying items of sequences in the
+// Prometheus configuration, used to match items of the marshalled
+// configuration to the original one. Items match when the values of all
+// identity keys are equal. Items without identity key are matched by index.
+var sequenceIdentityKeys = []string{
+	"job_name",
+	"name",
+	"url",
+}
+
+// MarshalConfig marshals the given Prometheus configuration to YAML. As
+// Prometheus masks secrets when marshalling, every secret is restored from the
+// original configuration data the configuration was loaded from, wherever it
+// is located.
+func MarshalConfig(promcfg config.Config, original string) ([]byte, error) {
+	data, err := yaml.Marshal(promcfg)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var updatedTree yaml.MapSlice
+	err = yaml.Unmarshal(data, &updatedTree)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var originalTree yaml.MapSlice
+	err = yaml.Unmarshal([]byte(original), &originalTree)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	data, err = yaml.Marshal(restoreSecrets(updatedTree, originalTree))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return data, nil
+}
+
+// restoreSecrets walks the updated YAML tree, and replaces masked secrets with
+// the value found at the same location of the original YAML tree.
+func restoreSecrets(updated, original interface{}) interface{} {
+	switch u := updated.(type) {
+	case yaml.MapSlice:
+		o, _ := original.(yaml.MapSlice)
+		for i := range u {
+			u[i].Value = restoreSecrets(u[i].Value, getMapValue(o, u[i].Key))
+		}
+		return u
+
+	case []interface{}:
+		o, _ := original.([]interface{})
+		for i := range u {
+			u[i] = restoreSecrets(u[i], getSequenceItem(o, u[i], i))
+		}
+		return u
+
+	case string:
+		// Secrets written as other scalars, e.g. numeric passwords, are
+		// restored as they were written.
+		switch original.(type) {
+		case nil, yaml.MapSlice, []interface{}:
+			return u
+		}
+		if u == secretPlaceholder {
+			return original
+		}
+		return u
+	}
+
+	return updated
+}
+
+// getMapValue returns the value of the given key, or nil if it does not exist.
+func getMapValue(m yaml.MapSlice, key interface{}) interface{} {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+
+	return nil
+}
+
+// getSequenceItem returns the item of the original sequence matching the given
+// item at the given index, or nil if there is none. When several original
+// items share the identity of the given item, the one at the same index is
+// preferred.
+func getSequenceItem(original []interface{}, item interface{}, index int) interface{} {
+	if m, ok := item.(yaml.MapSlice); ok && hasIdentity(m) {
+		var matches []int
+		for i, o := range original {
+			if om, ok := o.(yaml.MapSlice); ok && isSameIdentity(m, om) {
+				matches = append(matches, i)
+			}
+		}
+
+		for _, i := range matches {
+			if i == index {
+				return original[i]
+			}
+		}
+		if len(matches) > 0 {
+			return original[matches[0]]
+		}
+
+		return nil
+	}
+
+	if index < len(original) {
+		return original[index]
+	}
+
+	return nil
+}
+
+// hasIdentity returns true if the given item has at least one identity key,
+// see sequenceIdentityKeys.
+func hasIdentity(item yaml.MapSlice) bool {
+	for _, key := range sequenceIdentityKeys {
+		if getMapValue(item, key) != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSameIdentity returns true if the values of all identity keys of the given
+// items are equal, see sequenceIdentityKeys.
+func isSameIdentity(a, b yaml.MapSlice) bool {
+	for _, key := range sequenceIdentityKeys {
+		if !reflect.DeepEqual(getMapValue(a, key), getMapValue(b, key)) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,280 @@
+package prometheus
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/prometheus/config"
+	"gopkg.in/yaml.v2"
+)
+
+// Test_Prometheus_MarshalConfig tests that MarshalConfig preserves all secrets
+// of the original configuration.
+func Test_Prometheus_MarshalConfig(t *testing.T) {
+	tests := []struct {
+		original string
+
+		expectedSecrets []string
+	}{
+		// 0. Test that a configuration without secrets is marshalled.
+		{
+			original: `
+global:
+  scrape_interval: 1m
+`,
+
+			expectedSecrets: nil,
+		},
+
+		// 1. Test that the passwords of multiple remote writes are preserved.
+		{
+			original: `
+remote_write:
+- url: https://cortex-a.example.com/api/prom/push
+  basic_auth:
+    username: a
+    password: password-a
+- url: https://cortex-b.example.com/api/prom/push
+  basic_auth:
+    username: b
+    password: password-b
+`,
+
+			expectedSecrets: []string{"password-a", "password-b"},
+		},
+
+		// 2. Test that secrets of alerting and unmanaged scrape configs are
+		// preserved, even when scrape configs are added in front of them.
+		{
+			original: `
+alerting:
+  alertmanagers:
+  - basic_auth:
+      username: alertmanager
+      password: alertmanager-password
+    static_configs:
+    - targets:
+      - alertmanager:9093
+remote_read:
+- url: https://cortex.example.com/api/prom/read
+  bearer_token: remote-read-token
+scrape_configs:
+- job_name: workload-cluster-xa5ly-apiserver
+  static_configs:
+  - targets:
+    - apiserver.xa5ly
+- job_name: federation
+  bearer_token: federation-token
+  static_configs:
+  - targets:
+    - prometheus.example.com
+`,
+
+			expectedSecrets: []string{"alertmanager-password", "remote-read-token", "federation-token"},
+		},
+
+		// 3. Test that the passwords of remote writes sharing a URL are
+		// matched by name.
+		{
+			original: `
+remote_write:
+- url: https://cortex.example.com/api/prom/push
+  name: tenant-a
+  basic_auth:
+    username: a
+    password: password-a
+- url: https://cortex.example.com/api/prom/push
+  name: tenant-b
+  basic_auth:
+    username: b
+    password: password-b
+`,
+
+			expectedSecrets: []string{"password-a", "password-b"},
+		},
+
+		// 4. Test that secrets written as other scalars than strings are
+		// preserved.
+		{
+			original: `
+remote_write:
+- url: https://cortex.example.com/api/prom/push
+  basic_auth:
+    username: a
+    password: 12345
+`,
+
+			expectedSecrets: []string{"password: 12345"},
+		},
+	}
+
+	for index, test := range tests {
+		promcfg, err := config.Load(test.original)
+		if err != nil {
+			t.Fatalf("%d: error returned loading config: %s\n", index, err)
+		}
+
+		scrapeConfigs := []config.ScrapeConfig{
+			{
+				JobName: "workload-cluster-0ba9v-apiserver",
+			},
+		}
+
+		newPromcfg, err := UpdateConfig(*promcfg, scrapeConfigs)
+		if err != nil {
+			t.Fatalf("%d: error returned updating config: %s\n", index, err)
+		}
+
+		data, err := MarshalConfig(newPromcfg, test.original)
+		if err != nil {
+			t.Fatalf("%d: error returned marshalling config: %s\n", index, err)
+		}
+
+		if strings.Contains(string(data), secretPlaceholder) {
+			t.Fatalf("%d: expected no masked secrets, got:\n%s\n", index, data)
+		}
+		for _, secret := range test.expectedSecrets {
+			if !strings.Contains(string(data), secret) {
+				t.Fatalf("%d: expected secret %#q, got:\n%s\n", index, secret, data)
+			}
+		}
+	}
+}
+
+// Test_Prometheus_restoreSecrets tests that masked secrets are restored from
+// the matching location of the original YAML tree.
+func Test_Prometheus_restoreSecrets(t *testing.T) {
+	tests := []struct {
+		updated  string
+		original string
+
+		expected string
+	}{
+		// 0. Test that items sharing a URL are matched by their full
+		// identity, regardless of their order.
+		{
+			updated: `
+remote_write:
+- url: https://cortex.example.com/api/prom/push
+  name: tenant-b
+  password: <secret>
+- url: https://cortex.example.com/api/prom/push
+  name: tenant-a
+  password: <secret>
+`,
+			original: `
+remote_write:
+- url: https://cortex.example.com/api/prom/push
+  name: tenant-a
+  password: password-a
+- url: https://cortex.example.com/api/prom/push
+  name: tenant-b
+  password: password-b
+`,
+
+			expected: `
+remote_write:
+- url: https://cortex.example.com/api/prom/push
+  name: tenant-b
+  password: password-b
+- url: https://cortex.example.com/api/prom/push
+  name: tenant-a
+  password: password-a
+`,
+		},
+
+		// 1. Test that duplicated identities fall back to the index.
+		{
+			updated: `
+remote_write:
+- url: https://cortex.example.com/api/prom/push
+  password: <secret>
+- url: https://cortex.example.com/api/prom/push
+  password: <secret>
+`,
+			original: `
+remote_write:
+- url: https://cortex.example.com/api/prom/push
+  password: password-a
+- url: https://cortex.example.com/api/prom/push
+  password: password-b
+`,
+
+			expected: `
+remote_write:
+- url: https://cortex.example.com/api/prom/push
+  password: password-a
+- url: https://cortex.example.com/api/prom/push
+  password: password-b
+`,
+		},
+
+		// 2. Test that secrets written as other scalars than strings are
+		// restored.
+		{
+			updated: `
+remote_write:
+- url: https://cortex.example.com/api/prom/push
+  password: <secret>
+  bearer_token: <secret>
+`,
+			original: `
+remote_write:
+- url: https://cortex.example.com/api/prom/push
+  password: 12345
+  bearer_token: true
+`,
+
+			expected: `
+remote_write:
+- url: https://cortex.example.com/api/prom/push
+  password: 12345
+  bearer_token: true
+`,
+		},
+
+		// 3. Test that secrets without original value stay masked.
+		{
+			updated: `
+remote_write:
+- url: https://cortex-b.example.com/api/prom/push
+  password: <secret>
+`,
+			original: `
+remote_write:
+- url: https://cortex-a.example.com/api/prom/push
+  password: password-a
+`,
+
+			expected: `
+remote_write:
+- url: https://cortex-b.example.com/api/prom/push
+  password: <secret>
+`,
+		},
+	}
+
+	for index, test := range tests {
+		var updated, original, expected yaml.MapSlice
+		for _, u := range []struct {
+			data string
+			tree *yaml.MapSlice
+		}{
+			{test.updated, &updated},
+			{test.original, &original},
+			{test.expected, &expected},
+		} {
+			err := yaml.Unmarshal([]byte(u.data), u.tree)
+			if err != nil {
+				t.Fatalf("%d: error returned parsing YAML: %s\n", index, err)
+			}
+		}
+
+		restored := restoreSecrets(updated, original)
+
+		if !reflect.DeepEqual(restored, expected) {
+			t.Fatalf("%d: expected %#v, got %#v\n", index, expected, restored)
+		}
+	}
+}
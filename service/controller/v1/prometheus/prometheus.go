@@ -1,6 +1,8 @@
 package prometheus
 
 import (
+	"time"
+
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/relabel"
 	v1 "k8s.io/api/core/v1"
@@ -10,6 +12,32 @@ const (
 	// ClusterAnnotation is the Kubernetes annotation that identifies Services
 	// that the prometheus-config-controller should scrape.
 	ClusterAnnotation = "giantswarm.io/prometheus-cluster"
+
+	// APIEndpointAnnotation is the Kubernetes annotation that holds the
+	// address of the cluster's API server, for clusters whose API server is
+	// not reachable through the Service's name and namespace.
+	APIEndpointAnnotation = "giantswarm.io/prometheus-api-endpoint"
+
+	// ProviderAnnotation is the Kubernetes annotation that holds the provider
+	// of the cluster, e.g. "aws" or "azure". It takes precedence over the
+	// giantswarm.io/provider label and the globally configured provider.
+	ProviderAnnotation = "giantswarm.io/prometheus-provider"
+
+	// ScrapeIntervalAnnotation is the Kubernetes annotation that overrides the
+	// scrape interval of all jobs of a cluster. Suffixed with "." and a job
+	// type, e.g. "giantswarm.io/prometheus-scrape-interval.cadvisor", it
+	// overrides the scrape interval of that job only.
+	ScrapeIntervalAnnotation = "giantswarm.io/prometheus-scrape-interval"
+
+	// ScrapeTimeoutAnnotation is the Kubernetes annotation that overrides the
+	// scrape timeout of all jobs of a cluster. It can be suffixed with a job
+	// type like ScrapeIntervalAnnotation.
+	ScrapeTimeoutAnnotation = "giantswarm.io/prometheus-scrape-timeout"
+
+	// SampleLimitAnnotation is the Kubernetes annotation that overrides the
+	// sample limit of all jobs of a cluster. It can be suffixed with a job
+	// type like ScrapeIntervalAnnotation. A limit of 0 disables the limit.
+	SampleLimitAnnotation = "giantswarm.io/prometheus-sample-limit"
 )
 
 // Prometheus Kubernetes service discovery labels.
@@ -165,6 +193,9 @@ var (
 	// ProviderLabel is the label used to hold the provider type.
 	ProviderLabel = "provider"
 
+	// MemberLabel is the label used to hold the etcd member.
+	MemberLabel = "member"
+
 	// NodeLabel is the label used to hold the node name.
 	NodeLabel = "node"
 
@@ -337,6 +368,10 @@ var (
 
 	ManagedAppSourceRegexp = relabel.MustNewRegexp(`(.*);(.*);(.*);(.*)`)
 
+	// HostPortRegexp is the regular expression to match against an address,
+	// and capture the host without port.
+	HostPortRegexp = relabel.MustNewRegexp(`(.+?)(?::\d+)?`)
+
 	// NodeExporterRegexp is the regular expression to match against the
 	// node-exporter name.
 	NodeExporterRegexp = relabel.MustNewRegexp(`kube-system;node-exporter`)
@@ -345,16 +380,50 @@ var (
 	// node-exporter IP (including port), and capture the IP.
 	NodeExporterPortRegexp = relabel.MustNewRegexp(`(.*):10300`)
 
-	// ServiceWhitelistRegexp is the regular expression to match workload targets to scrape.
-	ServiceWhitelistRegexp = relabel.MustNewRegexp(`(kube-system;(cert-exporter|cluster-autoscaler|coredns|kiam-agent|kiam-server|kube-state-metrics|net-exporter|nic-exporter))|(giantswarm;chart-operator)|(giantswarm-elastic-logging;elastic-logging-elasticsearch-exporter)|(vault-exporter;vault-exporter)`)
-
 	// IngressWhitelistRegexp is the regular expression to match workload targets to scrape.
 	IngressWhitelistRegexp = relabel.MustNewRegexp(`(kube-system;nginx-ingress-controller)`)
 )
 
 type Config struct {
 	CertDirectory string
-	Provider      string
+	// DiscoveryURL is the URL of the controller's targets endpoint. When
+	// set, etcd jobs discover their targets from it over HTTP service
+	// discovery instead of static and DNS SRV configs, so that changes of
+	// etcd members do not require a reload. This requires Prometheus 2.28 or
+	// later.
+	DiscoveryURL string
+	// EtcdScrapeMode selects how etcd scraping is enabled for a cluster, one
+	// of EtcdScrapeModeDelay, EtcdScrapeModeAnnotation and
+	// EtcdScrapeModeProbe. Defaults to EtcdScrapeModeDelay.
+	EtcdScrapeMode string
+	// EtcdScrapeDelay is the minimum age of a cluster before etcd is scraped
+	// in EtcdScrapeModeDelay.
+	EtcdScrapeDelay time.Duration
+	// EtcdProbeErrors holds the results of probing etcd by cluster ID, used
+	// in EtcdScrapeModeProbe. Clusters without result are not scraped.
+	EtcdProbeErrors map[string]error
+	// Exporters is the exporter catalog to generate jobs from. When nil,
+	// DefaultExporters is used.
+	Exporters []Exporter
+	// GlobalScrapeInterval and GlobalScrapeTimeout are the global defaults of
+	// the Prometheus configuration the jobs are written to. Scrape overrides
+	// are validated against them. When zero, the Prometheus defaults are used.
+	GlobalScrapeInterval model.Duration
+	GlobalScrapeTimeout  model.Duration
+	// Provider is the provider of clusters not specifying their own, see
+	// GetProvider.
+	Provider string
+	// SampleLimits are the default sample limits by job type. Job types
+	// without limit are not limited, unless overridden, see GetSampleLimits.
+	SampleLimits map[string]uint
+	// ShardCount is the number of Prometheus shards clusters are distributed
+	// across, ShardIndex the shard to generate jobs for. Sharding is disabled
+	// when ShardCount is less than two.
+	ShardCount int
+	ShardIndex int
+	// Now is the time cluster ages are computed against. When zero, the
+	// current time is used.
+	Now time.Time
 }
 
 // GetClusterID returns the value of the cluster annotation.
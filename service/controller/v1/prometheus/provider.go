@@ -0,0 +1,34 @@
+package prometheus
+
+import (
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/pkg/label"
+)
+
+const (
+	// awsProvider is the name of the AWS provider. Providers prefixed with
+	// "aws-" are considered AWS providers as well.
+	awsProvider = "aws"
+)
+
+// GetProvider returns the provider of the given Service's cluster, taken from
+// ProviderAnnotation, falling back to the provider label and then to the
+// provider of the given config.
+func GetProvider(service v1.Service, metaConfig Config) string {
+	if provider := service.Annotations[ProviderAnnotation]; provider != "" {
+		return provider
+	}
+	if provider := service.Labels[label.Provider]; provider != "" {
+		return provider
+	}
+
+	return metaConfig.Provider
+}
+
+// isAWSProvider returns whether the given provider is AWS.
+func isAWSProvider(provider string) bool {
+	return provider == awsProvider || strings.HasPrefix(provider, awsProvider+"-")
+}
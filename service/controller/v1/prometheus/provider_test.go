@@ -0,0 +1,97 @@
+package prometheus
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/pkg/label"
+)
+
+// Test_Prometheus_GetProvider tests the GetProvider function, and that
+// provider specific jobs follow the cluster's provider.
+func Test_Prometheus_GetProvider(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		labels      map[string]string
+
+		expectedProvider   string
+		expectedAWSNodeJob bool
+	}{
+		// 0. Test that the configured provider is used by default.
+		{
+			annotations: nil,
+			labels:      nil,
+
+			expectedProvider:   "aws",
+			expectedAWSNodeJob: true,
+		},
+
+		// 1. Test that the provider label overrides the configured provider.
+		{
+			annotations: nil,
+			labels: map[string]string{
+				label.Provider: "kvm",
+			},
+
+			expectedProvider:   "kvm",
+			expectedAWSNodeJob: false,
+		},
+
+		// 2. Test that the provider annotation overrides the provider label.
+		{
+			annotations: map[string]string{
+				ProviderAnnotation: "azure",
+			},
+			labels: map[string]string{
+				label.Provider: "kvm",
+			},
+
+			expectedProvider:   "azure",
+			expectedAWSNodeJob: false,
+		},
+	}
+
+	for index, test := range tests {
+		annotations := map[string]string{
+			ClusterAnnotation: "xa5ly",
+		}
+		for k, v := range test.annotations {
+			annotations[k] = v
+		}
+
+		service := v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "apiserver",
+				Namespace:   "xa5ly",
+				Annotations: annotations,
+				Labels:      test.labels,
+			},
+		}
+		metaConfig := Config{
+			CertDirectory: "/certs",
+			Provider:      "aws",
+		}
+
+		provider := GetProvider(service, metaConfig)
+		if provider != test.expectedProvider {
+			t.Fatalf("%d: expected provider %#q, got %#q\n", index, test.expectedProvider, provider)
+		}
+
+		scrapeConfigs, err := GetScrapeConfigs([]v1.Service{service}, metaConfig)
+		if err != nil {
+			t.Fatalf("%d: error returned creating scrape configs: %s\n", index, err)
+		}
+
+		var hasAWSNodeJob bool
+		for _, s := range scrapeConfigs {
+			if s.JobName == getJobName(service, AWSNodeJobType) {
+				hasAWSNodeJob = true
+			}
+		}
+		if hasAWSNodeJob != test.expectedAWSNodeJob {
+			t.Fatalf("%d: expected aws-node job %t, got %t\n", index, test.expectedAWSNodeJob, hasAWSNodeJob)
+		}
+	}
+}
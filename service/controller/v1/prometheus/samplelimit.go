@@ -0,0 +1,142 @@
+package prometheus
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/prometheus/prometheus/config"
+	v1 "k8s.io/api/core/v1"
+)
+
+// ParseSampleLimits parses default sample limits by job type in the format
+// "managed-app=50000,workload=100000", see Config.SampleLimits. Job types must
+// be generated for clusters, either built in or by one of the given exporters.
+// When nil, DefaultExporters is used.
+func ParseSampleLimits(s string, exporters []Exporter) (map[string]uint, error) {
+	knownJobTypes := map[string]bool{}
+	for _, jobType := range getJobTypes(Config{Exporters: exporters}) {
+		knownJobTypes[jobType] = true
+	}
+
+	limits := map[string]uint{}
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, microerror.Maskf(invalidConfigError, "sample limit %#q must be in the format job-type=limit", entry)
+		}
+
+		jobType := strings.TrimSpace(parts[0])
+		if !knownJobTypes[jobType] {
+			return nil, microerror.Maskf(invalidConfigError, "sample limit %#q refers to unknown job type %#q", entry, jobType)
+		}
+
+		limit, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 32)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "sample limit %#q has invalid limit: %s", entry, err)
+		}
+
+		limits[jobType] = uint(limit)
+	}
+
+	return limits, nil
+}
+
+// getSampleLimitOverrides parses the sample limit annotations of the given
+// Service. The cluster wide override is returned under the empty job type.
+func getSampleLimitOverrides(service v1.Service, metaConfig Config) (map[string]uint, error) {
+	knownJobTypes := map[string]bool{}
+	for _, jobType := range getJobTypes(metaConfig) {
+		knownJobTypes[jobType] = true
+	}
+
+	overrides := map[string]uint{}
+
+	for k, v := range service.Annotations {
+		var jobType string
+		switch {
+		case k == SampleLimitAnnotation:
+		case strings.HasPrefix(k, SampleLimitAnnotation+"."):
+			jobType = strings.TrimPrefix(k, SampleLimitAnnotation+".")
+		default:
+			continue
+		}
+
+		if jobType != "" && !knownJobTypes[jobType] {
+			return nil, microerror.Maskf(invalidScrapeOverrideError, "annotation %#q refers to unknown job type %#q", k, jobType)
+		}
+
+		limit, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, microerror.Maskf(invalidScrapeOverrideError, "annotation %#q has invalid sample limit %#q: %s", k, v, err)
+		}
+
+		overrides[jobType] = uint(limit)
+	}
+
+	return overrides, nil
+}
+
+// GetSampleLimits returns the sample limits of the given Service's cluster by
+// job type, following the precedence job type override, cluster override and
+// default of the job type. Job types without limit are omitted. Overrides are
+// ignored when the Service's scrape overrides are invalid, see
+// ValidateScrapeOverrides.
+func GetSampleLimits(service v1.Service, metaConfig Config) map[string]uint {
+	overrides := map[string]uint{}
+	if ValidateScrapeOverrides(service, metaConfig) == nil {
+		overrides, _ = getSampleLimitOverrides(service, metaConfig)
+	}
+
+	limits := map[string]uint{}
+
+	for _, jobType := range getJobTypes(metaConfig) {
+		limit := metaConfig.SampleLimits[jobType]
+		if l, ok := overrides[""]; ok {
+			limit = l
+		}
+		if l, ok := overrides[jobType]; ok {
+			limit = l
+		}
+
+		if limit > 0 {
+			limits[jobType] = limit
+		}
+	}
+
+	return limits
+}
+
+// applySampleLimits sets the sample limit of the given jobs, see
+// GetSampleLimits.
+func applySampleLimits(service v1.Service, metaConfig Config, scrapeConfigs []config.ScrapeConfig) {
+	limits := GetSampleLimits(service, metaConfig)
+
+	for i := range scrapeConfigs {
+		jobType := strings.TrimPrefix(scrapeConfigs[i].JobName, getJobName(service, ""))
+
+		if limit, ok := limits[jobType]; ok {
+			scrapeConfigs[i].SampleLimit = limit
+		}
+	}
+}
+
+// UpdateSampleLimitMetrics exposes the sample limits applied to the jobs of the
+// given Services' clusters.
+func UpdateSampleLimitMetrics(services []v1.Service, metaConfig Config) {
+	sampleLimit.Reset()
+
+	for _, service := range services {
+		clusterID := GetClusterID(service)
+
+		for jobType, limit := range GetSampleLimits(service, metaConfig) {
+			sampleLimit.WithLabelValues(clusterID, jobType).Set(float64(limit))
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package prometheus
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test_Prometheus_ParseSampleLimits tests the ParseSampleLimits function.
+func Test_Prometheus_ParseSampleLimits(t *testing.T) {
+	tests := []struct {
+		sampleLimits string
+		exporters    []Exporter
+
+		expectedSampleLimits map[string]uint
+		expectedErrorHandler func(error) bool
+	}{
+		// 0. Test that an empty string yields no sample limits.
+		{
+			sampleLimits: "",
+
+			expectedSampleLimits: map[string]uint{},
+			expectedErrorHandler: nil,
+		},
+
+		// 1. Test that sample limits are parsed by job type.
+		{
+			sampleLimits: "managed-app=50000, workload=100000",
+
+			expectedSampleLimits: map[string]uint{
+				ManagedAppJobType: 50000,
+				WorkloadJobType:   100000,
+			},
+			expectedErrorHandler: nil,
+		},
+
+		// 2. Test that an entry without job type is rejected.
+		{
+			sampleLimits: "=50000",
+
+			expectedSampleLimits: nil,
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 3. Test that an invalid limit is rejected.
+		{
+			sampleLimits: "workload=lots",
+
+			expectedSampleLimits: nil,
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 4. Test that an unknown job type is rejected.
+		{
+			sampleLimits: "workloads=50000",
+
+			expectedSampleLimits: nil,
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 5. Test that job types of the exporter catalog are known.
+		{
+			sampleLimits: "etcd=1000,foo=2000",
+			exporters: []Exporter{
+				{
+					Name:      "foo-exporter",
+					Namespace: "foo",
+					Port:      "9000",
+					JobType:   "foo",
+				},
+			},
+
+			expectedSampleLimits: map[string]uint{
+				EtcdJobType: 1000,
+				"foo":       2000,
+			},
+			expectedErrorHandler: nil,
+		},
+	}
+
+	for index, test := range tests {
+		sampleLimits, err := ParseSampleLimits(test.sampleLimits, test.exporters)
+		if err != nil && test.expectedErrorHandler == nil {
+			t.Fatalf("%d: unexpected error returned parsing sample limits: %s\n", index, err)
+		}
+		if err != nil && !test.expectedErrorHandler(err) {
+			t.Fatalf("%d: incorrect error returned parsing sample limits: %s\n", index, err)
+		}
+		if err == nil && test.expectedErrorHandler != nil {
+			t.Fatalf("%d: expected error not returned parsing sample limits\n", index)
+		}
+
+		if !reflect.DeepEqual(sampleLimits, test.expectedSampleLimits) {
+			t.Fatalf("%d: expected sample limits %#v, got %#v\n", index, test.expectedSampleLimits, sampleLimits)
+		}
+	}
+}
+
+// Test_Prometheus_GetScrapeConfigs_SampleLimits tests that sample limits are
+// applied to the generated jobs.
+func Test_Prometheus_GetScrapeConfigs_SampleLimits(t *testing.T) {
+	defaultSampleLimits := map[string]uint{
+		ManagedAppJobType: 50000,
+		WorkloadJobType:   50000,
+	}
+
+	tests := []struct {
+		annotations map[string]string
+
+		expectedSampleLimits map[string]uint
+	}{
+		// 0. Test that jobs get the default sample limit of their job type.
+		{
+			annotations: map[string]string{
+				ClusterAnnotation: "xa5ly",
+			},
+
+			expectedSampleLimits: map[string]uint{
+				CadvisorJobType:   0,
+				ManagedAppJobType: 50000,
+				WorkloadJobType:   50000,
+			},
+		},
+
+		// 1. Test that job type overrides take precedence over cluster wide
+		// overrides, and that a limit of 0 disables the limit.
+		{
+			annotations: map[string]string{
+				ClusterAnnotation:                               "xa5ly",
+				SampleLimitAnnotation:                           "20000",
+				SampleLimitAnnotation + "." + ManagedAppJobType: "100000",
+				SampleLimitAnnotation + "." + WorkloadJobType:   "0",
+			},
+
+			expectedSampleLimits: map[string]uint{
+				CadvisorJobType:   20000,
+				ManagedAppJobType: 100000,
+				WorkloadJobType:   0,
+			},
+		},
+
+		// 2. Test that invalid overrides are ignored.
+		{
+			annotations: map[string]string{
+				ClusterAnnotation: "xa5ly",
+				SampleLimitAnnotation + "." + WorkloadJobType: "-1",
+			},
+
+			expectedSampleLimits: map[string]uint{
+				CadvisorJobType:   0,
+				ManagedAppJobType: 50000,
+				WorkloadJobType:   50000,
+			},
+		},
+	}
+
+	for index, test := range tests {
+		services := []v1.Service{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "apiserver",
+					Namespace:   "xa5ly",
+					Annotations: test.annotations,
+				},
+			},
+		}
+
+		scrapeConfigs, err := GetScrapeConfigs(services, Config{CertDirectory: "/certs", Provider: "aws-test", SampleLimits: defaultSampleLimits})
+		if err != nil {
+			t.Fatalf("%d: error returned creating scrape configs: %s\n", index, err)
+		}
+
+		for _, s := range scrapeConfigs {
+			for jobType, sampleLimit := range test.expectedSampleLimits {
+				if s.JobName != getJobName(services[0], jobType) {
+					continue
+				}
+
+				if s.SampleLimit != sampleLimit {
+					t.Fatalf("%d: expected job %#q sample limit %d, got %d\n", index, s.JobName, sampleLimit, s.SampleLimit)
+				}
+			}
+		}
+	}
+}
@@ -2,19 +2,19 @@ package prometheus
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"sort"
-	"time"
 
 	config_util "github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/config"
 	sd_config "github.com/prometheus/prometheus/discovery/config"
+	"github.com/prometheus/prometheus/discovery/dns"
 	"github.com/prometheus/prometheus/discovery/kubernetes"
 	"github.com/prometheus/prometheus/discovery/targetgroup"
 	"github.com/prometheus/prometheus/pkg/relabel"
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
 )
@@ -70,9 +70,29 @@ func getJobName(service v1.Service, name string) string {
 
 // getTargetHost takes a Kubernetes Service, and returns a suitable host.
 func getTargetHost(service v1.Service) string {
+	if endpoint, ok := service.Annotations[APIEndpointAnnotation]; ok && endpoint != "" {
+		return endpoint
+	}
+
 	return fmt.Sprintf("%s.%s", service.Name, service.Namespace)
 }
 
+// getAPIProxyHost takes a Kubernetes Service, and returns the host and port
+// of the cluster's API server, which pods and nodes are scraped through. It is
+// the control plane endpoint, for clusters whose API server is not reachable
+// through the Service's name and namespace.
+func getAPIProxyHost(service v1.Service) string {
+	if endpoint, ok := service.Annotations[APIEndpointAnnotation]; ok && endpoint != "" {
+		if _, _, err := net.SplitHostPort(endpoint); err == nil {
+			return endpoint
+		}
+
+		return net.JoinHostPort(endpoint, "443")
+	}
+
+	return key.APIServiceHost(key.PrefixMaster, GetClusterID(service))
+}
+
 // getTarget takes a Kubernetes Service, and returns a LabelSet,
 // suitable for use as a target.
 func getTarget(service v1.Service) model.LabelSet {
@@ -94,7 +114,7 @@ func getEtcdTarget(etcdUrl string) model.LabelSet {
 func getScrapeConfigs(service v1.Service, metaConfig Config) []config.ScrapeConfig {
 	certificateDirectory := metaConfig.CertDirectory
 	clusterID := GetClusterID(service)
-	provider := metaConfig.Provider
+	provider := GetProvider(service, metaConfig)
 
 	secureTLSConfig := config_util.TLSConfig{
 		CAFile:             key.CAPath(certificateDirectory, clusterID),
@@ -183,7 +203,7 @@ func getScrapeConfigs(service v1.Service, metaConfig Config) []config.ScrapeConf
 	}
 	rewriteAddress := &relabel.Config{
 		TargetLabel: AddressLabel,
-		Replacement: key.APIServiceHost(key.PrefixMaster, clusterID),
+		Replacement: getAPIProxyHost(service),
 	}
 	rewriteManagedAppMetricPath := &relabel.Config{
 		SourceLabels: model.LabelNames{model.LabelName(NamespaceLabel), model.LabelName(PodNameLabel), KubernetesSDServiceGiantSwarmMonitoringPortLabel, KubernetesSDServiceGiantSwarmMonitoringPathLabel},
@@ -215,66 +235,12 @@ func getScrapeConfigs(service v1.Service, metaConfig Config) []config.ScrapeConf
 		TargetLabel:  MetricPathLabel,
 		Replacement:  key.APIProxyPodMetricsPath(key.CalicoNodeNamespace, key.CalicoNodeMetricPort),
 	}
-	rewriteChartOperatorPath := &relabel.Config{
-		SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
-		Regex:        ChartOperatorPodNameRegexp,
-		TargetLabel:  MetricPathLabel,
-		Replacement:  key.APIProxyPodMetricsPath(key.ChartOperatorNamespace, key.ChartOperatorMetricPort),
-	}
-	rewriteCertExporterPath := &relabel.Config{
-		SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
-		Regex:        CertExporterPodNameRegexp,
-		TargetLabel:  MetricPathLabel,
-		Replacement:  key.APIProxyPodMetricsPath(key.CertExporterNamespace, key.CertExporterMetricPort),
-	}
-	rewriteClusterAutoscalerPath := &relabel.Config{
-		SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
-		Regex:        ClusterAutoscalerPodNameRegexp,
-		TargetLabel:  MetricPathLabel,
-		Replacement:  key.APIProxyPodMetricsPath(key.ClusterAutoscalerNamespace, key.ClusterAutoscalerMetricPort),
-	}
-	rewriteCoreDNSPath := &relabel.Config{
-		SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
-		Regex:        CoreDNSPodNameRegexp,
-		TargetLabel:  MetricPathLabel,
-		Replacement:  key.APIProxyPodMetricsPath(key.CoreDNSNamespace, key.CoreDNSMetricPort),
-	}
-	rewriteElasticLoggingMetricPath := &relabel.Config{
-		SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
-		Regex:        ElasticLoggingPodNameRegexp,
-		TargetLabel:  MetricPathLabel,
-		Replacement:  key.APIProxyPodMetricsPath(key.ElasticLoggingNamespace, key.ElasticLoggingMetricPort),
-	}
-	rewriteNetExporterPath := &relabel.Config{
-		SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
-		Regex:        NetExporterPodNameRegexp,
-		TargetLabel:  MetricPathLabel,
-		Replacement:  key.APIProxyPodMetricsPath(key.NetExporterNamespace, key.NetExporterMetricPort),
-	}
-	rewriteNicExporterPath := &relabel.Config{
-		SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
-		Regex:        NicExporterPodNameRegexp,
-		TargetLabel:  MetricPathLabel,
-		Replacement:  key.APIProxyPodMetricsPath(key.NicExporterNamespace, key.NicExporterMetricPort),
-	}
-	rewriteKiamPath := &relabel.Config{
-		SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
-		Regex:        KiamPodNameRegexp,
-		TargetLabel:  MetricPathLabel,
-		Replacement:  key.APIProxyPodMetricsPath(key.KiamNamespace, key.KiamMetricPort),
-	}
 	rewriteKubeProxyPath := &relabel.Config{
 		SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
 		Regex:        KubeProxyPodNameRegexp,
 		TargetLabel:  MetricPathLabel,
 		Replacement:  key.APIProxyPodMetricsPath(key.KubeProxyNamespace, key.KubeProxyMetricPort),
 	}
-	rewriteVaultExporterPath := &relabel.Config{
-		SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
-		Regex:        VaultExporterPodNameRegexp,
-		TargetLabel:  MetricPathLabel,
-		Replacement:  key.APIProxyPodMetricsPath(key.VaultExporterNamespace, key.VaultExporterMetricPort),
-	}
 
 	ipLabelRelabelConfig := &relabel.Config{
 		TargetLabel:  IPLabel,
@@ -405,47 +371,6 @@ func getScrapeConfigs(service v1.Service, metaConfig Config) []config.ScrapeConf
 			},
 		},
 
-		{
-			JobName:                getJobName(service, AWSNodeJobType),
-			HTTPClientConfig:       secureHTTPClientConfig,
-			Scheme:                 HttpsScheme,
-			ServiceDiscoveryConfig: podSDConfig,
-			RelabelConfigs: []*relabel.Config{
-				// Only keep kube-state-metrics targets.
-				{
-					SourceLabels: model.LabelNames{PodSDNamespaceLabel, PodSDPodNameLabel},
-					Regex:        AWSNodePodRegexp,
-					Action:       relabel.Keep,
-				},
-				// Add app label.
-				{
-					TargetLabel:  AppLabel,
-					SourceLabels: model.LabelNames{PodSDContainerNameLabel},
-				},
-				// Add namespace label.
-				{
-					TargetLabel:  NamespaceLabel,
-					SourceLabels: model.LabelNames{PodSDNamespaceLabel},
-				},
-				// Add pod_name label.
-				{
-					TargetLabel:  PodNameLabel,
-					SourceLabels: model.LabelNames{PodSDPodNameLabel},
-				},
-				// Add cluster_id label.
-				clusterIDLabelRelabelConfig,
-				// Add cluster_type label.
-				clusterTypeLabelRelabelConfig,
-				// rewrite host to api proxy
-				rewriteAddress,
-				// rewrite metrics scrape path to connect pods
-				rewriteAWSNodePath,
-			},
-			MetricRelabelConfigs: []*relabel.Config{
-				providerLabelRelabelConfig,
-			},
-		},
-
 		{
 			JobName:                getJobName(service, CalicoNodeJobType),
 			HTTPClientConfig:       secureHTTPClientConfig,
@@ -670,83 +595,6 @@ func getScrapeConfigs(service v1.Service, metaConfig Config) []config.ScrapeConf
 				providerLabelRelabelConfig,
 			},
 		},
-		{
-			JobName:                getJobName(service, WorkloadJobType),
-			HTTPClientConfig:       secureHTTPClientConfig,
-			Scheme:                 HttpsScheme,
-			ServiceDiscoveryConfig: endpointSDConfig,
-			RelabelConfigs: []*relabel.Config{
-				// Only keep kube-state-metrics targets.
-				{
-					SourceLabels: model.LabelNames{KubernetesSDNamespaceLabel, KubernetesSDServiceNameLabel},
-					Regex:        ServiceWhitelistRegexp,
-					Action:       relabel.Keep,
-				},
-				// Drop non-managed kiam pods and keep only Giantswarm managed kiam pods
-				{
-					SourceLabels: model.LabelNames{KubernetesSDPodNameLabel, PodSDGiantswarmServiceTypeLabel},
-					Regex:        KiamPodNameRegexpNonManaged,
-					Action:       relabel.Drop,
-				},
-				// Add app label.
-				{
-					TargetLabel:  AppLabel,
-					SourceLabels: model.LabelNames{KubernetesSDServiceNameLabel},
-				},
-				// Add namespace label.
-				{
-					TargetLabel:  NamespaceLabel,
-					SourceLabels: model.LabelNames{KubernetesSDNamespaceLabel},
-				},
-				// Add pod_name label.
-				{
-					TargetLabel:  PodNameLabel,
-					SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
-				},
-				// Add node label.
-				{
-					TargetLabel:  NodeLabel,
-					SourceLabels: model.LabelNames{KubernetesSDPodNodeNameLabel},
-				},
-				// Add cluster_id label.
-				clusterIDLabelRelabelConfig,
-				// Add cluster_type label.
-				clusterTypeLabelRelabelConfig,
-				// rewrite host to api proxy
-				rewriteAddress,
-				// rewrite metrics scrape path to connect pods
-				rewriteKubeStateMetricPath,
-				rewriteCalicoNodePath,
-				rewriteChartOperatorPath,
-				rewriteCertExporterPath,
-				rewriteClusterAutoscalerPath,
-				rewriteCoreDNSPath,
-				rewriteElasticLoggingMetricPath,
-				rewriteNetExporterPath,
-				rewriteNicExporterPath,
-				rewriteKiamPath,
-				rewriteVaultExporterPath,
-			},
-			MetricRelabelConfigs: []*relabel.Config{
-				// relabel namespace to exported_namespace for endpoints in kube-system namespace.
-				// this keeps metrics from nginx ingress controller from being dropped by filter below
-				{
-					Action:       ActionRelabel,
-					SourceLabels: model.LabelNames{MetricExportedNamespaceLabel, MetricNamespaceLabel},
-					Regex:        RelabelNamespaceRegexp,
-					Replacement:  GroupCapture,
-					TargetLabel:  ExportedNamespaceLabel,
-				},
-				// keep only kube-system cadvisor metrics
-				{
-					Action:       ActionKeep,
-					SourceLabels: model.LabelNames{MetricExportedNamespaceLabel},
-					Regex:        NSRegexp,
-				},
-				providerLabelRelabelConfig,
-			},
-		},
-
 		{
 			JobName:                getJobName(service, IngressJobType),
 			HTTPClientConfig:       secureHTTPClientConfig,
@@ -923,43 +771,249 @@ func getScrapeConfigs(service v1.Service, metaConfig Config) []config.ScrapeConf
 			},
 		},
 	}
-	// check if we can add etcd monitoring
 
-	//  to ensure all components in cloud are ready we delay creation of etcd scrape config by 30 minutes
-	etcdScrapeDelay := metav1.Time{Time: time.Now().Add(-time.Minute * 30)}
-
-	if service.CreationTimestamp.Before(&etcdScrapeDelay) {
-		if _, ok := service.Annotations[key.AnnotationEtcdDomain]; ok {
-			// prepare etcd static discovery config
-			etcdStaticConfig := sd_config.ServiceDiscoveryConfig{
-				StaticConfigs: []*targetgroup.Group{
-					{
-						Targets: []model.LabelSet{
-							getEtcdTarget(service.Annotations[key.AnnotationEtcdDomain]),
-						},
-						Labels: model.LabelSet{
-							model.LabelName(ClusterTypeLabel): model.LabelValue(WorkloadClusterType),
-							model.LabelName(ClusterIDLabel):   model.LabelValue(clusterID),
-							model.LabelName(ProviderLabel):    model.LabelValue(provider),
-						},
-					},
+	// aws-node only runs in AWS clusters.
+	if isAWSProvider(provider) {
+		scrapeConfigs = append(scrapeConfigs, config.ScrapeConfig{
+			JobName:                getJobName(service, AWSNodeJobType),
+			HTTPClientConfig:       secureHTTPClientConfig,
+			Scheme:                 HttpsScheme,
+			ServiceDiscoveryConfig: podSDConfig,
+			RelabelConfigs: []*relabel.Config{
+				// Only keep kube-state-metrics targets.
+				{
+					SourceLabels: model.LabelNames{PodSDNamespaceLabel, PodSDPodNameLabel},
+					Regex:        AWSNodePodRegexp,
+					Action:       relabel.Keep,
+				},
+				// Add app label.
+				{
+					TargetLabel:  AppLabel,
+					SourceLabels: model.LabelNames{PodSDContainerNameLabel},
+				},
+				// Add namespace label.
+				{
+					TargetLabel:  NamespaceLabel,
+					SourceLabels: model.LabelNames{PodSDNamespaceLabel},
+				},
+				// Add pod_name label.
+				{
+					TargetLabel:  PodNameLabel,
+					SourceLabels: model.LabelNames{PodSDPodNameLabel},
 				},
+				// Add cluster_id label.
+				clusterIDLabelRelabelConfig,
+				// Add cluster_type label.
+				clusterTypeLabelRelabelConfig,
+				// rewrite host to api proxy
+				rewriteAddress,
+				// rewrite metrics scrape path to connect pods
+				rewriteAWSNodePath,
+			},
+			MetricRelabelConfigs: []*relabel.Config{
+				providerLabelRelabelConfig,
+			},
+		})
+	}
+
+	jobTypes, exportersByJobType := groupExportersByJobType(getExporters(metaConfig))
+	for _, jobType := range jobTypes {
+		exporters := exportersByJobType[jobType]
+
+		if jobType == WorkloadJobType {
+			exporterNamespaces := getExporterNamespaces(exporters)
+
+			workloadRelabelConfigs := []*relabel.Config{
+				// Only keep exporter targets.
+				{
+					SourceLabels: model.LabelNames{KubernetesSDNamespaceLabel, KubernetesSDServiceNameLabel},
+					Regex:        getExporterWhitelistRegexp(exporters),
+					Action:       relabel.Keep,
+				},
+				// Drop non-managed kiam pods and keep only Giantswarm managed kiam pods
+				{
+					SourceLabels: model.LabelNames{KubernetesSDPodNameLabel, PodSDGiantswarmServiceTypeLabel},
+					Regex:        KiamPodNameRegexpNonManaged,
+					Action:       relabel.Drop,
+				},
+				// Add app label.
+				{
+					TargetLabel:  AppLabel,
+					SourceLabels: model.LabelNames{KubernetesSDServiceNameLabel},
+				},
+				// Add namespace label.
+				{
+					TargetLabel:  NamespaceLabel,
+					SourceLabels: model.LabelNames{KubernetesSDNamespaceLabel},
+				},
+				// Add pod_name label.
+				{
+					TargetLabel:  PodNameLabel,
+					SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
+				},
+				// Add node label.
+				{
+					TargetLabel:  NodeLabel,
+					SourceLabels: model.LabelNames{KubernetesSDPodNodeNameLabel},
+				},
+				// Add cluster_id label.
+				clusterIDLabelRelabelConfig,
+				// Add cluster_type label.
+				clusterTypeLabelRelabelConfig,
+				// rewrite host to api proxy
+				rewriteAddress,
 			}
+			// rewrite metrics scrape path to connect pods
+			workloadRelabelConfigs = append(workloadRelabelConfigs, getExporterPathRelabelConfigs(exporters)...)
 
-			etcdScrapeConfig := config.ScrapeConfig{
-				JobName:                getJobName(service, EtcdJobType),
-				Scheme:                 HttpsScheme,
+			workloadMetricRelabelConfigs := []*relabel.Config{
+				// relabel namespace to exported_namespace for endpoints in exporter namespaces.
+				// this keeps metrics from nginx ingress controller from being dropped by filter below
+				{
+					Action:       ActionRelabel,
+					SourceLabels: model.LabelNames{MetricExportedNamespaceLabel, MetricNamespaceLabel},
+					Regex:        relabel.MustNewRegexp(fmt.Sprintf(";(%s)", exporterNamespaces)),
+					Replacement:  GroupCapture,
+					TargetLabel:  ExportedNamespaceLabel,
+				},
+				// keep only metrics of exporter namespaces
+				{
+					Action:       ActionKeep,
+					SourceLabels: model.LabelNames{MetricExportedNamespaceLabel},
+					Regex:        relabel.MustNewRegexp(fmt.Sprintf("(%s)", exporterNamespaces)),
+				},
+			}
+			workloadMetricRelabelConfigs = append(workloadMetricRelabelConfigs, getExporterMetricRelabelConfigs(exporters)...)
+			workloadMetricRelabelConfigs = append(workloadMetricRelabelConfigs, providerLabelRelabelConfig)
+
+			scrapeConfigs = append(scrapeConfigs, config.ScrapeConfig{
+				JobName:                getJobName(service, WorkloadJobType),
 				HTTPClientConfig:       secureHTTPClientConfig,
-				ServiceDiscoveryConfig: etcdStaticConfig,
-				MetricRelabelConfigs: []*relabel.Config{
-					providerLabelRelabelConfig,
+				Scheme:                 HttpsScheme,
+				ServiceDiscoveryConfig: endpointSDConfig,
+				RelabelConfigs:         workloadRelabelConfigs,
+				MetricRelabelConfigs:   workloadMetricRelabelConfigs,
+			})
+
+			continue
+		}
+
+		exporterRelabelConfigs := []*relabel.Config{
+			// Only keep exporter targets.
+			{
+				SourceLabels: model.LabelNames{KubernetesSDNamespaceLabel, KubernetesSDServiceNameLabel},
+				Regex:        getExporterWhitelistRegexp(exporters),
+				Action:       relabel.Keep,
+			},
+			// Add app label.
+			{
+				TargetLabel:  AppLabel,
+				SourceLabels: model.LabelNames{KubernetesSDServiceNameLabel},
+			},
+			// Add namespace label.
+			{
+				TargetLabel:  NamespaceLabel,
+				SourceLabels: model.LabelNames{KubernetesSDNamespaceLabel},
+			},
+			// Add pod_name label.
+			{
+				TargetLabel:  PodNameLabel,
+				SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
+			},
+			// Add node label.
+			{
+				TargetLabel:  NodeLabel,
+				SourceLabels: model.LabelNames{KubernetesSDPodNodeNameLabel},
+			},
+			// Add cluster_id label.
+			clusterIDLabelRelabelConfig,
+			// Add cluster_type label.
+			clusterTypeLabelRelabelConfig,
+			// rewrite host to api proxy
+			rewriteAddress,
+		}
+		// rewrite metrics scrape path to connect pods
+		exporterRelabelConfigs = append(exporterRelabelConfigs, getExporterPathRelabelConfigs(exporters)...)
+
+		exporterMetricRelabelConfigs := getExporterMetricRelabelConfigs(exporters)
+		exporterMetricRelabelConfigs = append(exporterMetricRelabelConfigs, providerLabelRelabelConfig)
+
+		scrapeConfigs = append(scrapeConfigs, config.ScrapeConfig{
+			JobName:                getJobName(service, jobType),
+			HTTPClientConfig:       secureHTTPClientConfig,
+			Scheme:                 HttpsScheme,
+			ServiceDiscoveryConfig: endpointSDConfig,
+			RelabelConfigs:         exporterRelabelConfigs,
+			MetricRelabelConfigs:   exporterMetricRelabelConfigs,
+		})
+	}
+
+	// check if we can add etcd monitoring
+	if ok, _ := GetEtcdScrapeStatus(service, metaConfig); ok {
+		etcdLabels := model.LabelSet{
+			model.LabelName(ClusterTypeLabel): model.LabelValue(WorkloadClusterType),
+			model.LabelName(ClusterIDLabel):   model.LabelValue(clusterID),
+			model.LabelName(ProviderLabel):    model.LabelValue(provider),
+		}
+
+		// prepare etcd discovery config, with one target per member
+		var etcdSDConfig sd_config.ServiceDiscoveryConfig
+		endpoints := GetEtcdEndpoints(service)
+		switch {
+		case metaConfig.DiscoveryURL != "":
+			// targets are discovered over HTTP service discovery, which is
+			// added when marshalling, see addHTTPSDConfigs
+		case len(endpoints) > 0:
+			var targets []model.LabelSet
+			for _, e := range endpoints {
+				targets = append(targets, getEtcdTarget(e))
+			}
+
+			etcdSDConfig.StaticConfigs = []*targetgroup.Group{
+				{
+					Targets: targets,
+					Labels:  etcdLabels,
 				},
 			}
-			// append etcd scrape config
-			scrapeConfigs = append(scrapeConfigs, etcdScrapeConfig)
+		default:
+			dnsSDConfig := dns.DefaultSDConfig
+			dnsSDConfig.Names = []string{GetEtcdSRV(service)}
+			dnsSDConfig.Type = "SRV"
+
+			etcdSDConfig.DNSSDConfigs = []*dns.SDConfig{
+				&dnsSDConfig,
+			}
 		}
+
+		etcdScrapeConfig := config.ScrapeConfig{
+			JobName:                getJobName(service, EtcdJobType),
+			Scheme:                 HttpsScheme,
+			HTTPClientConfig:       secureHTTPClientConfig,
+			ServiceDiscoveryConfig: etcdSDConfig,
+			RelabelConfigs: []*relabel.Config{
+				// Add member label.
+				{
+					SourceLabels: model.LabelNames{model.AddressLabel},
+					Regex:        HostPortRegexp,
+					Replacement:  GroupCapture,
+					TargetLabel:  MemberLabel,
+				},
+				// Add cluster_id label.
+				clusterIDLabelRelabelConfig,
+				// Add cluster_type label.
+				clusterTypeLabelRelabelConfig,
+			},
+			MetricRelabelConfigs: []*relabel.Config{
+				providerLabelRelabelConfig,
+			},
+		}
+		// append etcd scrape config
+		scrapeConfigs = append(scrapeConfigs, etcdScrapeConfig)
 	}
 
+	applyScrapeOverrides(service, metaConfig, scrapeConfigs)
+	applySampleLimits(service, metaConfig, scrapeConfigs)
+
 	return scrapeConfigs
 }
 
@@ -967,6 +1021,7 @@ func getScrapeConfigs(service v1.Service, metaConfig Config) []config.ScrapeConf
 // and returns a list of Prometheus ScrapeConfigs.
 func GetScrapeConfigs(services []v1.Service, metaConfig Config) ([]config.ScrapeConfig, error) {
 	filteredServices := FilterInvalidServices(services)
+	filteredServices = FilterShardServices(filteredServices, metaConfig.ShardIndex, metaConfig.ShardCount)
 
 	scrapeConfigs := []config.ScrapeConfig{}
 	for _, service := range filteredServices {
@@ -83,6 +83,68 @@ func Test_Prometheus_getTargetHost(t *testing.T) {
 	}
 }
 
+// Test_Prometheus_getAPIProxyHost tests the getAPIProxyHost function.
+func Test_Prometheus_getAPIProxyHost(t *testing.T) {
+	tests := []struct {
+		service      v1.Service
+		expectedHost string
+	}{
+		// 0. Test that the master Service of the cluster is used without
+		// control plane endpoint.
+		{
+			service: v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "master",
+					Namespace: "xa5ly",
+					Annotations: map[string]string{
+						ClusterAnnotation: "xa5ly",
+					},
+				},
+			},
+			expectedHost: "master.xa5ly:443",
+		},
+
+		// 1. Test that the control plane endpoint is used, with the default
+		// port added.
+		{
+			service: v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "xa5ly",
+					Namespace: "xa5ly",
+					Annotations: map[string]string{
+						ClusterAnnotation:     "xa5ly",
+						APIEndpointAnnotation: "api.xa5ly.example.com",
+					},
+				},
+			},
+			expectedHost: "api.xa5ly.example.com:443",
+		},
+
+		// 2. Test that the port of the control plane endpoint is kept.
+		{
+			service: v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "xa5ly",
+					Namespace: "xa5ly",
+					Annotations: map[string]string{
+						ClusterAnnotation:     "xa5ly",
+						APIEndpointAnnotation: "api.xa5ly.example.com:6443",
+					},
+				},
+			},
+			expectedHost: "api.xa5ly.example.com:6443",
+		},
+	}
+
+	for index, test := range tests {
+		host := getAPIProxyHost(test.service)
+
+		if test.expectedHost != host {
+			t.Fatalf("%d: expected host %#q, got %#q\n", index, test.expectedHost, host)
+		}
+	}
+}
+
 // Test_Prometheus_getTarget tests the getTarget function.
 func Test_Prometheus_getTarget(t *testing.T) {
 	tests := []struct {
@@ -332,50 +394,78 @@ var update = flag.Bool("update", false, "update .golden scrapeconfig file")
 
 // Test_Prometheus_YamlMarshal tests that Prometheus marshals YAML correctly.
 //
-// It uses golden file as reference template and when changes to template are
-// intentional, they can be updated by providing -update flag for go test.
+// It uses golden files as reference templates and when changes to templates
+// are intentional, they can be updated by providing -update flag for go test.
 //
 //  go test ./service/controller/v1/prometheus -run Test_Prometheus_YamlMarshal -update
 //
 func Test_Prometheus_YamlMarshal(t *testing.T) {
-	service := v1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "apiserver",
-			Namespace: "xa5ly",
-			Annotations: map[string]string{
-				ClusterAnnotation: "xa5ly",
+	tests := []struct {
+		service    v1.Service
+		goldenFile string
+	}{
+		// 0. Test the scrape configs of a cluster discovered from its master
+		// Service.
+		{
+			service: v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "apiserver",
+					Namespace: "xa5ly",
+					Annotations: map[string]string{
+						ClusterAnnotation: "xa5ly",
+					},
+				},
 			},
+			goldenFile: "scrapeconfig.golden",
 		},
-	}
 
-	metaConfig := Config{
-		CertDirectory: "/certs",
-		Provider:      "aws-test",
-	}
-	scrapeConfigs, err := GetScrapeConfigs([]v1.Service{service}, metaConfig)
-	if err != nil {
-		t.Fatalf("error returned creating scrape configs: %s\n", err)
+		// 1. Test the scrape configs of a Cluster API cluster, whose jobs
+		// scrape through the control plane endpoint.
+		{
+			service: v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "xa5ly",
+					Namespace: "xa5ly",
+					Annotations: map[string]string{
+						ClusterAnnotation:     "xa5ly",
+						APIEndpointAnnotation: "api.xa5ly.example.com:6443",
+					},
+				},
+			},
+			goldenFile: "scrapeconfig_capi.golden",
+		},
 	}
 
-	data, err := yaml.Marshal(scrapeConfigs)
-	if err != nil {
-		t.Fatalf("error occurred marshaling yaml: %s\n", err)
-	}
+	for index, test := range tests {
+		metaConfig := Config{
+			CertDirectory: "/certs",
+			Provider:      "aws-test",
+		}
+		scrapeConfigs, err := GetScrapeConfigs([]v1.Service{test.service}, metaConfig)
+		if err != nil {
+			t.Fatalf("%d: error returned creating scrape configs: %s\n", index, err)
+		}
+
+		data, err := yaml.Marshal(scrapeConfigs)
+		if err != nil {
+			t.Fatalf("%d: error occurred marshaling yaml: %s\n", index, err)
+		}
 
-	p := filepath.Join("testdata", "scrapeconfig.golden")
+		p := filepath.Join("testdata", test.goldenFile)
 
-	if *update {
-		err := ioutil.WriteFile(p, data, 0644)
+		if *update {
+			err := ioutil.WriteFile(p, data, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		goldenFile, err := ioutil.ReadFile(p)
 		if err != nil {
 			t.Fatal(err)
 		}
-	}
-	goldenFile, err := ioutil.ReadFile(p)
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	if !cmp.Equal(data, goldenFile) {
-		t.Fatalf("\n\n%s\n", cmp.Diff(goldenFile, data))
+		if !cmp.Equal(data, goldenFile) {
+			t.Fatalf("%d:\n\n%s\n", index, cmp.Diff(goldenFile, data))
+		}
 	}
 }
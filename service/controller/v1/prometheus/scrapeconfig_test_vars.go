@@ -678,7 +678,7 @@ var (
 		RelabelConfigs: []*relabel.Config{
 			{
 				SourceLabels: model.LabelNames{KubernetesSDNamespaceLabel, KubernetesSDServiceNameLabel},
-				Regex:        ServiceWhitelistRegexp,
+				Regex:        relabel.MustNewRegexp(`(kube-system;(cert-exporter|cluster-autoscaler|coredns|kiam-agent|kiam-server|kube-state-metrics|net-exporter|nic-exporter))|(giantswarm;chart-operator)|(giantswarm-elastic-logging;elastic-logging-elasticsearch-exporter)|(vault-exporter;vault-exporter)`),
 				Action:       relabel.Keep,
 			},
 			{
@@ -720,12 +720,6 @@ var (
 				TargetLabel:  MetricPathLabel,
 				Replacement:  key.APIProxyPodMetricsPath(key.KubeStateMetricsNamespace, key.KubeStateMetricsPort),
 			},
-			{
-				SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
-				Regex:        CalicoNodePodNameRegexp,
-				TargetLabel:  MetricPathLabel,
-				Replacement:  key.APIProxyPodMetricsPath(key.CalicoNodeNamespace, key.CalicoNodeMetricPort),
-			},
 			{
 				SourceLabels: model.LabelNames{KubernetesSDPodNameLabel},
 				Regex:        ChartOperatorPodNameRegexp,
@@ -0,0 +1,33 @@
+package prometheus
+
+import (
+	"hash/fnv"
+)
+
+// GetShard returns the shard the cluster with the given ID is assigned to, out
+// of shardCount shards. It uses jump consistent hashing, so that changing the
+// number of shards only moves the clusters required to rebalance them.
+func GetShard(clusterID string, shardCount int) int {
+	if shardCount <= 1 {
+		return 0
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(clusterID))
+
+	return jumpHash(h.Sum64(), shardCount)
+}
+
+// jumpHash implements the jump consistent hash algorithm, see
+// https://arxiv.org/abs/1406.2294.
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+
+	return int(b)
+}
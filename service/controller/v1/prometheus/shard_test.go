@@ -0,0 +1,160 @@
+package prometheus
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Test_Prometheus_GetShard tests the GetShard function.
+func Test_Prometheus_GetShard(t *testing.T) {
+	tests := []struct {
+		clusterID  string
+		shardCount int
+
+		expectedShard int
+	}{
+		// 0. Test that all clusters are assigned to the first shard when
+		// sharding is disabled.
+		{
+			clusterID:  "xa5ly",
+			shardCount: 0,
+
+			expectedShard: 0,
+		},
+
+		// 1. Test that all clusters are assigned to the first shard when there
+		// is a single shard.
+		{
+			clusterID:  "xa5ly",
+			shardCount: 1,
+
+			expectedShard: 0,
+		},
+
+		// 2. Test that clusters are assigned to fixed shards, so that
+		// assignments do not change across controller versions.
+		{
+			clusterID:  "xa5ly",
+			shardCount: 5,
+
+			expectedShard: 4,
+		},
+
+		// 3. Test that a different cluster is assigned to another shard.
+		{
+			clusterID:  "0ba9v",
+			shardCount: 5,
+
+			expectedShard: 2,
+		},
+
+		// 4. Test that the assignment depends on the shard count.
+		{
+			clusterID:  "0ba9v",
+			shardCount: 8,
+
+			expectedShard: 7,
+		},
+
+		// 5. Test the assignment with two shards.
+		{
+			clusterID:  "al9qy",
+			shardCount: 2,
+
+			expectedShard: 1,
+		},
+	}
+
+	for index, test := range tests {
+		shard := GetShard(test.clusterID, test.shardCount)
+
+		if shard != test.expectedShard {
+			t.Fatalf("%d: expected shard %d, got %d\n", index, test.expectedShard, shard)
+		}
+	}
+}
+
+// Test_Prometheus_GetShard_MinimalChurn tests that adding a shard only moves
+// clusters to the new shard, and that clusters are spread across shards.
+func Test_Prometheus_GetShard_MinimalChurn(t *testing.T) {
+	shardCount := 4
+	clusters := map[int]int{}
+
+	for i := 0; i < 1000; i++ {
+		clusterID := fmt.Sprintf("c%04d", i)
+
+		before := GetShard(clusterID, shardCount)
+		after := GetShard(clusterID, shardCount+1)
+
+		if before < 0 || before >= shardCount {
+			t.Fatalf("cluster %#q assigned to shard %d out of range", clusterID, before)
+		}
+		if before != after && after != shardCount {
+			t.Fatalf("cluster %#q moved from shard %d to existing shard %d", clusterID, before, after)
+		}
+		if GetShard(clusterID, shardCount) != before {
+			t.Fatalf("cluster %#q assignment is not stable", clusterID)
+		}
+
+		clusters[before]++
+	}
+
+	for shard := 0; shard < shardCount; shard++ {
+		if clusters[shard] == 0 {
+			t.Fatalf("expected clusters assigned to shard %d", shard)
+		}
+	}
+}
+
+// Test_Prometheus_GetShard_Balanced tests that clusters are spread evenly
+// across shards, for different shard counts.
+func Test_Prometheus_GetShard_Balanced(t *testing.T) {
+	clusterCount := 1000
+
+	for shardCount := 2; shardCount <= 8; shardCount++ {
+		clusters := map[int]int{}
+		for i := 0; i < clusterCount; i++ {
+			clusters[GetShard(fmt.Sprintf("c%04d", i), shardCount)]++
+		}
+
+		// Every shard is expected to hold its share of clusters, within 20%.
+		expected := float64(clusterCount) / float64(shardCount)
+		for shard := 0; shard < shardCount; shard++ {
+			if float64(clusters[shard]) < expected*0.8 || float64(clusters[shard]) > expected*1.2 {
+				t.Fatalf("%d shards: expected about %.0f clusters on shard %d, got %d", shardCount, expected, shard, clusters[shard])
+			}
+		}
+	}
+}
+
+// Test_Prometheus_GetShard_MinimalMoves tests that changing the shard count
+// only moves the share of clusters required to rebalance the shards, both
+// when adding and when removing a shard.
+func Test_Prometheus_GetShard_MinimalMoves(t *testing.T) {
+	clusterCount := 1000
+
+	for shardCount := 2; shardCount <= 8; shardCount++ {
+		var moved int
+		for i := 0; i < clusterCount; i++ {
+			clusterID := fmt.Sprintf("c%04d", i)
+
+			before := GetShard(clusterID, shardCount)
+			after := GetShard(clusterID, shardCount+1)
+
+			if before != after {
+				moved++
+			}
+			// Removing the added shard again must restore the assignment.
+			if after != shardCount && GetShard(clusterID, shardCount) != after {
+				t.Fatalf("cluster %#q not restored to shard %d when removing shard %d", clusterID, after, shardCount)
+			}
+		}
+
+		// Ideally, the new shard receives its share of clusters and no other
+		// cluster moves. Allow 25% above the ideal.
+		ideal := float64(clusterCount) / float64(shardCount+1)
+		if float64(moved) > ideal*1.25 {
+			t.Fatalf("%d to %d shards: expected about %.0f clusters to move, %d moved", shardCount, shardCount+1, ideal, moved)
+		}
+	}
+}
@@ -3,9 +3,95 @@ package prometheus
 import (
 	"strings"
 
+	"github.com/giantswarm/microerror"
 	"github.com/prometheus/prometheus/config"
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
 )
 
+// RenderConfig takes Prometheus configuration data and a list of Kubernetes
+// Services, and returns the configuration data including the scrape configs of
+// the Services' clusters. It is shared by the configmap resource and the
+// render command, so that both produce identical configurations.
+func RenderConfig(data string, services []v1.Service, metaConfig Config) ([]byte, error) {
+	promcfg, err := LoadConfig(data)
+	if err != nil {
+		return nil, microerror.Maskf(invalidPrometheusConfigError, "failed to load config: %s", err)
+	}
+
+	metaConfig.GlobalScrapeInterval = promcfg.GlobalConfig.ScrapeInterval
+	metaConfig.GlobalScrapeTimeout = promcfg.GlobalConfig.ScrapeTimeout
+
+	scrapeConfigs, err := GetScrapeConfigs(services, metaConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	newPromcfg, err := UpdateConfig(*promcfg, scrapeConfigs)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	newData, err := MarshalConfig(newPromcfg, data)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	urls, err := getHTTPSDURLs(services, metaConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	newData, err = addHTTPSDConfigsToConfig(newData, urls)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return newData, nil
+}
+
+// RenderScrapeConfigs takes a list of Kubernetes Services, and returns the
+// scrape configs of the Services' clusters as YAML list, in the format of the
+// Prometheus Operator's additionalScrapeConfigs.
+func RenderScrapeConfigs(services []v1.Service, metaConfig Config) ([]byte, error) {
+	scrapeConfigs, err := GetScrapeConfigs(services, metaConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	data, err := yaml.Marshal(scrapeConfigs)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	urls, err := getHTTPSDURLs(services, metaConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(urls) > 0 {
+		var items []yaml.MapSlice
+		err = yaml.Unmarshal(data, &items)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		tree := make([]interface{}, len(items))
+		for i := range items {
+			tree[i] = items[i]
+		}
+
+		addHTTPSDConfigs(tree, urls)
+
+		data, err = yaml.Marshal(tree)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return data, nil
+}
+
 // UpdateConfig takes an existing Prometheus configuration,
 // and a list of Prometheus scrape configurations.
 // A new configuration is returned, that includes both the scrape configurations
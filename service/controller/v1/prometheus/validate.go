@@ -0,0 +1,104 @@
+package prometheus
+
+import (
+	"os"
+
+	"github.com/giantswarm/microerror"
+	config_util "github.com/prometheus/common/config"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+)
+
+// ValidateConfig validates the marshalled Prometheus configuration before it
+// is handed to Prometheus, to catch errors Prometheus would only report when
+// reloading. The configuration is parsed like Prometheus does, job names must
+// be unique, and the certificate files of managed jobs must exist. Relabel
+// regexes are compiled when the config is loaded.
+func ValidateConfig(fs afero.Fs, data string) error {
+	// Job names are checked before loading the config, to report the
+	// colliding job by name.
+	var jobs struct {
+		ScrapeConfigs []struct {
+			JobName string `yaml:"job_name"`
+		} `yaml:"scrape_configs"`
+	}
+	err := yaml.Unmarshal([]byte(data), &jobs)
+	if err != nil {
+		return microerror.Maskf(invalidPrometheusConfigError, "failed to parse config: %s", err)
+	}
+
+	jobNames := map[string]bool{}
+	for _, j := range jobs.ScrapeConfigs {
+		if jobNames[j.JobName] {
+			return microerror.Maskf(invalidPrometheusConfigError, "job name %#q is not unique", j.JobName)
+		}
+		jobNames[j.JobName] = true
+	}
+
+	promcfg, err := LoadConfig(data)
+	if err != nil {
+		return microerror.Maskf(invalidPrometheusConfigError, "failed to load config: %s", err)
+	}
+
+	for _, scrapeConfig := range promcfg.ScrapeConfigs {
+		// Certificate files of unmanaged jobs are not necessarily accessible
+		// to the controller.
+		if isManaged(*scrapeConfig) {
+			err = validateTLSFiles(fs, scrapeConfig.JobName, scrapeConfig.HTTPClientConfig.TLSConfig)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			for _, sdConfig := range scrapeConfig.ServiceDiscoveryConfig.KubernetesSDConfigs {
+				err = validateTLSFiles(fs, scrapeConfig.JobName, sdConfig.HTTPClientConfig.TLSConfig)
+				if err != nil {
+					return microerror.Mask(err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateScrapeConfigs validates a list of scrape configs marshalled to YAML,
+// as written to the Prometheus Operator's additionalScrapeConfigs, like
+// ValidateConfig does.
+func ValidateScrapeConfigs(fs afero.Fs, data string) error {
+	var scrapeConfigs []interface{}
+	err := yaml.Unmarshal([]byte(data), &scrapeConfigs)
+	if err != nil {
+		return microerror.Maskf(invalidPrometheusConfigError, "failed to parse scrape configs: %s", err)
+	}
+
+	configData, err := yaml.Marshal(yaml.MapSlice{{Key: scrapeConfigsKey, Value: scrapeConfigs}})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = ValidateConfig(fs, string(configData))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// validateTLSFiles checks that the files referenced by the given TLS config
+// exist.
+func validateTLSFiles(fs afero.Fs, jobName string, tlsConfig config_util.TLSConfig) error {
+	for _, p := range []string{tlsConfig.CAFile, tlsConfig.CertFile, tlsConfig.KeyFile} {
+		if p == "" {
+			continue
+		}
+
+		_, err := fs.Stat(p)
+		if os.IsNotExist(err) {
+			return microerror.Maskf(invalidPrometheusConfigError, "job %#q references missing file %#q", jobName, p)
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
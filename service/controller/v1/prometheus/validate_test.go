@@ -0,0 +1,117 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+// Test_Prometheus_ValidateConfig tests the ValidateConfig function.
+func Test_Prometheus_ValidateConfig(t *testing.T) {
+	tests := []struct {
+		files []string
+		data  string
+
+		expectedErrorHandler func(error) bool
+	}{
+		// 0. Test that a valid config with existing certificate files is
+		// valid.
+		{
+			files: []string{"/certs/xa5ly-ca.pem", "/certs/xa5ly-crt.pem", "/certs/xa5ly-key.pem"},
+			data: `
+scrape_configs:
+- job_name: workload-cluster-xa5ly-apiserver
+  scheme: https
+  tls_config:
+    ca_file: /certs/xa5ly-ca.pem
+    cert_file: /certs/xa5ly-crt.pem
+    key_file: /certs/xa5ly-key.pem
+  static_configs:
+  - targets:
+    - apiserver.xa5ly
+  relabel_configs:
+  - source_labels: [__address__]
+    regex: (.+?)(?::\d+)?
+    target_label: member
+`,
+
+			expectedErrorHandler: nil,
+		},
+
+		// 1. Test that a config Prometheus can not load is invalid.
+		{
+			files: nil,
+			data: `
+scrape_configs:
+- job_name: workload-cluster-xa5ly-apiserver
+  scrape_interval: 1 minute
+`,
+
+			expectedErrorHandler: IsInvalidPrometheusConfig,
+		},
+
+		// 2. Test that duplicate job names are invalid.
+		{
+			files: nil,
+			data: `
+scrape_configs:
+- job_name: workload-cluster-xa5ly-apiserver
+- job_name: workload-cluster-xa5ly-apiserver
+`,
+
+			expectedErrorHandler: IsInvalidPrometheusConfig,
+		},
+
+		// 3. Test that a managed job referencing a missing certificate file
+		// is invalid.
+		{
+			files: []string{"/certs/xa5ly-ca.pem", "/certs/xa5ly-crt.pem"},
+			data: `
+scrape_configs:
+- job_name: workload-cluster-xa5ly-apiserver
+  scheme: https
+  tls_config:
+    ca_file: /certs/xa5ly-ca.pem
+    cert_file: /certs/xa5ly-crt.pem
+    key_file: /certs/xa5ly-key.pem
+`,
+
+			expectedErrorHandler: IsInvalidPrometheusConfig,
+		},
+
+		// 4. Test that certificate files of unmanaged jobs are not checked.
+		{
+			files: nil,
+			data: `
+scrape_configs:
+- job_name: federation
+  scheme: https
+  tls_config:
+    ca_file: /etc/prometheus/secrets/ca.pem
+`,
+
+			expectedErrorHandler: nil,
+		},
+	}
+
+	for index, test := range tests {
+		fs := afero.NewMemMapFs()
+		for _, f := range test.files {
+			err := afero.WriteFile(fs, f, []byte("test"), 0644)
+			if err != nil {
+				t.Fatalf("%d: error returned writing file: %s\n", index, err)
+			}
+		}
+
+		err := ValidateConfig(fs, test.data)
+		if err != nil && test.expectedErrorHandler == nil {
+			t.Fatalf("%d: unexpected error returned validating config: %s\n", index, err)
+		}
+		if err != nil && !test.expectedErrorHandler(err) {
+			t.Fatalf("%d: incorrect error returned validating config: %s\n", index, err)
+		}
+		if err == nil && test.expectedErrorHandler != nil {
+			t.Fatalf("%d: expected error not returned validating config\n", index)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package certificate
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/afero"
+)
+
+// The certificate directory is laid out like the volumes written by the
+// kubelet's AtomicWriter. Every certificate file is a symbolic link into the
+// data directory link, which points to a timestamped directory holding the
+// full certificate set. Updating the data directory link swaps all
+// certificates at once. Entries prefixed with hiddenPrefix are internal to
+// this layout.
+//
+//	/certs/xa5ly-ca.pem -> ..data/xa5ly-ca.pem
+//	/certs/..data -> ..2021_02_03_10_00_00.000000000
+//	/certs/..2021_02_03_10_00_00.000000000/xa5ly-ca.pem -> ../..objects/<sha256>
+//	/certs/..objects/<sha256>
+const (
+	// hiddenPrefix prefixes the internal entries of the certificate
+	// directory.
+	hiddenPrefix = ".."
+	// dataDirName is the symbolic link pointing to the timestamped directory
+	// of the current certificate set.
+	dataDirName = "..data"
+	// dataDirTmpName is the symbolic link renamed onto dataDirName to swap
+	// the certificate set.
+	dataDirTmpName = "..data_tmp"
+	// objectsDirName is the directory holding the contents of the
+	// certificates named by their SHA-256 hash, so that unchanged
+	// certificates are never rewritten.
+	objectsDirName = "..objects"
+	// timestampDirFormat is the time format of the timestamped directories.
+	timestampDirFormat = "..2006_01_02_15_04_05.000000000"
+)
+
+// isHidden returns whether the given name of an entry of the certificate
+// directory is internal to the atomic layout.
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, hiddenPrefix)
+}
+
+// objectName returns the name of the object holding the given data.
+func objectName(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// writeAtomic writes the given certificate files into a new timestamped
+// directory and swaps the data directory link to it. Certificates whose
+// contents did not change are not rewritten.
+func (r *Resource) writeAtomic(ctx context.Context, linker afero.Symlinker, certificateFiles []certificateFile) error {
+	objectsDir := path.Join(r.certDirectory, objectsDirName)
+	if err := r.fs.MkdirAll(objectsDir, 0755); err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Write the contents of new and changed certificates.
+	objects := map[string]bool{}
+	var written int
+	for _, f := range certificateFiles {
+		data := []byte(f.data)
+		object := objectName(data)
+		objects[object] = true
+
+		// Check the contents of the object, so that unchanged certificates
+		// are not rewritten, and damaged objects are.
+		objectPath := path.Join(objectsDir, object)
+		current, err := afero.ReadFile(r.fs, objectPath)
+		if err == nil && objectName(current) == object {
+			continue
+		} else if err != nil && !os.IsNotExist(err) {
+			return microerror.Mask(err)
+		}
+
+		r.logger.LogCtx(ctx, "debug", fmt.Sprintf("writing certificate: %s", f.path))
+		if err := r.writeFile(objectPath, data); err != nil {
+			return microerror.Mask(err)
+		}
+		written++
+	}
+	r.logger.LogCtx(ctx, "debug", fmt.Sprintf("wrote %d of %d certificates", written, len(certificateFiles)))
+
+	// Link the full certificate set from a new timestamped directory.
+	tsDirName := time.Now().UTC().Format(timestampDirFormat)
+	tsDir := path.Join(r.certDirectory, tsDirName)
+	if err := r.fs.Mkdir(tsDir, 0755); err != nil {
+		return microerror.Mask(err)
+	}
+	for _, f := range certificateFiles {
+		target := path.Join("..", objectsDirName, objectName([]byte(f.data)))
+		if err := linker.SymlinkIfPossible(target, path.Join(tsDir, path.Base(f.path))); err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	// Swap the data directory link to the new timestamped directory.
+	r.logger.LogCtx(ctx, "debug", fmt.Sprintf("swapping certificate directory to %s", tsDirName))
+	if err := r.replaceSymlink(linker, tsDirName, path.Join(r.certDirectory, dataDirTmpName), path.Join(r.certDirectory, dataDirName)); err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Link the certificate files through the data directory link.
+	desired := map[string]bool{}
+	for _, f := range certificateFiles {
+		name := path.Base(f.path)
+		desired[name] = true
+
+		target := path.Join(dataDirName, name)
+		if current, err := linker.ReadlinkIfPossible(f.path); err == nil && current == target {
+			continue
+		}
+
+		tmp := path.Join(r.certDirectory, hiddenPrefix+name+"_tmp")
+		if err := r.replaceSymlink(linker, target, tmp, f.path); err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	// Remove unwanted certificate files, previous timestamped directories
+	// and unreferenced objects.
+	fileInfos, err := afero.ReadDir(r.fs, r.certDirectory)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	for _, fileInfo := range fileInfos {
+		name := fileInfo.Name()
+		if desired[name] || name == dataDirName || name == objectsDirName || name == tsDirName {
+			continue
+		}
+
+		if !isHidden(name) {
+			r.logger.LogCtx(ctx, "debug", fmt.Sprintf("removing certificate: %s", path.Join(r.certDirectory, name)))
+		}
+		if err := r.fs.RemoveAll(path.Join(r.certDirectory, name)); err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	objectInfos, err := afero.ReadDir(r.fs, objectsDir)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	for _, objectInfo := range objectInfos {
+		if objects[objectInfo.Name()] {
+			continue
+		}
+
+		if err := r.fs.Remove(path.Join(objectsDir, objectInfo.Name())); err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// writeFile writes the given data to a temporary file, which is renamed onto
+// the given path once complete.
+func (r *Resource) writeFile(p string, data []byte) error {
+	tmp := p + "_tmp"
+	if err := afero.WriteFile(r.fs, tmp, data, r.certPermission); err != nil {
+		return microerror.Mask(err)
+	}
+	if err := r.fs.Rename(tmp, p); err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// replaceSymlink atomically replaces the given path by a symbolic link to the
+// given target, by renaming a link created at the given temporary path onto
+// it.
+func (r *Resource) replaceSymlink(linker afero.Symlinker, target, tmp, p string) error {
+	if err := r.fs.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return microerror.Mask(err)
+	}
+	if err := linker.SymlinkIfPossible(target, tmp); err != nil {
+		return microerror.Mask(err)
+	}
+	if err := r.fs.Rename(tmp, p); err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package certificate
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/spf13/afero"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+// Test_Resource_Certificate_ApplyUpdateChange_Atomic tests the
+// ApplyUpdateChange method on a filesystem supporting symbolic links. File
+// paths are relative to a temporary certificate directory.
+func Test_Resource_Certificate_ApplyUpdateChange_Atomic(t *testing.T) {
+	tests := []struct {
+		// inPlaceCertificateFiles are written as regular files before the
+		// current certificate files are applied.
+		inPlaceCertificateFiles []certificateFile
+		currentCertificateFiles []certificateFile
+		updateChange            []certificateFile
+
+		expectedCertificateFiles []certificateFile
+		// expectedUnchanged are the certificates which must not be
+		// rewritten.
+		expectedUnchanged []string
+	}{
+		// Test that certificates are written into an empty directory.
+		{
+			updateChange: []certificateFile{
+				{path: "bar", data: "bar"},
+				{path: "foo", data: "foo"},
+			},
+
+			expectedCertificateFiles: []certificateFile{
+				{path: "bar", data: "bar"},
+				{path: "foo", data: "foo"},
+			},
+		},
+
+		// Test that when one of two certificates changes, the changed
+		// certificate is updated, and the other one is not rewritten.
+		{
+			currentCertificateFiles: []certificateFile{
+				{path: "bar", data: "bar"},
+				{path: "foo", data: "foo"},
+			},
+			updateChange: []certificateFile{
+				{path: "bar", data: "baz"},
+				{path: "foo", data: "foo"},
+			},
+
+			expectedCertificateFiles: []certificateFile{
+				{path: "bar", data: "baz"},
+				{path: "foo", data: "foo"},
+			},
+			expectedUnchanged: []string{"foo"},
+		},
+
+		// Test that certificates which are not desired anymore are removed.
+		{
+			currentCertificateFiles: []certificateFile{
+				{path: "bar", data: "bar"},
+				{path: "foo", data: "foo"},
+			},
+			updateChange: []certificateFile{
+				{path: "foo", data: "foo"},
+			},
+
+			expectedCertificateFiles: []certificateFile{
+				{path: "foo", data: "foo"},
+			},
+			expectedUnchanged: []string{"foo"},
+		},
+
+		// Test that certificates written in place are replaced.
+		{
+			inPlaceCertificateFiles: []certificateFile{
+				{path: "bar", data: "bar"},
+				{path: "foo", data: "foo"},
+			},
+			updateChange: []certificateFile{
+				{path: "foo", data: "baz"},
+			},
+
+			expectedCertificateFiles: []certificateFile{
+				{path: "foo", data: "baz"},
+			},
+		},
+	}
+
+	for index, test := range tests {
+		certificateDirectory, err := ioutil.TempDir("", "certificate")
+		if err != nil {
+			t.Fatalf("%d: error returned creating certificate directory: %s\n", index, err)
+		}
+		defer os.RemoveAll(certificateDirectory)
+
+		withDirectory := func(certificateFiles []certificateFile) []certificateFile {
+			if certificateFiles == nil {
+				return nil
+			}
+			var files []certificateFile
+			for _, f := range certificateFiles {
+				files = append(files, certificateFile{
+					path: path.Join(certificateDirectory, f.path),
+					data: f.data,
+				})
+			}
+			return files
+		}
+
+		fakeInventory := newInventory(t, fake.NewSimpleClientset())
+
+		resourceConfig := Config{}
+
+		resourceConfig.CertificateSource = newCertificateSource(t, fakeInventory)
+		resourceConfig.ClusterSource = newClusterSource(t, fakeInventory)
+		resourceConfig.DryRun = newDryRun(t, false)
+		resourceConfig.EventRecorder = record.NewFakeRecorder(100)
+		resourceConfig.Fs = afero.NewOsFs()
+		resourceConfig.Logger = microloggertest.New()
+
+		resourceConfig.CertDirectory = certificateDirectory
+		resourceConfig.CertPermission = 0600
+		resourceConfig.Output = OutputDirectory
+
+		resource, err := New(resourceConfig)
+		if err != nil {
+			t.Fatalf("%d: error returned creating resource: %s\n", index, err)
+		}
+
+		for _, f := range withDirectory(test.inPlaceCertificateFiles) {
+			if err := ioutil.WriteFile(f.path, []byte(f.data), 0600); err != nil {
+				t.Fatalf("%d: error returned writing certificate file: %s\n", index, err)
+			}
+		}
+
+		if test.currentCertificateFiles != nil {
+			if err := resource.ApplyUpdateChange(context.TODO(), v1.Service{}, withDirectory(test.currentCertificateFiles)); err != nil {
+				t.Fatalf("%d: error returned applying current certificate files: %s\n", index, err)
+			}
+		}
+
+		unchangedFileInfos := map[string]os.FileInfo{}
+		for _, name := range test.expectedUnchanged {
+			fileInfo, err := os.Stat(path.Join(certificateDirectory, name))
+			if err != nil {
+				t.Fatalf("%d: error returned getting file info: %s\n", index, err)
+			}
+			unchangedFileInfos[name] = fileInfo
+		}
+
+		if err := resource.ApplyUpdateChange(context.TODO(), v1.Service{}, withDirectory(test.updateChange)); err != nil {
+			t.Fatalf("%d: error returned applying update change: %s\n", index, err)
+		}
+
+		currentState, err := resource.GetCurrentState(context.TODO(), v1.Service{})
+		if err != nil {
+			t.Fatalf("%d: error returned getting current state: %s\n", index, err)
+		}
+		currentStateCertificateFiles, err := toCertificateFiles(currentState)
+		if err != nil {
+			t.Fatalf("%d: could not cast current state to certificate files: %s\n", index, spew.Sdump(currentState))
+		}
+
+		if !reflect.DeepEqual(withDirectory(test.expectedCertificateFiles), currentStateCertificateFiles) {
+			t.Fatalf(
+				"%d: expected certificate files do not match certificate files on disk.\nexpected:\n%s\nreturned:\n%s\n",
+				index,
+				spew.Sdump(withDirectory(test.expectedCertificateFiles)),
+				spew.Sdump(currentStateCertificateFiles),
+			)
+		}
+
+		for name, before := range unchangedFileInfos {
+			after, err := os.Stat(path.Join(certificateDirectory, name))
+			if err != nil {
+				t.Fatalf("%d: error returned getting file info: %s\n", index, err)
+			}
+			if !os.SameFile(before, after) {
+				t.Fatalf("%d: expected certificate %#q not to be rewritten", index, name)
+			}
+		}
+
+		// Only the data directory link, the objects directory and the
+		// current timestamped directory are left.
+		fileInfos, err := ioutil.ReadDir(certificateDirectory)
+		if err != nil {
+			t.Fatalf("%d: error returned reading certificate directory: %s\n", index, err)
+		}
+		var hidden int
+		for _, fileInfo := range fileInfos {
+			if isHidden(fileInfo.Name()) {
+				hidden++
+			}
+		}
+		if hidden != 3 {
+			t.Fatalf("%d: expected 3 hidden entries in the certificate directory, got %d", index, hidden)
+		}
+	}
+}
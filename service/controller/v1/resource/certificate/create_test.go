@@ -8,23 +8,26 @@ import (
 	"github.com/spf13/afero"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 )
 
 // Test_Resource_Certificate_ApplyCreateChange tests the ApplyCreateChange method.
 func Test_Resource_Certificate_ApplyCreateChange(t *testing.T) {
 	fs := afero.NewMemMapFs()
-	fakeK8sClient := fake.NewSimpleClientset()
+	fakeInventory := newInventory(t, fake.NewSimpleClientset())
 
 	resourceConfig := Config{}
 
+	resourceConfig.CertificateSource = newCertificateSource(t, fakeInventory)
+	resourceConfig.ClusterSource = newClusterSource(t, fakeInventory)
+	resourceConfig.DryRun = newDryRun(t, false)
+	resourceConfig.EventRecorder = record.NewFakeRecorder(100)
 	resourceConfig.Fs = fs
-	resourceConfig.K8sClient = fakeK8sClient
 	resourceConfig.Logger = microloggertest.New()
 
-	resourceConfig.CertComponentName = "prometheus"
 	resourceConfig.CertDirectory = "/certs"
-	resourceConfig.CertNamespace = "default"
 	resourceConfig.CertPermission = 0644
+	resourceConfig.Output = OutputDirectory
 
 	resource, err := New(resourceConfig)
 	if err != nil {
@@ -10,6 +10,26 @@ import (
 )
 
 func (r *Resource) GetCurrentState(ctx context.Context, obj interface{}) (interface{}, error) {
+	var certificateFiles []certificateFile
+	var err error
+
+	switch r.output {
+	case OutputProjection:
+		certificateFiles, err = r.readProjection(ctx)
+	default:
+		certificateFiles, err = r.readDirectory(ctx)
+	}
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	certificateCount.Set(float64(len(certificateFiles)))
+
+	return certificateFiles, nil
+}
+
+// readDirectory returns the certificate files of the certificate directory.
+func (r *Resource) readDirectory(ctx context.Context) ([]certificateFile, error) {
 	r.logger.LogCtx(ctx, "debug", fmt.Sprintf("reading certificate directory: %s", r.certDirectory))
 
 	fileInfos, err := afero.ReadDir(r.fs, r.certDirectory)
@@ -20,6 +40,11 @@ func (r *Resource) GetCurrentState(ctx context.Context, obj interface{}) (interf
 	certificateFiles := []certificateFile{}
 
 	for _, fileInfo := range fileInfos {
+		// Skip the internals of the atomic certificate directory layout.
+		if isHidden(fileInfo.Name()) {
+			continue
+		}
+
 		filePath := path.Join(r.certDirectory, fileInfo.Name())
 		fileData, err := afero.ReadFile(r.fs, filePath)
 		if err != nil {
@@ -32,7 +57,5 @@ func (r *Resource) GetCurrentState(ctx context.Context, obj interface{}) (interf
 		})
 	}
 
-	certificateCount.Set(float64(len(certificateFiles)))
-
 	return certificateFiles, nil
 }
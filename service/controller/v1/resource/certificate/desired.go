@@ -3,31 +3,44 @@ package certificate
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/giantswarm/microerror"
 	prometheusclient "github.com/prometheus/client_golang/prometheus"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/giantswarm/prometheus-config-controller/service/controller/certificatesource"
 	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
 	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
 )
 
 const (
-	caKey  = "ca"  // CaKey is the key in the Secret that holds the CA.
-	crtKey = "crt" // CrtKey is the key in the Secret that holds the certificate.
-	keyKey = "key" // KeyKey is the key in the Secret that holds the key.
-
-	// ServiceLabelSelector is the label selector to match master services.
-	serviceLabelSelector = "app=master"
+	caKey  = "ca"  // CaKey is the key of the CA.
+	crtKey = "crt" // CrtKey is the key of the certificate.
+	keyKey = "key" // KeyKey is the key of the key.
 )
 
+// certificateData returns the parts of the given certificate by their key,
+// leaving out missing parts.
+func certificateData(certificate certificatesource.Certificate) map[string][]byte {
+	data := map[string][]byte{}
+	if certificate.CA != nil {
+		data[caKey] = certificate.CA
+	}
+	if certificate.Crt != nil {
+		data[crtKey] = certificate.Crt
+	}
+	if certificate.Key != nil {
+		data[keyKey] = certificate.Key
+	}
+
+	return data
+}
+
 func (r *Resource) GetDesiredState(ctx context.Context, obj interface{}) (interface{}, error) {
-	r.logger.LogCtx(ctx, "debug", "fetching all services")
+	r.logger.LogCtx(ctx, "debug", "fetching all clusters")
 
-	servicesTimer := prometheusclient.NewTimer(kubernetesResource.WithLabelValues("services", "list"))
-	services, err := r.k8sClient.CoreV1().Services("").List(ctx, metav1.ListOptions{
-		LabelSelector: serviceLabelSelector,
-	})
+	servicesTimer := prometheusclient.NewTimer(kubernetesResource.WithLabelValues("clusters", "list"))
+	services, err := r.clusterSource.Clusters(ctx)
 	servicesTimer.ObserveDuration()
 
 	if err != nil {
@@ -35,29 +48,28 @@ func (r *Resource) GetDesiredState(ctx context.Context, obj interface{}) (interf
 	}
 
 	r.logger.LogCtx(ctx, "debug", "filtering services")
-	validServices := prometheus.FilterInvalidServices(services.Items)
+	validServices := prometheus.FilterInvalidServices(services)
+	validServices = prometheus.FilterShardServices(validServices, r.shardIndex, r.shardCount)
 
 	r.logger.LogCtx(ctx, "debug", "fetching certificates")
 	certificateFiles := []certificateFile{}
 
+	now := time.Now()
+	labels := gaugeLabels{}
+
+	var clusterIDs []string
 	for _, service := range validServices {
-		clusterID := prometheus.GetClusterID(service)
-
-		certificatesTimer := prometheusclient.NewTimer(kubernetesResource.WithLabelValues("secrets", "list"))
-		certificates, err := r.k8sClient.CoreV1().Secrets(r.certNamespace).List(ctx, metav1.ListOptions{
-			LabelSelector: fmt.Sprintf(
-				"clusterComponent=%s, clusterID=%s",
-				r.certComponentName,
-				clusterID,
-			),
-		})
-		certificatesTimer.ObserveDuration()
+		clusterIDs = append(clusterIDs, prometheus.GetClusterID(service))
+	}
+
+	for _, clusterID := range clusterIDs {
 
+		certificate, err := r.certificateSource.Certificate(ctx, clusterID)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
 
-		if len(certificates.Items) == 0 {
+		if certificate == nil {
 			// If the certificate can't be found, try to continue on.
 			// It's possible that the certificate just hasn't been created yet.
 			// If the certificate is consistently missing, we'll be notified
@@ -65,22 +77,26 @@ func (r *Resource) GetDesiredState(ctx context.Context, obj interface{}) (interf
 			r.logger.LogCtx(ctx, "warning", fmt.Sprintf("certificate for cluster '%s' is missing, continuing", clusterID))
 			continue
 		}
-		certificate := certificates.Items[0]
 
-		for _, certificateKey := range []string{caKey, crtKey, keyKey} {
-			if data, ok := certificate.Data[certificateKey]; ok {
-				var p string
-				switch certificateKey {
-				case caKey:
-					p = key.CAPath(r.certDirectory, clusterID)
-				case crtKey:
-					p = key.CrtPath(r.certDirectory, clusterID)
-				case keyKey:
-					p = key.KeyPath(r.certDirectory, clusterID)
-				}
+		r.checkExpiry(ctx, clusterID, *certificate, now, labels)
+
+		reason, err := validateCertificate(*certificate, now)
+		if IsInvalidCertificate(err) {
+			lastGoodFiles, err := r.rejectCertificate(ctx, clusterID, *certificate, reason, err, labels)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
 
+			certificateFiles = append(certificateFiles, lastGoodFiles...)
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, certificateKey := range []string{caKey, crtKey, keyKey} {
+			if data, ok := certificateData(*certificate)[certificateKey]; ok {
 				certificateFiles = append(certificateFiles, certificateFile{
-					path: p,
+					path: r.certificatePath(certificateKey, clusterID),
 					data: string(data),
 				})
 			}
@@ -89,5 +105,32 @@ func (r *Resource) GetDesiredState(ctx context.Context, obj interface{}) (interf
 
 	r.logger.LogCtx(ctx, "debug", "certificates fetched")
 
+	// Certificate sources may keep state per cluster, e.g. issued
+	// certificates, which must not outlive deleted clusters.
+	err = r.certificateSource.Prune(ctx, clusterIDs)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// The series of clusters which are gone or whose certificates changed
+	// are only deleted after a successful pass.
+	r.mutex.Lock()
+	labels.deleteStale(r.exposedLabels)
+	r.exposedLabels = labels
+	r.mutex.Unlock()
+
 	return certificateFiles, nil
 }
+
+// certificatePath returns the path of the file holding the given key of the
+// certificate of the given cluster.
+func (r *Resource) certificatePath(certificateKey, clusterID string) string {
+	switch certificateKey {
+	case caKey:
+		return key.CAPath(r.certDirectory, clusterID)
+	case crtKey:
+		return key.CrtPath(r.certDirectory, clusterID)
+	default:
+		return key.KeyPath(r.certDirectory, clusterID)
+	}
+}
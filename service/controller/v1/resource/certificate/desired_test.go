@@ -4,14 +4,18 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/giantswarm/micrologger/microloggertest"
 	"github.com/spf13/afero"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 
+	"github.com/giantswarm/prometheus-config-controller/pkg/label"
 	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
 	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
 )
@@ -20,10 +24,49 @@ import (
 func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 	defaultCertificateDirectory := "/certs"
 
+	now := time.Now()
+	pki := newTestPKI(t, now.Add(-time.Hour), now.Add(24*time.Hour))
+	otherPKI := newTestPKI(t, now.Add(-time.Hour), now.Add(24*time.Hour))
+	expiredPKI := newTestPKI(t, now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+
+	xa5lyService := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "apiserver",
+			Namespace: "xa5ly",
+			Annotations: map[string]string{
+				prometheus.ClusterAnnotation: "xa5ly",
+			},
+			Labels: map[string]string{
+				"app":         "master",
+				label.Cluster: "xa5ly",
+			},
+		},
+	}
+	xa5lySecret := func(ca, crt, key string) *v1.Secret {
+		return &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "xa5ly-prometheus",
+				Namespace: "default",
+				Labels: map[string]string{
+					"clusterComponent": "prometheus",
+					"clusterID":        "xa5ly",
+				},
+			},
+			Data: map[string][]byte{
+				"ca":  []byte(ca),
+				"crt": []byte(crt),
+				"key": []byte(key),
+			},
+		}
+	}
+
 	tests := []struct {
 		certificateDirectory string
-		services             []*v1.Service
-		secrets              []*v1.Secret
+		// currentCertificateFiles are written to the filesystem before
+		// getting the desired state.
+		currentCertificateFiles []certificateFile
+		services                []*v1.Service
+		secrets                 []*v1.Secret
 
 		expectedCertificateFiles []certificateFile
 		expectedErrorHandler     func(error) bool
@@ -47,7 +90,8 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 						Name:      "foo",
 						Namespace: "default",
 						Labels: map[string]string{
-							"app": "master",
+							"app":         "master",
+							label.Cluster: "default",
 						},
 					},
 				},
@@ -71,7 +115,8 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 							prometheus.ClusterAnnotation: "xa5ly",
 						},
 						Labels: map[string]string{
-							"app": "master",
+							"app":         "master",
+							label.Cluster: "xa5ly",
 						},
 					},
 				},
@@ -109,9 +154,9 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 						},
 					},
 					Data: map[string][]byte{
-						"ca":  []byte("foo"),
-						"crt": []byte("bar"),
-						"key": []byte("baz"),
+						"ca":  []byte(pki.ca),
+						"crt": []byte(pki.crt),
+						"key": []byte(pki.key),
 					},
 				},
 			},
@@ -134,7 +179,8 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 							prometheus.ClusterAnnotation: "0ajf9",
 						},
 						Labels: map[string]string{
-							"app": "master",
+							"app":         "master",
+							label.Cluster: "0ajf9",
 						},
 					},
 				},
@@ -146,7 +192,8 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 							prometheus.ClusterAnnotation: "xa5ly",
 						},
 						Labels: map[string]string{
-							"app": "master",
+							"app":         "master",
+							label.Cluster: "xa5ly",
 						},
 					},
 				},
@@ -162,9 +209,9 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 						},
 					},
 					Data: map[string][]byte{
-						"ca":  []byte("foo"),
-						"crt": []byte("bar"),
-						"key": []byte("baz"),
+						"ca":  []byte(pki.ca),
+						"crt": []byte(pki.crt),
+						"key": []byte(pki.key),
 					},
 				},
 			},
@@ -172,15 +219,15 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 			expectedCertificateFiles: []certificateFile{
 				{
 					path: key.CAPath(defaultCertificateDirectory, "xa5ly"),
-					data: "foo",
+					data: pki.ca,
 				},
 				{
 					path: key.CrtPath(defaultCertificateDirectory, "xa5ly"),
-					data: "bar",
+					data: pki.crt,
 				},
 				{
 					path: key.KeyPath(defaultCertificateDirectory, "xa5ly"),
-					data: "baz",
+					data: pki.key,
 				},
 			},
 			expectedErrorHandler: nil,
@@ -199,7 +246,8 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 							prometheus.ClusterAnnotation: "xa5ly",
 						},
 						Labels: map[string]string{
-							"app": "master",
+							"app":         "master",
+							label.Cluster: "xa5ly",
 						},
 					},
 				},
@@ -215,7 +263,7 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 						},
 					},
 					Data: map[string][]byte{
-						"ca": []byte("foo"),
+						"ca": []byte(pki.ca),
 					},
 				},
 			},
@@ -226,7 +274,7 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 					// to make the test more specific - we care that the path matches `CAPath` string,
 					// not the exact string.
 					path: key.CAPath(defaultCertificateDirectory, "xa5ly"),
-					data: "foo",
+					data: pki.ca,
 				},
 			},
 			expectedErrorHandler: nil,
@@ -245,7 +293,8 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 							prometheus.ClusterAnnotation: "xa5ly",
 						},
 						Labels: map[string]string{
-							"app": "master",
+							"app":         "master",
+							label.Cluster: "xa5ly",
 						},
 					},
 				},
@@ -261,9 +310,9 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 						},
 					},
 					Data: map[string][]byte{
-						"ca":  []byte("foo"),
-						"crt": []byte("bar"),
-						"key": []byte("baz"),
+						"ca":  []byte(pki.ca),
+						"crt": []byte(pki.crt),
+						"key": []byte(pki.key),
 					},
 				},
 			},
@@ -271,15 +320,15 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 			expectedCertificateFiles: []certificateFile{
 				{
 					path: key.CAPath(defaultCertificateDirectory, "xa5ly"),
-					data: "foo",
+					data: pki.ca,
 				},
 				{
 					path: key.CrtPath(defaultCertificateDirectory, "xa5ly"),
-					data: "bar",
+					data: pki.crt,
 				},
 				{
 					path: key.KeyPath(defaultCertificateDirectory, "xa5ly"),
-					data: "baz",
+					data: pki.key,
 				},
 			},
 			expectedErrorHandler: nil,
@@ -298,7 +347,8 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 							prometheus.ClusterAnnotation: "xa5ly",
 						},
 						Labels: map[string]string{
-							"app": "master",
+							"app":         "master",
+							label.Cluster: "xa5ly",
 						},
 					},
 				},
@@ -310,7 +360,8 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 							prometheus.ClusterAnnotation: "al9qy",
 						},
 						Labels: map[string]string{
-							"app": "master",
+							"app":         "master",
+							label.Cluster: "al9qy",
 						},
 					},
 				},
@@ -326,7 +377,7 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 						},
 					},
 					Data: map[string][]byte{
-						"ca": []byte("foo"),
+						"ca": []byte(pki.ca),
 					},
 				},
 				{
@@ -339,19 +390,19 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 						},
 					},
 					Data: map[string][]byte{
-						"ca": []byte("bar"),
+						"ca": []byte(otherPKI.ca),
 					},
 				},
 			},
 
 			expectedCertificateFiles: []certificateFile{
 				{
-					path: key.CAPath(defaultCertificateDirectory, "xa5ly"),
-					data: "foo",
+					path: key.CAPath(defaultCertificateDirectory, "al9qy"),
+					data: otherPKI.ca,
 				},
 				{
-					path: key.CAPath(defaultCertificateDirectory, "al9qy"),
-					data: "bar",
+					path: key.CAPath(defaultCertificateDirectory, "xa5ly"),
+					data: pki.ca,
 				},
 			},
 			expectedErrorHandler: nil,
@@ -371,7 +422,8 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 							prometheus.ClusterAnnotation: "xa5ly",
 						},
 						Labels: map[string]string{
-							"app": "master",
+							"app":         "master",
+							label.Cluster: "xa5ly",
 						},
 					},
 				},
@@ -387,7 +439,7 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 						},
 					},
 					Data: map[string][]byte{
-						"ca": []byte("foo"),
+						"ca": []byte(pki.ca),
 					},
 				},
 			},
@@ -395,45 +447,109 @@ func Test_Resource_Certificate_GetDesiredState(t *testing.T) {
 			expectedCertificateFiles: []certificateFile{
 				{
 					path: key.CAPath("/foo/bar", "xa5ly"),
-					data: "foo",
+					data: pki.ca,
 				},
 			},
 			expectedErrorHandler: nil,
 		},
+
+		// Test that a certificate whose key does not match the client
+		// certificate is rejected, and no certificate files are returned
+		// when there are none on disk.
+		{
+			certificateDirectory: defaultCertificateDirectory,
+			services:             []*v1.Service{xa5lyService},
+			secrets:              []*v1.Secret{xa5lySecret(pki.ca, pki.crt, otherPKI.key)},
+
+			expectedCertificateFiles: []certificateFile{},
+			expectedErrorHandler:     nil,
+		},
+
+		// Test that a certificate whose client certificate does not chain to
+		// the CA is rejected, and the certificate files on disk are kept.
+		{
+			certificateDirectory: defaultCertificateDirectory,
+			currentCertificateFiles: []certificateFile{
+				{
+					path: key.CAPath(defaultCertificateDirectory, "xa5ly"),
+					data: pki.ca,
+				},
+				{
+					path: key.CrtPath(defaultCertificateDirectory, "xa5ly"),
+					data: pki.crt,
+				},
+				{
+					path: key.KeyPath(defaultCertificateDirectory, "xa5ly"),
+					data: pki.key,
+				},
+			},
+			services: []*v1.Service{xa5lyService},
+			secrets:  []*v1.Secret{xa5lySecret(pki.ca, otherPKI.crt, otherPKI.key)},
+
+			expectedCertificateFiles: []certificateFile{
+				{
+					path: key.CAPath(defaultCertificateDirectory, "xa5ly"),
+					data: pki.ca,
+				},
+				{
+					path: key.CrtPath(defaultCertificateDirectory, "xa5ly"),
+					data: pki.crt,
+				},
+				{
+					path: key.KeyPath(defaultCertificateDirectory, "xa5ly"),
+					data: pki.key,
+				},
+			},
+			expectedErrorHandler: nil,
+		},
+
+		// Test that an expired certificate is rejected.
+		{
+			certificateDirectory: defaultCertificateDirectory,
+			services:             []*v1.Service{xa5lyService},
+			secrets:              []*v1.Secret{xa5lySecret(expiredPKI.ca, expiredPKI.crt, expiredPKI.key)},
+
+			expectedCertificateFiles: []certificateFile{},
+			expectedErrorHandler:     nil,
+		},
 	}
 
 	for index, test := range tests {
 		fs := afero.NewMemMapFs()
-		fakeK8sClient := fake.NewSimpleClientset()
+		for _, f := range test.currentCertificateFiles {
+			if err := afero.WriteFile(fs, f.path, []byte(f.data), 0644); err != nil {
+				t.Fatalf("%d: error returned writing certificate file: %s\n", index, err)
+			}
+		}
+		// The objects are known to the clientset before the inventory is
+		// started, so that they are cached once its caches are synced.
+		var objects []runtime.Object
+		for _, service := range test.services {
+			objects = append(objects, service)
+		}
+		for _, secret := range test.secrets {
+			objects = append(objects, secret)
+		}
+		fakeInventory := newInventory(t, fake.NewSimpleClientset(objects...))
 
 		resourceConfig := Config{}
 
+		resourceConfig.CertificateSource = newCertificateSource(t, fakeInventory)
+		resourceConfig.ClusterSource = newClusterSource(t, fakeInventory)
+		resourceConfig.DryRun = newDryRun(t, false)
+		resourceConfig.EventRecorder = record.NewFakeRecorder(100)
 		resourceConfig.Fs = fs
-		resourceConfig.K8sClient = fakeK8sClient
 		resourceConfig.Logger = microloggertest.New()
 
-		resourceConfig.CertComponentName = "prometheus"
 		resourceConfig.CertDirectory = test.certificateDirectory
-		resourceConfig.CertNamespace = "default"
 		resourceConfig.CertPermission = 0644
+		resourceConfig.Output = OutputDirectory
 
 		resource, err := New(resourceConfig)
 		if err != nil {
 			t.Fatalf("%d: error returned creating resource: %s\n", index, err)
 		}
 
-		for _, service := range test.services {
-			if _, err := fakeK8sClient.CoreV1().Services(service.Namespace).Create(context.TODO(), service, metav1.CreateOptions{}); err != nil {
-				t.Fatalf("%d: error returned creating service: %s\n", index, err)
-			}
-		}
-
-		for _, secret := range test.secrets {
-			if _, err := fakeK8sClient.CoreV1().Secrets(secret.Namespace).Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil {
-				t.Fatalf("%d: error returned creating secret: %s\n", index, err)
-			}
-		}
-
 		desiredState, err := resource.GetDesiredState(context.TODO(), v1.Service{})
 
 		if err != nil && test.expectedErrorHandler == nil {
@@ -21,3 +21,12 @@ var wrongTypeError = &microerror.Error{
 func IsWrongTypeError(err error) bool {
 	return microerror.Cause(err) == wrongTypeError
 }
+
+var invalidCertificateError = &microerror.Error{
+	Kind: "invalidCertificateError",
+}
+
+// IsInvalidCertificate asserts invalidCertificateError.
+func IsInvalidCertificate(err error) bool {
+	return microerror.Cause(err) == invalidCertificateError
+}
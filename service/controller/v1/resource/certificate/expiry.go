@@ -0,0 +1,98 @@
+package certificate
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/certificatesource"
+)
+
+const (
+	// certificateExpiringEventReason is the reason of the Event recorded
+	// when a certificate expires within the expiry warning window.
+	certificateExpiringEventReason = "CertificateExpiring"
+)
+
+// checkExpiry exposes the expiry of the CA and client certificate of the given
+// certificate, and warns about certificates expiring within the expiry warning
+// window. Only the certificates of the chain the client certificate verifies
+// against are checked, so that a CA bundle may still hold a rotated CA which
+// expired. The earliest expiry of the chain's certificates of a bundle is
+// exposed. When the chain can not be verified, all certificates are checked.
+func (r *Resource) checkExpiry(ctx context.Context, clusterID string, c certificatesource.Certificate, now time.Time, labels gaugeLabels) {
+	parts := certificateData(c)
+
+	bundles := map[string][]*x509.Certificate{}
+	for _, certificateKey := range []string{caKey, crtKey} {
+		data, ok := parts[certificateKey]
+		if !ok {
+			continue
+		}
+
+		certificates, err := parseCertificates(data)
+		if err != nil {
+			r.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("failed to parse %#q of certificate %#q of cluster %#q", certificateKey, c.Name, clusterID), "stack", fmt.Sprintf("%#v", err))
+			continue
+		}
+
+		bundles[certificateKey] = certificates
+	}
+
+	// The chain is verified at the time the client certificate was issued,
+	// so that it is still found once the client certificate or its CA
+	// expired.
+	if len(bundles[caKey]) > 0 && len(bundles[crtKey]) > 0 {
+		chain, err := verifyChain(bundles[caKey], bundles[crtKey], bundles[crtKey][0].NotBefore)
+		if err == nil {
+			for certificateKey, certificates := range bundles {
+				bundles[certificateKey] = chainCertificates(certificates, chain)
+			}
+		}
+	}
+
+	for _, certificateKey := range []string{caKey, crtKey} {
+		certificates := bundles[certificateKey]
+		if len(certificates) == 0 {
+			continue
+		}
+
+		notAfter := certificates[0].NotAfter
+		for _, certificate := range certificates {
+			if certificate.NotAfter.Before(notAfter) {
+				notAfter = certificate.NotAfter
+			}
+
+			if certificate.NotAfter.Sub(now) <= r.expiryWarningWindow {
+				message := fmt.Sprintf("%s %s of certificate %s of cluster %s expires at %s", certificateKey, certificate.Subject.CommonName, c.Name, clusterID, certificate.NotAfter.UTC().Format(time.RFC3339))
+
+				r.logger.LogCtx(ctx, "level", "warning", "message", message)
+				if c.Object != nil {
+					r.eventRecorder.Event(c.Object, v1.EventTypeWarning, certificateExpiringEventReason, message)
+				}
+			}
+		}
+
+		labels.set(certificateNotAfter, float64(notAfter.Unix()), clusterID, certificateKey)
+		labels.set(certificateExpiry, notAfter.Sub(now).Seconds(), clusterID, certificateKey)
+	}
+}
+
+// chainCertificates returns the given certificates which are part of the given
+// chain.
+func chainCertificates(certificates []*x509.Certificate, chain []*x509.Certificate) []*x509.Certificate {
+	var inChain []*x509.Certificate
+	for _, certificate := range certificates {
+		for _, c := range chain {
+			if certificate.Equal(c) {
+				inChain = append(inChain, certificate)
+				break
+			}
+		}
+	}
+
+	return inChain
+}
@@ -0,0 +1,213 @@
+package certificate
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/certificatesource"
+)
+
+// testPKI holds PEM encoded certificates and keys generated for tests.
+type testPKI struct {
+	ca  string
+	crt string
+	key string
+}
+
+// newTestPKI returns a CA and a client certificate signed by it, both valid
+// between the given times.
+func newTestPKI(t *testing.T, notBefore, notAfter time.Time) testPKI {
+	return newTestPKIWithCAValidity(t, notBefore, notAfter, notBefore, notAfter)
+}
+
+// newTestPKIWithCAValidity returns a CA valid between the given CA times, and
+// a client certificate signed by it valid between the other given times.
+func newTestPKIWithCAValidity(t *testing.T, caNotBefore, caNotAfter, notBefore, notAfter time.Time) testPKI {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error returned generating CA key: %s\n", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             caNotBefore,
+		NotAfter:              caNotAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("error returned creating CA certificate: %s\n", err)
+	}
+
+	crtKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error returned generating client key: %s\n", err)
+	}
+	crtTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "prometheus"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	crtDER, err := x509.CreateCertificate(rand.Reader, crtTemplate, caTemplate, &crtKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("error returned creating client certificate: %s\n", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(crtKey)
+	if err != nil {
+		t.Fatalf("error returned marshalling client key: %s\n", err)
+	}
+
+	return testPKI{
+		ca:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})),
+		crt: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: crtDER})),
+		key: string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	}
+}
+
+// Test_Resource_Certificate_checkExpiry tests the checkExpiry method.
+func Test_Resource_Certificate_checkExpiry(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		notAfter time.Time
+		// caNotAfter, when set, lets the CA expire at the given time instead
+		// of together with the client certificate.
+		caNotAfter time.Time
+		// bundledCANotAfter, when set, adds another CA expiring at the given
+		// time to the CA bundle.
+		bundledCANotAfter time.Time
+
+		expectedEvents     int
+		expectedCANotAfter time.Time
+	}{
+		// Test that certificates expiring after the warning window do not
+		// record Events.
+		{
+			notAfter: now.Add(30 * 24 * time.Hour),
+
+			expectedEvents:     0,
+			expectedCANotAfter: now.Add(30 * 24 * time.Hour),
+		},
+
+		// Test that the CA and client certificate expiring within the
+		// warning window each record an Event.
+		{
+			notAfter: now.Add(24 * time.Hour),
+
+			expectedEvents:     2,
+			expectedCANotAfter: now.Add(24 * time.Hour),
+		},
+
+		// Test that a rotated CA of a CA bundle which expired is not checked,
+		// as the client certificate does not chain to it.
+		{
+			notAfter:          now.Add(30 * 24 * time.Hour),
+			bundledCANotAfter: now.Add(-24 * time.Hour),
+
+			expectedEvents:     0,
+			expectedCANotAfter: now.Add(30 * 24 * time.Hour),
+		},
+
+		// Test that the expired CA of the client certificate's chain is still
+		// checked and exposed.
+		{
+			notAfter:   now.Add(30 * 24 * time.Hour),
+			caNotAfter: now.Add(-time.Minute),
+
+			expectedEvents:     1,
+			expectedCANotAfter: now.Add(-time.Minute),
+		},
+	}
+
+	for index, test := range tests {
+		caValidUntil := test.notAfter
+		if !test.caNotAfter.IsZero() {
+			caValidUntil = test.caNotAfter
+		}
+		pki := newTestPKIWithCAValidity(t, now.Add(-time.Hour), caValidUntil, now.Add(-time.Hour), test.notAfter)
+		if !test.bundledCANotAfter.IsZero() {
+			pki.ca += newTestPKI(t, now.Add(-48*time.Hour), test.bundledCANotAfter).ca
+		}
+		eventRecorder := record.NewFakeRecorder(10)
+
+		r := &Resource{
+			eventRecorder: eventRecorder,
+			logger:        microloggertest.New(),
+
+			expiryWarningWindow: 7 * 24 * time.Hour,
+		}
+
+		certificate := certificatesource.Certificate{
+			Name: "xa5ly-prometheus",
+			Object: &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "xa5ly-prometheus",
+					Namespace: "default",
+				},
+			},
+
+			CA:  []byte(pki.ca),
+			Crt: []byte(pki.crt),
+			Key: []byte(pki.key),
+		}
+
+		r.checkExpiry(context.TODO(), "xa5ly", certificate, now, gaugeLabels{})
+
+		caNotAfter := testutil.ToFloat64(certificateNotAfter.WithLabelValues("xa5ly", caKey))
+		if caNotAfter != float64(test.expectedCANotAfter.Unix()) {
+			t.Fatalf("%d: expected CA not after %d, got %d", index, test.expectedCANotAfter.Unix(), int64(caNotAfter))
+		}
+
+		if len(eventRecorder.Events) != test.expectedEvents {
+			t.Fatalf("%d: expected %d events, got %d", index, test.expectedEvents, len(eventRecorder.Events))
+		}
+		for i := 0; i < test.expectedEvents; i++ {
+			event := <-eventRecorder.Events
+			if !strings.Contains(event, certificateExpiringEventReason) {
+				t.Fatalf("%d: expected event with reason %#q, got %#q", index, certificateExpiringEventReason, event)
+			}
+		}
+	}
+}
+
+// Test_Resource_Certificate_gaugeLabels tests that only the series which were
+// not set again are deleted.
+func Test_Resource_Certificate_gaugeLabels(t *testing.T) {
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test"}, []string{"cluster_id", "type"})
+
+	previous := gaugeLabels{}
+	previous.set(g, 1, "xa5ly", caKey)
+	previous.set(g, 1, "0ba9v", caKey)
+
+	current := gaugeLabels{}
+	current.set(g, 2, "xa5ly", caKey)
+	current.deleteStale(previous)
+
+	if n := testutil.CollectAndCount(g); n != 1 {
+		t.Fatalf("expected 1 series, got %d", n)
+	}
+	if v := testutil.ToFloat64(g.WithLabelValues("xa5ly", caKey)); v != 2 {
+		t.Fatalf("expected series of cluster xa5ly to be kept with value 2, got %f", v)
+	}
+}
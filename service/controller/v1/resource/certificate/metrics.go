@@ -1,6 +1,8 @@
 package certificate
 
 import (
+	"strings"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -19,6 +21,65 @@ var (
 		},
 	)
 
+	certificateNotAfter = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "certificate_not_after_timestamp_seconds",
+			Help:      "Timestamp after which the CA or client certificate of a cluster expires.",
+		},
+		[]string{"cluster_id", "type"},
+	)
+
+	certificateExpiry = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "certificate_expiry_seconds",
+			Help:      "Seconds remaining until the CA or client certificate of a cluster expires.",
+		},
+		[]string{"cluster_id", "type"},
+	)
+
+	certificateRejected = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "certificate_rejected",
+			Help:      "Whether the certificate of a cluster was rejected by validation, by rejection reason.",
+		},
+		[]string{"cluster_id", "reason"},
+	)
+
+	certificateRejectionCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "certificate_rejection_count",
+			Help:      "Count of the times a certificate was rejected by validation, by rejection reason.",
+		},
+		[]string{"reason"},
+	)
+
+	projectionSecretSize = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "projection_secret_size_bytes",
+			Help:      "Size of the certificates held in a projected Secret.",
+		},
+		[]string{"secret"},
+	)
+
+	projectionUnplacedClusters = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "projection_unplaced_clusters",
+			Help:      "Number of clusters whose certificates do not fit into the projected Secrets.",
+		},
+	)
+
 	kubernetesResource = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Namespace: prometheusNamespace,
@@ -30,7 +91,41 @@ var (
 	)
 )
 
+// gaugeLabels holds the label values of the gauges set during a
+// reconciliation, so that only the series which were not set again are
+// deleted afterwards. Resetting the gauges instead would make all series
+// disappear while a reconciliation runs.
+type gaugeLabels map[*prometheus.GaugeVec]map[string][]string
+
+// set sets the gauge with the given label values, and records them.
+func (l gaugeLabels) set(g *prometheus.GaugeVec, value float64, labelValues ...string) {
+	g.WithLabelValues(labelValues...).Set(value)
+
+	if l[g] == nil {
+		l[g] = map[string][]string{}
+	}
+	l[g][strings.Join(labelValues, "\xff")] = labelValues
+}
+
+// deleteStale deletes the series recorded in the given previous labels which
+// were not set again.
+func (l gaugeLabels) deleteStale(previous gaugeLabels) {
+	for g, series := range previous {
+		for k, labelValues := range series {
+			if _, ok := l[g][k]; !ok {
+				g.DeleteLabelValues(labelValues...)
+			}
+		}
+	}
+}
+
 func init() {
 	prometheus.MustRegister(certificateCount)
+	prometheus.MustRegister(certificateNotAfter)
+	prometheus.MustRegister(certificateExpiry)
+	prometheus.MustRegister(certificateRejected)
+	prometheus.MustRegister(certificateRejectionCount)
+	prometheus.MustRegister(projectionSecretSize)
+	prometheus.MustRegister(projectionUnplacedClusters)
 	prometheus.MustRegister(kubernetesResource)
 }
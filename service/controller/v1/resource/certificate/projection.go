@@ -0,0 +1,399 @@
+package certificate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/afero"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/giantswarm/prometheus-config-controller/pkg/label"
+	"github.com/giantswarm/prometheus-config-controller/pkg/project"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+)
+
+// With the projection output, certificates are held in a fixed number of
+// Secrets, which Prometheus mounts as a projected volume at the certificate
+// directory. The data keys of the Secrets are the names of the certificate
+// files, so the paths of key.CAPath, key.CrtPath and key.KeyPath resolve on
+// the projected volume.
+//
+//	volumes:
+//	- name: certs
+//	  projected:
+//	    sources:
+//	    - secret:
+//	        name: prometheus-certificates-0
+//	    - secret:
+//	        name: prometheus-certificates-1
+//
+// The projected files are mirrored into the filesystem of the resource, so
+// that the configmap resource and the etcd prober find the certificates the
+// way Prometheus does.
+const (
+	// projectionSecretMaxSize is the maximum size of the data of a projected
+	// Secret. Kubernetes limits Secrets to 1MiB, some of which is left to
+	// the metadata.
+	projectionSecretMaxSize = 1024*1024 - 16*1024
+)
+
+// projectionSecretName returns the name of the projected Secret with the
+// given index.
+func (r *Resource) projectionSecretName(index int) string {
+	return fmt.Sprintf("%s-%d", r.projectionName, index)
+}
+
+// getProjectionSecrets returns the projected Secrets by index. Secrets which
+// do not exist yet are nil.
+func (r *Resource) getProjectionSecrets(ctx context.Context) ([]*v1.Secret, error) {
+	secrets := make([]*v1.Secret, r.projectionSecrets)
+
+	for i := range secrets {
+		secret, err := r.k8sClient.CoreV1().Secrets(r.projectionNamespace).Get(ctx, r.projectionSecretName(i), metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		secrets[i] = secret
+	}
+
+	return secrets, nil
+}
+
+// readProjection returns the certificate files held in the projected Secrets,
+// ordered by path, and mirrors them into the filesystem of the resource.
+func (r *Resource) readProjection(ctx context.Context) ([]certificateFile, error) {
+	r.logger.LogCtx(ctx, "debug", fmt.Sprintf("reading projected certificate secrets: %s", r.projectionName))
+
+	secrets, err := r.getProjectionSecrets(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	certificateFiles := []certificateFile{}
+
+	for _, secret := range secrets {
+		if secret == nil {
+			continue
+		}
+
+		for name, data := range secret.Data {
+			certificateFiles = append(certificateFiles, certificateFile{
+				path: path.Join(r.certDirectory, name),
+				data: string(data),
+			})
+		}
+	}
+
+	sort.Slice(certificateFiles, func(i, j int) bool {
+		return certificateFiles[i].path < certificateFiles[j].path
+	})
+
+	err = r.mirror(certificateFiles)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return certificateFiles, nil
+}
+
+// writeProjection distributes the given certificate files across the
+// projected Secrets, writes the Secrets whose contents changed, and mirrors
+// the files into the filesystem of the resource.
+func (r *Resource) writeProjection(ctx context.Context, certificateFiles []certificateFile) error {
+	current, err := r.getProjectionSecrets(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	desired, unplaced := distributeProjection(current, certificateFiles, r.projectionSecrets, projectionSecretMaxSize)
+
+	for _, group := range unplaced {
+		r.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("certificates of cluster %#q do not fit into the projected secrets, skipping its scrape jobs", group))
+	}
+	projectionUnplacedClusters.Set(float64(len(unplaced)))
+
+	for i, data := range desired {
+		name := r.projectionSecretName(i)
+		projectionSecretSize.WithLabelValues(name).Set(float64(projectionDataSize(data)))
+
+		if current[i] == nil {
+			r.logger.LogCtx(ctx, "debug", fmt.Sprintf("creating projected certificate secret: %s", name))
+
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: r.projectionNamespace,
+					Labels: map[string]string{
+						label.App: project.Name(),
+					},
+				},
+				Type: v1.SecretTypeOpaque,
+				Data: data,
+			}
+
+			_, err := r.k8sClient.CoreV1().Secrets(r.projectionNamespace).Create(ctx, secret, metav1.CreateOptions{})
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			continue
+		}
+
+		if projectionDataEqual(current[i].Data, data) {
+			continue
+		}
+
+		r.logger.LogCtx(ctx, "debug", fmt.Sprintf("updating projected certificate secret: %s", name))
+
+		secret := current[i].DeepCopy()
+		secret.Data = data
+
+		_, err := r.k8sClient.CoreV1().Secrets(r.projectionNamespace).Update(ctx, secret, metav1.UpdateOptions{})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	err = r.deleteStaleProjectionSecrets(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var projected []certificateFile
+	for _, data := range desired {
+		for name, d := range data {
+			projected = append(projected, certificateFile{
+				path: path.Join(r.certDirectory, name),
+				data: string(d),
+			})
+		}
+	}
+
+	err = r.mirror(projected)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// deleteStaleProjectionSecrets deletes the projected Secrets with an index
+// beyond the configured number of Secrets, left behind when the number of
+// Secrets was lowered. Their certificates were moved to the remaining Secrets
+// or dropped by distributeProjection.
+func (r *Resource) deleteStaleProjectionSecrets(ctx context.Context) error {
+	selector := labels.SelectorFromSet(labels.Set{
+		label.App: project.Name(),
+	})
+
+	list, err := r.k8sClient.CoreV1().Secrets(r.projectionNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, secret := range list.Items {
+		suffix := strings.TrimPrefix(secret.Name, r.projectionName+"-")
+		if suffix == secret.Name {
+			continue
+		}
+		index, err := strconv.Atoi(suffix)
+		if err != nil || index < r.projectionSecrets {
+			continue
+		}
+
+		r.logger.LogCtx(ctx, "debug", fmt.Sprintf("deleting stale projected certificate secret: %s", secret.Name))
+
+		err = r.k8sClient.CoreV1().Secrets(r.projectionNamespace).Delete(ctx, secret.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return microerror.Mask(err)
+		}
+
+		projectionSecretSize.DeleteLabelValues(secret.Name)
+	}
+
+	return nil
+}
+
+// mirror writes the given certificate files into the filesystem of the
+// resource, and removes any other file of the certificate directory.
+func (r *Resource) mirror(certificateFiles []certificateFile) error {
+	err := r.fs.MkdirAll(r.certDirectory, 0755)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	desired := map[string]bool{}
+	for _, f := range certificateFiles {
+		desired[f.path] = true
+
+		current, err := afero.ReadFile(r.fs, f.path)
+		if err == nil && string(current) == f.data {
+			continue
+		} else if err != nil && !os.IsNotExist(err) {
+			return microerror.Mask(err)
+		}
+
+		err = afero.WriteFile(r.fs, f.path, []byte(f.data), r.certPermission)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	fileInfos, err := afero.ReadDir(r.fs, r.certDirectory)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	for _, fileInfo := range fileInfos {
+		p := path.Join(r.certDirectory, fileInfo.Name())
+		if desired[p] {
+			continue
+		}
+
+		err = r.fs.RemoveAll(p)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// distributeProjection distributes the given certificate files across the
+// given number of projected Secrets, none of which exceeds the given size,
+// and returns the data of each Secret. The files of a cluster are kept in one
+// Secret, so that Prometheus never sees a certificate next to the key of
+// another. Clusters stay in their current Secret while it has room, so that
+// adding or removing clusters changes as few Secrets as possible, and new
+// clusters go to the first Secret with room. The clusters that do not fit are
+// returned as well.
+func distributeProjection(current []*v1.Secret, certificateFiles []certificateFile, secretCount, maxSize int) ([]map[string][]byte, []string) {
+	// Group the certificate files by cluster. Files not named after a
+	// cluster are a group of their own.
+	groups := map[string]map[string][]byte{}
+	for _, f := range certificateFiles {
+		name := path.Base(f.path)
+
+		group := key.CertClusterID(f.path)
+		if group == "" {
+			group = name
+		}
+
+		if groups[group] == nil {
+			groups[group] = map[string][]byte{}
+		}
+		groups[group][name] = []byte(f.data)
+	}
+
+	var names []string
+	for group := range groups {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+
+	// Find the Secret each group is currently held in.
+	currentIndex := map[string]int{}
+	for i, secret := range current {
+		if secret == nil || i >= secretCount {
+			continue
+		}
+
+		for name := range secret.Data {
+			group := key.CertClusterID(name)
+			if group == "" {
+				group = name
+			}
+
+			if _, ok := currentIndex[group]; !ok {
+				currentIndex[group] = i
+			}
+		}
+	}
+
+	desired := make([]map[string][]byte, secretCount)
+	sizes := make([]int, secretCount)
+	for i := range desired {
+		desired[i] = map[string][]byte{}
+	}
+
+	place := func(group string, i int) bool {
+		size := projectionDataSize(groups[group])
+		if sizes[i]+size > maxSize {
+			return false
+		}
+
+		for name, data := range groups[group] {
+			desired[i][name] = data
+		}
+		sizes[i] += size
+
+		return true
+	}
+
+	placed := map[string]bool{}
+	for _, group := range names {
+		i, ok := currentIndex[group]
+		if ok && place(group, i) {
+			placed[group] = true
+		}
+	}
+
+	var unplaced []string
+	for _, group := range names {
+		if placed[group] {
+			continue
+		}
+
+		for i := range desired {
+			if place(group, i) {
+				placed[group] = true
+				break
+			}
+		}
+
+		if !placed[group] {
+			unplaced = append(unplaced, group)
+		}
+	}
+
+	return desired, unplaced
+}
+
+// projectionDataSize returns the size of the given Secret data.
+func projectionDataSize(data map[string][]byte) int {
+	var size int
+	for name, d := range data {
+		size += len(name) + len(d)
+	}
+
+	return size
+}
+
+// projectionDataEqual returns whether the given Secret data are equal,
+// treating nil and empty data alike.
+func projectionDataEqual(a, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, d := range a {
+		other, ok := b[name]
+		if !ok || string(other) != string(d) {
+			return false
+		}
+	}
+
+	return true
+}
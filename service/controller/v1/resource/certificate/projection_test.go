@@ -0,0 +1,419 @@
+package certificate
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/afero"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/giantswarm/prometheus-config-controller/pkg/label"
+	"github.com/giantswarm/prometheus-config-controller/pkg/project"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/etcd"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/resource/configmap"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/scrapeconfig"
+	"github.com/giantswarm/prometheus-config-controller/service/dryrun"
+)
+
+// fakeClusterSource is a cluster source returning a fixed list of clusters.
+type fakeClusterSource struct {
+	services []v1.Service
+}
+
+func (s *fakeClusterSource) Clusters(ctx context.Context) ([]v1.Service, error) {
+	return s.services, nil
+}
+
+func (s *fakeClusterSource) NewRuntimeObject() runtime.Object {
+	return new(v1.Service)
+}
+
+func (s *fakeClusterSource) Selector() labels.Selector {
+	return labels.Everything()
+}
+
+// projectionFiles returns the certificate files of the given clusters in the
+// certificate directory.
+func projectionFiles(clusterIDs ...string) []certificateFile {
+	var certificateFiles []certificateFile
+	for _, clusterID := range clusterIDs {
+		certificateFiles = append(certificateFiles,
+			certificateFile{path: key.CAPath("/certs", clusterID), data: clusterID + "-ca"},
+			certificateFile{path: key.CrtPath("/certs", clusterID), data: clusterID + "-crt"},
+			certificateFile{path: key.KeyPath("/certs", clusterID), data: clusterID + "-key"},
+		)
+	}
+
+	return certificateFiles
+}
+
+// projectionSecret returns a projected Secret holding the certificates of the
+// given clusters.
+func projectionSecret(name string, clusterIDs ...string) *v1.Secret {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "monitoring",
+		},
+		Data: map[string][]byte{},
+	}
+	for _, f := range projectionFiles(clusterIDs...) {
+		secret.Data[f.path[len("/certs/"):]] = []byte(f.data)
+	}
+
+	return secret
+}
+
+// projectionClusters returns the sorted cluster IDs of the given Secret data.
+func projectionClusters(data map[string][]byte) []string {
+	clusters := map[string]bool{}
+	for name := range data {
+		clusters[key.CertClusterID(name)] = true
+	}
+
+	clusterIDs := []string{}
+	for clusterID := range clusters {
+		clusterIDs = append(clusterIDs, clusterID)
+	}
+	sort.Strings(clusterIDs)
+
+	return clusterIDs
+}
+
+// Test_Resource_Certificate_distributeProjection tests the
+// distributeProjection function. The certificates of a cluster take 64 bytes.
+func Test_Resource_Certificate_distributeProjection(t *testing.T) {
+	tests := []struct {
+		current          []*v1.Secret
+		certificateFiles []certificateFile
+		secretCount      int
+
+		expectedClusters [][]string
+		expectedUnplaced []string
+	}{
+		// Test that clusters fill the first Secrets with room, keeping the
+		// certificates of a cluster together.
+		{
+			current:          []*v1.Secret{nil, nil},
+			certificateFiles: projectionFiles("al9qy", "fah0a", "xa5ly"),
+			secretCount:      2,
+
+			expectedClusters: [][]string{{"al9qy", "fah0a"}, {"xa5ly"}},
+			expectedUnplaced: nil,
+		},
+
+		// Test that clusters stay in their current Secret, and new clusters
+		// go to the first Secret with room.
+		{
+			current: []*v1.Secret{
+				projectionSecret("prometheus-certificates-0", "xa5ly"),
+				projectionSecret("prometheus-certificates-1", "al9qy", "old01"),
+			},
+			certificateFiles: projectionFiles("al9qy", "fah0a", "xa5ly"),
+			secretCount:      2,
+
+			expectedClusters: [][]string{{"fah0a", "xa5ly"}, {"al9qy"}},
+			expectedUnplaced: nil,
+		},
+
+		// Test that clusters not fitting into the Secrets are returned.
+		{
+			current:          []*v1.Secret{nil},
+			certificateFiles: projectionFiles("al9qy", "fah0a", "xa5ly"),
+			secretCount:      1,
+
+			expectedClusters: [][]string{{"al9qy", "fah0a"}},
+			expectedUnplaced: []string{"xa5ly"},
+		},
+	}
+
+	for index, test := range tests {
+		desired, unplaced := distributeProjection(test.current, test.certificateFiles, test.secretCount, 150)
+
+		var clusters [][]string
+		for _, data := range desired {
+			clusters = append(clusters, projectionClusters(data))
+		}
+
+		if !reflect.DeepEqual(test.expectedClusters, clusters) {
+			t.Fatalf("%d: expected clusters %v, got %v", index, test.expectedClusters, clusters)
+		}
+		if !reflect.DeepEqual(test.expectedUnplaced, unplaced) {
+			t.Fatalf("%d: expected unplaced clusters %v, got %v", index, test.expectedUnplaced, unplaced)
+		}
+	}
+}
+
+// Test_Resource_Certificate_Projection tests that certificates written with
+// the projection output are held in the projected Secrets, read back as the
+// current state, and mirrored into the filesystem.
+func Test_Resource_Certificate_Projection(t *testing.T) {
+	k8sClient := fake.NewSimpleClientset()
+	fs := afero.NewMemMapFs()
+
+	r := &Resource{
+		dryRun:    newDryRun(t, false),
+		fs:        fs,
+		k8sClient: k8sClient,
+		logger:    microloggertest.New(),
+
+		certDirectory:       "/certs",
+		certPermission:      0600,
+		output:              OutputProjection,
+		projectionName:      "prometheus-certificates",
+		projectionNamespace: "monitoring",
+		projectionSecrets:   2,
+	}
+
+	// Write a stale file, which the mirror removes.
+	err := afero.WriteFile(fs, "/certs/old01-ca.pem", []byte("old01-ca"), 0600)
+	if err != nil {
+		t.Fatalf("error returned writing stale file: %s\n", err)
+	}
+
+	certificateFiles := projectionFiles("al9qy", "xa5ly")
+
+	err = r.ApplyUpdateChange(context.TODO(), nil, certificateFiles)
+	if err != nil {
+		t.Fatalf("error returned applying update change: %s\n", err)
+	}
+
+	for i, expectedClusters := range [][]string{{"al9qy", "xa5ly"}, {}} {
+		name := r.projectionSecretName(i)
+
+		secret, err := k8sClient.CoreV1().Secrets("monitoring").Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("error returned getting secret %#q: %s\n", name, err)
+		}
+		if clusters := projectionClusters(secret.Data); !reflect.DeepEqual(expectedClusters, clusters) {
+			t.Fatalf("expected secret %#q to hold clusters %v, got %v", name, expectedClusters, clusters)
+		}
+	}
+
+	currentState, err := r.GetCurrentState(context.TODO(), nil)
+	if err != nil {
+		t.Fatalf("error returned getting current state: %s\n", err)
+	}
+	if !reflect.DeepEqual(certificateFiles, currentState) {
+		t.Fatalf("expected current state %#v, got %#v", certificateFiles, currentState)
+	}
+
+	fileInfos, err := afero.ReadDir(fs, "/certs")
+	if err != nil {
+		t.Fatalf("error returned reading certificate directory: %s\n", err)
+	}
+	if len(fileInfos) != len(certificateFiles) {
+		t.Fatalf("expected %d mirrored files, got %d", len(certificateFiles), len(fileInfos))
+	}
+	for _, f := range certificateFiles {
+		data, err := afero.ReadFile(fs, f.path)
+		if err != nil {
+			t.Fatalf("error returned reading mirrored file %#q: %s\n", f.path, err)
+		}
+		if string(data) != f.data {
+			t.Fatalf("expected mirrored file %#q to hold %#q, got %#q", f.path, f.data, data)
+		}
+	}
+}
+
+// Test_Resource_Certificate_Projection_StaleSecrets tests that projected
+// Secrets beyond the configured number of Secrets are deleted, and their
+// clusters moved to the remaining Secrets.
+func Test_Resource_Certificate_Projection_StaleSecrets(t *testing.T) {
+	var objects []runtime.Object
+	for i, clusterID := range []string{"al9qy", "xa5ly", "0ba9v"} {
+		secret := projectionSecret(fmt.Sprintf("prometheus-certificates-%d", i), clusterID)
+		secret.Labels = map[string]string{
+			label.App: project.Name(),
+		}
+		objects = append(objects, secret)
+	}
+	other := projectionSecret("prometheus-certificates-other")
+	other.Labels = map[string]string{
+		label.App: project.Name(),
+	}
+	objects = append(objects, other)
+
+	k8sClient := fake.NewSimpleClientset(objects...)
+
+	r := &Resource{
+		dryRun:    newDryRun(t, false),
+		fs:        afero.NewMemMapFs(),
+		k8sClient: k8sClient,
+		logger:    microloggertest.New(),
+
+		certDirectory:       "/certs",
+		certPermission:      0600,
+		output:              OutputProjection,
+		projectionName:      "prometheus-certificates",
+		projectionNamespace: "monitoring",
+		projectionSecrets:   1,
+	}
+
+	err := r.ApplyUpdateChange(context.TODO(), nil, projectionFiles("0ba9v", "al9qy", "xa5ly"))
+	if err != nil {
+		t.Fatalf("error returned applying update change: %s\n", err)
+	}
+
+	secret, err := k8sClient.CoreV1().Secrets("monitoring").Get(context.TODO(), "prometheus-certificates-0", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error returned getting secret: %s\n", err)
+	}
+	if clusters := projectionClusters(secret.Data); !reflect.DeepEqual([]string{"0ba9v", "al9qy", "xa5ly"}, clusters) {
+		t.Fatalf("expected secret to hold clusters %v, got %v", []string{"0ba9v", "al9qy", "xa5ly"}, clusters)
+	}
+
+	for _, name := range []string{"prometheus-certificates-1", "prometheus-certificates-2"} {
+		_, err := k8sClient.CoreV1().Secrets("monitoring").Get(context.TODO(), name, metav1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			t.Fatalf("expected secret %#q to be deleted, got %v", name, err)
+		}
+	}
+
+	_, err = k8sClient.CoreV1().Secrets("monitoring").Get(context.TODO(), "prometheus-certificates-other", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected secret %#q to be kept, got %s", "prometheus-certificates-other", err)
+	}
+}
+
+// Test_Resource_Certificate_Projection_Overflow tests that clusters whose
+// certificates do not fit into the projected Secrets are not scraped, while
+// the configuration of the other clusters is still written.
+func Test_Resource_Certificate_Projection_Overflow(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	k8sClient := fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "prometheus",
+			Namespace: "monitoring",
+		},
+		Data: map[string]string{
+			"prometheus.yml": "global:\n  scrape_interval: 30s\n",
+		},
+	})
+
+	r := &Resource{
+		dryRun:    newDryRun(t, false),
+		fs:        fs,
+		k8sClient: k8sClient,
+		logger:    microloggertest.New(),
+
+		certDirectory:       "/certs",
+		certPermission:      0600,
+		output:              OutputProjection,
+		projectionName:      "prometheus-certificates",
+		projectionNamespace: "monitoring",
+		projectionSecrets:   1,
+	}
+
+	// The certificates of each cluster take more than half of a Secret, so
+	// only the first cluster fits.
+	var certificateFiles []certificateFile
+	for _, f := range projectionFiles("al9qy", "xa5ly") {
+		if strings.HasSuffix(f.path, "-ca.pem") {
+			f.data = strings.Repeat("c", projectionSecretMaxSize/2+1)
+		}
+		certificateFiles = append(certificateFiles, f)
+	}
+
+	err := r.ApplyUpdateChange(context.TODO(), nil, certificateFiles)
+	if err != nil {
+		t.Fatalf("error returned applying update change: %s\n", err)
+	}
+	if v := testutil.ToFloat64(projectionUnplacedClusters); v != 1 {
+		t.Fatalf("expected %d unplaced clusters, got %v", 1, v)
+	}
+
+	var services []v1.Service
+	for _, clusterID := range []string{"al9qy", "xa5ly"} {
+		services = append(services, v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "master",
+				Namespace: clusterID,
+				Annotations: map[string]string{
+					prometheus.ClusterAnnotation: clusterID,
+				},
+			},
+		})
+	}
+
+	etcdProber, err := etcd.NewProber(etcd.ProberConfig{
+		Fs:     fs,
+		Logger: microloggertest.New(),
+
+		CertDirectory: "/certs",
+		Timeout:       time.Second,
+	})
+	if err != nil {
+		t.Fatalf("error returned creating etcd prober: %s\n", err)
+	}
+
+	builder, err := scrapeconfig.NewBuilder(scrapeconfig.BuilderConfig{
+		ClusterSource: &fakeClusterSource{services: services},
+		EtcdProber:    etcdProber,
+		EventRecorder: record.NewFakeRecorder(10),
+		Fs:            fs,
+		Logger:        microloggertest.New(),
+
+		CertDirectory:   "/certs",
+		EtcdScrapeDelay: 30 * time.Minute,
+		EtcdScrapeMode:  "delay",
+		Provider:        "aws",
+		ShardCount:      1,
+	})
+	if err != nil {
+		t.Fatalf("error returned creating builder: %s\n", err)
+	}
+
+	dryRun, err := dryrun.New(dryrun.Config{})
+	if err != nil {
+		t.Fatalf("error returned creating dry run: %s\n", err)
+	}
+
+	configMapResource, err := configmap.New(configmap.Config{
+		Builder:       builder,
+		DryRun:        dryRun,
+		EventRecorder: record.NewFakeRecorder(10),
+		Fs:            fs,
+		K8sClient:     k8sClient,
+		Logger:        microloggertest.New(),
+
+		ConfigMapKey:       "prometheus.yml",
+		ConfigMapName:      "prometheus",
+		ConfigMapNamespace: "monitoring",
+	})
+	if err != nil {
+		t.Fatalf("error returned creating configmap resource: %s\n", err)
+	}
+
+	err = configMapResource.EnsureCreated(context.TODO(), v1.Service{})
+	if err != nil {
+		t.Fatalf("error returned ensuring configmap: %s\n", err)
+	}
+
+	cm, err := k8sClient.CoreV1().ConfigMaps("monitoring").Get(context.TODO(), "prometheus", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error returned getting configmap: %s\n", err)
+	}
+
+	if !strings.Contains(cm.Data["prometheus.yml"], "workload-cluster-al9qy-apiserver") {
+		t.Fatalf("expected configuration to scrape cluster %#q, got\n%s", "al9qy", cm.Data["prometheus.yml"])
+	}
+	if strings.Contains(cm.Data["prometheus.yml"], "xa5ly") {
+		t.Fatalf("expected configuration not to scrape cluster %#q, got\n%s", "xa5ly", cm.Data["prometheus.yml"])
+	}
+}
@@ -2,72 +2,167 @@ package certificate
 
 import (
 	"os"
+	"sync"
+	"time"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/spf13/afero"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/certificatesource"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/clustersource"
+	"github.com/giantswarm/prometheus-config-controller/service/dryrun"
 )
 
 const (
 	Name = "certificatev1"
 )
 
+const (
+	// OutputDirectory writes certificates into the certificate directory,
+	// shared with Prometheus.
+	OutputDirectory = "directory"
+	// OutputProjection writes certificates into Secrets, which Prometheus
+	// mounts as a projected volume at the certificate directory.
+	OutputProjection = "projection"
+)
+
 type Config struct {
-	Fs        afero.Fs
+	// CertificateSource provides the certificates of clusters.
+	CertificateSource certificatesource.Interface
+	ClusterSource     clustersource.Interface
+	// DryRun holds whether the dry-run mode is enabled, in which certificates
+	// are neither written to the filesystem nor to projected Secrets.
+	DryRun *dryrun.Service
+	// EventRecorder records Events on the objects holding certificates, e.g.
+	// when a certificate expires soon.
+	EventRecorder record.EventRecorder
+	// Fs is the filesystem certificates are written to. When Output is
+	// OutputProjection, it mirrors the projected Secrets instead.
+	Fs afero.Fs
+	// K8sClient manages the projected Secrets when Output is
+	// OutputProjection.
 	K8sClient kubernetes.Interface
 	Logger    micrologger.Logger
 
-	CertComponentName string
-	CertDirectory     string
-	CertNamespace     string
-	CertPermission    os.FileMode
+	CertDirectory  string
+	CertPermission os.FileMode
+	// ExpiryWarningWindow is the time before the expiry of a certificate
+	// from which on a warning is logged and a Warning Event is recorded.
+	ExpiryWarningWindow time.Duration
+	// Output is where certificates are written to, one of OutputDirectory
+	// and OutputProjection.
+	Output string
+	// ProjectionName is the name prefix of the projected Secrets, which are
+	// named <ProjectionName>-<index>.
+	ProjectionName      string
+	ProjectionNamespace string
+	// ProjectionSecrets is the number of projected Secrets certificates are
+	// distributed across. All of them are managed, also when empty, so that
+	// Prometheus can mount them unconditionally.
+	ProjectionSecrets int
+	// ShardCount and ShardIndex select the clusters to write certificates
+	// for when Prometheus is sharded, see prometheus.FilterShardServices.
+	ShardCount int
+	ShardIndex int
 }
 
 type Resource struct {
-	fs        afero.Fs
-	k8sClient kubernetes.Interface
-	logger    micrologger.Logger
-
-	certComponentName string
-	certDirectory     string
-	certNamespace     string
-	certPermission    os.FileMode
+	certificateSource certificatesource.Interface
+	clusterSource     clustersource.Interface
+	dryRun            *dryrun.Service
+	eventRecorder     record.EventRecorder
+	fs                afero.Fs
+	k8sClient         kubernetes.Interface
+	logger            micrologger.Logger
+
+	// exposedLabels holds the label values of the certificate gauges set
+	// during the last successful reconciliation.
+	exposedLabels gaugeLabels
+	mutex         sync.Mutex
+
+	certDirectory       string
+	certPermission      os.FileMode
+	expiryWarningWindow time.Duration
+	output              string
+	projectionName      string
+	projectionNamespace string
+	projectionSecrets   int
+	shardCount          int
+	shardIndex          int
 }
 
 func New(config Config) (*Resource, error) {
+	if config.CertificateSource == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.CertificateSource must not be empty")
+	}
+	if config.ClusterSource == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.ClusterSource must not be empty")
+	}
+	if config.DryRun == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.DryRun must not be empty")
+	}
+	if config.EventRecorder == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.EventRecorder must not be empty")
+	}
 	if config.Fs == nil {
 		return nil, microerror.Maskf(invalidConfigError, "config.Fs must not be empty")
 	}
-	if config.K8sClient == nil {
-		return nil, microerror.Maskf(invalidConfigError, "config.K8sClient must not be empty")
-	}
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
 	}
 
-	if config.CertComponentName == "" {
-		return nil, microerror.Maskf(invalidConfigError, "config.CertComponentName must not be empty")
-	}
 	if config.CertDirectory == "" {
 		return nil, microerror.Maskf(invalidConfigError, "config.CertDirectory must not be empty")
 	}
-	if config.CertNamespace == "" {
-		return nil, microerror.Maskf(invalidConfigError, "config.CertNamespace must not be empty")
-	}
 	if config.CertPermission == 0 {
 		return nil, microerror.Maskf(invalidConfigError, "config.CertPermission must not be zero")
 	}
+	if config.ExpiryWarningWindow < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.ExpiryWarningWindow must not be negative")
+	}
+
+	switch config.Output {
+	case OutputDirectory:
+	case OutputProjection:
+		if config.K8sClient == nil {
+			return nil, microerror.Maskf(invalidConfigError, "config.K8sClient must not be empty")
+		}
+		if config.ProjectionName == "" {
+			return nil, microerror.Maskf(invalidConfigError, "config.ProjectionName must not be empty")
+		}
+		if config.ProjectionNamespace == "" {
+			return nil, microerror.Maskf(invalidConfigError, "config.ProjectionNamespace must not be empty")
+		}
+		if config.ProjectionSecrets < 1 {
+			return nil, microerror.Maskf(invalidConfigError, "config.ProjectionSecrets must be at least 1")
+		}
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.Output must be one of %#q, %#q", OutputDirectory, OutputProjection)
+	}
 
 	r := &Resource{
-		fs:        config.Fs,
-		k8sClient: config.K8sClient,
-		logger:    config.Logger,
-
-		certComponentName: config.CertComponentName,
-		certDirectory:     config.CertDirectory,
-		certNamespace:     config.CertNamespace,
-		certPermission:    config.CertPermission,
+		certificateSource: config.CertificateSource,
+		clusterSource:     config.ClusterSource,
+		dryRun:            config.DryRun,
+		eventRecorder:     config.EventRecorder,
+		fs:                config.Fs,
+		k8sClient:         config.K8sClient,
+		logger:            config.Logger,
+
+		exposedLabels: gaugeLabels{},
+
+		certDirectory:       config.CertDirectory,
+		certPermission:      config.CertPermission,
+		expiryWarningWindow: config.ExpiryWarningWindow,
+		output:              config.Output,
+		projectionName:      config.ProjectionName,
+		projectionNamespace: config.ProjectionNamespace,
+		projectionSecrets:   config.ProjectionSecrets,
+		shardCount:          config.ShardCount,
+		shardIndex:          config.ShardIndex,
 	}
 
 	return r, nil
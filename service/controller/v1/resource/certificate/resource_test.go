@@ -1,13 +1,84 @@
 package certificate
 
 import (
+	"context"
 	"testing"
 
 	"github.com/giantswarm/micrologger/microloggertest"
 	"github.com/spf13/afero"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/certificatesource"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/clustersource"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/inventory"
+	"github.com/giantswarm/prometheus-config-controller/service/dryrun"
 )
 
+// newInventory returns a started inventory of the given clientset, caching
+// the Secrets of the default namespace.
+func newInventory(t *testing.T, k8sClient kubernetes.Interface) *inventory.Inventory {
+	i, err := inventory.New(inventory.Config{
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+
+		CertNamespace: "default",
+	})
+	if err != nil {
+		t.Fatalf("error returned creating inventory: %s\n", err)
+	}
+
+	err = i.Start(context.TODO())
+	if err != nil {
+		t.Fatalf("error returned starting inventory: %s\n", err)
+	}
+
+	return i
+}
+
+// newCertificateSource returns a certificate source looking certificates up
+// as Secrets labelled with the prometheus cluster component, cached by the
+// given inventory.
+func newCertificateSource(t *testing.T, i *inventory.Inventory) certificatesource.Interface {
+	certificateSource, err := certificatesource.NewLegacy(certificatesource.LegacyConfig{
+		Inventory: i,
+
+		ComponentName: "prometheus",
+	})
+	if err != nil {
+		t.Fatalf("error returned creating certificate source: %s\n", err)
+	}
+
+	return certificateSource
+}
+
+// newClusterSource returns a cluster source discovering clusters from the
+// master Services cached by the given inventory.
+func newClusterSource(t *testing.T, i *inventory.Inventory) clustersource.Interface {
+	clusterSource, err := clustersource.NewService(clustersource.ServiceConfig{
+		Inventory: i,
+	})
+	if err != nil {
+		t.Fatalf("error returned creating cluster source: %s\n", err)
+	}
+
+	return clusterSource
+}
+
+// newDryRun returns a dry-run service with the dry-run mode enabled or
+// disabled.
+func newDryRun(t *testing.T, enabled bool) *dryrun.Service {
+	dryRun, err := dryrun.New(dryrun.Config{
+		Enabled: enabled,
+	})
+	if err != nil {
+		t.Fatalf("error returned creating dry-run service: %s\n", err)
+	}
+
+	return dryRun
+}
+
 // Test_Resource_Certificate_New tests the New function.
 func Test_Resource_Certificate_New(t *testing.T) {
 	tests := []struct {
@@ -22,108 +93,140 @@ func Test_Resource_Certificate_New(t *testing.T) {
 			expectedErrorHandler: IsInvalidConfig,
 		},
 
-		// Test that the fs must not be empty.
+		// Test that the certificate source must not be empty.
 		{
 			config: func() Config {
 				return Config{
-					Fs:        nil,
-					K8sClient: fake.NewSimpleClientset(),
-					Logger:    microloggertest.New(),
-
-					CertComponentName: "prometheus",
-					CertDirectory:     "/certs",
-					CertNamespace:     "default",
-					CertPermission:    0600,
+					CertificateSource: nil,
+					ClusterSource:     newClusterSource(t, newInventory(t, fake.NewSimpleClientset())),
+					DryRun:            newDryRun(t, false),
+					EventRecorder:     record.NewFakeRecorder(10),
+					Fs:                afero.NewMemMapFs(),
+					Logger:            microloggertest.New(),
+
+					CertDirectory:  "/certs",
+					CertPermission: 0600,
+					Output:         OutputDirectory,
 				}
 			},
 
 			expectedErrorHandler: IsInvalidConfig,
 		},
 
-		// Test that the kubernetes clientset must not be empty.
+		// Test that the cluster source must not be empty.
 		{
 			config: func() Config {
 				return Config{
-					Fs:        afero.NewMemMapFs(),
-					K8sClient: nil,
-					Logger:    microloggertest.New(),
-
-					CertComponentName: "prometheus",
-					CertDirectory:     "/certs",
-					CertNamespace:     "default",
-					CertPermission:    0600,
+					CertificateSource: newCertificateSource(t, newInventory(t, fake.NewSimpleClientset())),
+					ClusterSource:     nil,
+					DryRun:            newDryRun(t, false),
+					EventRecorder:     record.NewFakeRecorder(10),
+					Fs:                afero.NewMemMapFs(),
+					Logger:            microloggertest.New(),
+
+					CertDirectory:  "/certs",
+					CertPermission: 0600,
+					Output:         OutputDirectory,
 				}
 			},
 
 			expectedErrorHandler: IsInvalidConfig,
 		},
 
-		// Test that the logger must not be empty.
+		// Test that the dry-run service must not be empty.
 		{
 			config: func() Config {
 				return Config{
-					Fs:        afero.NewMemMapFs(),
-					K8sClient: fake.NewSimpleClientset(),
-					Logger:    nil,
-
-					CertComponentName: "prometheus",
-					CertDirectory:     "/certs",
-					CertNamespace:     "default",
-					CertPermission:    0600,
+					CertificateSource: newCertificateSource(t, newInventory(t, fake.NewSimpleClientset())),
+					ClusterSource:     newClusterSource(t, newInventory(t, fake.NewSimpleClientset())),
+					DryRun:            nil,
+					EventRecorder:     record.NewFakeRecorder(10),
+					Fs:                afero.NewMemMapFs(),
+					Logger:            microloggertest.New(),
+
+					CertDirectory:  "/certs",
+					CertPermission: 0600,
+					Output:         OutputDirectory,
 				}
 			},
 
 			expectedErrorHandler: IsInvalidConfig,
 		},
 
-		// Test that the certificate component name must not be empty.
+		// Test that the event recorder must not be empty.
 		{
 			config: func() Config {
 				return Config{
-					Fs:        afero.NewMemMapFs(),
-					K8sClient: fake.NewSimpleClientset(),
-					Logger:    microloggertest.New(),
-
-					CertComponentName: "",
-					CertDirectory:     "/certs",
-					CertNamespace:     "default",
-					CertPermission:    0600,
+					CertificateSource: newCertificateSource(t, newInventory(t, fake.NewSimpleClientset())),
+					ClusterSource:     newClusterSource(t, newInventory(t, fake.NewSimpleClientset())),
+					DryRun:            newDryRun(t, false),
+					EventRecorder:     nil,
+					Fs:                afero.NewMemMapFs(),
+					Logger:            microloggertest.New(),
+
+					CertDirectory:  "/certs",
+					CertPermission: 0600,
+					Output:         OutputDirectory,
 				}
 			},
 
 			expectedErrorHandler: IsInvalidConfig,
 		},
 
-		// Test that the certificate directory must not be empty.
+		// Test that the fs must not be empty.
 		{
 			config: func() Config {
 				return Config{
-					Fs:        afero.NewMemMapFs(),
-					K8sClient: fake.NewSimpleClientset(),
-					Logger:    microloggertest.New(),
-
-					CertComponentName: "prometheus",
-					CertDirectory:     "",
-					CertNamespace:     "default",
-					CertPermission:    0600,
+					CertificateSource: newCertificateSource(t, newInventory(t, fake.NewSimpleClientset())),
+					ClusterSource:     newClusterSource(t, newInventory(t, fake.NewSimpleClientset())),
+					DryRun:            newDryRun(t, false),
+					EventRecorder:     record.NewFakeRecorder(10),
+					Fs:                nil,
+					Logger:            microloggertest.New(),
+
+					CertDirectory:  "/certs",
+					CertPermission: 0600,
+					Output:         OutputDirectory,
 				}
 			},
 
 			expectedErrorHandler: IsInvalidConfig,
 		},
 
-		// Test that the certificate namespace must not be empty.
+		// Test that the logger must not be empty.
 		{
 			config: func() Config {
 				return Config{
-					Fs:        afero.NewMemMapFs(),
-					K8sClient: fake.NewSimpleClientset(),
-					Logger:    microloggertest.New(),
-
-					CertComponentName: "prometheus",
-					CertDirectory:     "/certs",
-					CertNamespace:     "",
-					CertPermission:    0600,
+					CertificateSource: newCertificateSource(t, newInventory(t, fake.NewSimpleClientset())),
+					ClusterSource:     newClusterSource(t, newInventory(t, fake.NewSimpleClientset())),
+					DryRun:            newDryRun(t, false),
+					EventRecorder:     record.NewFakeRecorder(10),
+					Fs:                afero.NewMemMapFs(),
+					Logger:            nil,
+
+					CertDirectory:  "/certs",
+					CertPermission: 0600,
+					Output:         OutputDirectory,
+				}
+			},
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// Test that the certificate directory must not be empty.
+		{
+			config: func() Config {
+				return Config{
+					CertificateSource: newCertificateSource(t, newInventory(t, fake.NewSimpleClientset())),
+					ClusterSource:     newClusterSource(t, newInventory(t, fake.NewSimpleClientset())),
+					DryRun:            newDryRun(t, false),
+					EventRecorder:     record.NewFakeRecorder(10),
+					Fs:                afero.NewMemMapFs(),
+					Logger:            microloggertest.New(),
+
+					CertDirectory:  "",
+					CertPermission: 0600,
+					Output:         OutputDirectory,
 				}
 			},
 
@@ -134,14 +237,16 @@ func Test_Resource_Certificate_New(t *testing.T) {
 		{
 			config: func() Config {
 				return Config{
-					Fs:        afero.NewMemMapFs(),
-					K8sClient: fake.NewSimpleClientset(),
-					Logger:    microloggertest.New(),
-
-					CertComponentName: "prometheus",
-					CertDirectory:     "/certs",
-					CertNamespace:     "default",
-					CertPermission:    0,
+					CertificateSource: newCertificateSource(t, newInventory(t, fake.NewSimpleClientset())),
+					ClusterSource:     newClusterSource(t, newInventory(t, fake.NewSimpleClientset())),
+					DryRun:            newDryRun(t, false),
+					EventRecorder:     record.NewFakeRecorder(10),
+					Fs:                afero.NewMemMapFs(),
+					Logger:            microloggertest.New(),
+
+					CertDirectory:  "/certs",
+					CertPermission: 0,
+					Output:         OutputDirectory,
 				}
 			},
 
@@ -152,14 +257,85 @@ func Test_Resource_Certificate_New(t *testing.T) {
 		{
 			config: func() Config {
 				return Config{
-					Fs:        afero.NewMemMapFs(),
-					K8sClient: fake.NewSimpleClientset(),
-					Logger:    microloggertest.New(),
-
-					CertComponentName: "prometheus",
-					CertDirectory:     "/certs",
-					CertNamespace:     "default",
-					CertPermission:    0600,
+					CertificateSource: newCertificateSource(t, newInventory(t, fake.NewSimpleClientset())),
+					ClusterSource:     newClusterSource(t, newInventory(t, fake.NewSimpleClientset())),
+					DryRun:            newDryRun(t, false),
+					EventRecorder:     record.NewFakeRecorder(10),
+					Fs:                afero.NewMemMapFs(),
+					Logger:            microloggertest.New(),
+
+					CertDirectory:  "/certs",
+					CertPermission: 0600,
+					Output:         OutputDirectory,
+				}
+			},
+
+			expectedErrorHandler: nil,
+		},
+
+		// Test that the output must be known.
+		{
+			config: func() Config {
+				return Config{
+					CertificateSource: newCertificateSource(t, newInventory(t, fake.NewSimpleClientset())),
+					ClusterSource:     newClusterSource(t, newInventory(t, fake.NewSimpleClientset())),
+					DryRun:            newDryRun(t, false),
+					EventRecorder:     record.NewFakeRecorder(10),
+					Fs:                afero.NewMemMapFs(),
+					Logger:            microloggertest.New(),
+
+					CertDirectory:  "/certs",
+					CertPermission: 0600,
+					Output:         "",
+				}
+			},
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// Test that the projection output requires the projected Secrets.
+		{
+			config: func() Config {
+				return Config{
+					CertificateSource: newCertificateSource(t, newInventory(t, fake.NewSimpleClientset())),
+					ClusterSource:     newClusterSource(t, newInventory(t, fake.NewSimpleClientset())),
+					DryRun:            newDryRun(t, false),
+					EventRecorder:     record.NewFakeRecorder(10),
+					Fs:                afero.NewMemMapFs(),
+					K8sClient:         fake.NewSimpleClientset(),
+					Logger:            microloggertest.New(),
+
+					CertDirectory:       "/certs",
+					CertPermission:      0600,
+					Output:              OutputProjection,
+					ProjectionName:      "",
+					ProjectionNamespace: "monitoring",
+					ProjectionSecrets:   2,
+				}
+			},
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// Test that a valid projection config produces a certificate
+		// resource.
+		{
+			config: func() Config {
+				return Config{
+					CertificateSource: newCertificateSource(t, newInventory(t, fake.NewSimpleClientset())),
+					ClusterSource:     newClusterSource(t, newInventory(t, fake.NewSimpleClientset())),
+					DryRun:            newDryRun(t, false),
+					EventRecorder:     record.NewFakeRecorder(10),
+					Fs:                afero.NewMemMapFs(),
+					K8sClient:         fake.NewSimpleClientset(),
+					Logger:            microloggertest.New(),
+
+					CertDirectory:       "/certs",
+					CertPermission:      0600,
+					Output:              OutputProjection,
+					ProjectionName:      "prometheus-certificates",
+					ProjectionNamespace: "monitoring",
+					ProjectionSecrets:   2,
 				}
 			},
 
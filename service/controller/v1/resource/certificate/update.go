@@ -59,8 +59,39 @@ func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange inte
 		return nil
 	}
 
-	// Write the update state certificate files.
-	for _, fileToWrite := range updateCertificateFiles {
+	if r.dryRun.Enabled() {
+		for _, f := range updateCertificateFiles {
+			r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("dry-run mode is enabled, not writing certificate %#q", f.path))
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "canceling resource")
+		return nil
+	}
+
+	// Projected certificates are written into Secrets. Filesystems supporting
+	// symbolic links get the certificates swapped atomically, others get them
+	// written in place.
+	if r.output == OutputProjection {
+		err = r.writeProjection(ctx, updateCertificateFiles)
+	} else if linker, ok := r.fs.(afero.Symlinker); ok {
+		err = r.writeAtomic(ctx, linker, updateCertificateFiles)
+	} else {
+		err = r.writeInPlace(ctx, updateCertificateFiles)
+	}
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.LogCtx(ctx, "debug", "certificates have been updated")
+
+	return nil
+}
+
+// writeInPlace writes the given certificate files one by one, and removes any
+// other file of the certificate directory.
+func (r *Resource) writeInPlace(ctx context.Context, certificateFiles []certificateFile) error {
+	// Write the certificate files.
+	for _, fileToWrite := range certificateFiles {
 		r.logger.LogCtx(ctx, "debug", fmt.Sprintf("writing certificate: %s", fileToWrite.path))
 		if err := afero.WriteFile(r.fs, fileToWrite.path, []byte(fileToWrite.data), r.certPermission); err != nil {
 			return microerror.Mask(err)
@@ -77,7 +108,7 @@ func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange inte
 		fileDesired := false
 		filePath := path.Join(r.certDirectory, fileInfo.Name())
 
-		for _, updateCertificateFile := range updateCertificateFiles {
+		for _, updateCertificateFile := range certificateFiles {
 			if filePath == updateCertificateFile.path {
 				fileDesired = true
 			}
@@ -91,8 +122,6 @@ func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange inte
 		}
 	}
 
-	r.logger.LogCtx(ctx, "debug", "certificates have been updated")
-
 	return nil
 }
 
@@ -10,7 +10,9 @@ import (
 	"github.com/giantswarm/micrologger/microloggertest"
 	"github.com/spf13/afero"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 )
 
 // Test_Resource_Certificate_newUpdateChange tests the newUpdateChange method.
@@ -149,18 +151,20 @@ func Test_Resource_Certificate_newUpdateChange(t *testing.T) {
 
 	for index, test := range tests {
 		fs := afero.NewMemMapFs()
-		fakeK8sClient := fake.NewSimpleClientset()
+		fakeInventory := newInventory(t, fake.NewSimpleClientset())
 
 		resourceConfig := Config{}
 
+		resourceConfig.CertificateSource = newCertificateSource(t, fakeInventory)
+		resourceConfig.ClusterSource = newClusterSource(t, fakeInventory)
+		resourceConfig.DryRun = newDryRun(t, false)
+		resourceConfig.EventRecorder = record.NewFakeRecorder(100)
 		resourceConfig.Fs = fs
-		resourceConfig.K8sClient = fakeK8sClient
 		resourceConfig.Logger = microloggertest.New()
 
-		resourceConfig.CertComponentName = "prometheus"
 		resourceConfig.CertDirectory = "/certs"
-		resourceConfig.CertNamespace = "default"
 		resourceConfig.CertPermission = 0644
+		resourceConfig.Output = OutputDirectory
 
 		resource, err := New(resourceConfig)
 		if err != nil {
@@ -407,18 +411,20 @@ func Test_Resource_Certificate_ApplyUpdateChange(t *testing.T) {
 
 	for index, test := range tests {
 		fs := afero.NewMemMapFs()
-		fakeK8sClient := fake.NewSimpleClientset()
+		fakeInventory := newInventory(t, fake.NewSimpleClientset())
 
 		resourceConfig := Config{}
 
+		resourceConfig.CertificateSource = newCertificateSource(t, fakeInventory)
+		resourceConfig.ClusterSource = newClusterSource(t, fakeInventory)
+		resourceConfig.DryRun = newDryRun(t, false)
+		resourceConfig.EventRecorder = record.NewFakeRecorder(100)
 		resourceConfig.Fs = fs
-		resourceConfig.K8sClient = fakeK8sClient
 		resourceConfig.Logger = microloggertest.New()
 
-		resourceConfig.CertComponentName = "prometheus"
 		resourceConfig.CertDirectory = "/certs"
-		resourceConfig.CertNamespace = "default"
 		resourceConfig.CertPermission = 0644
+		resourceConfig.Output = OutputDirectory
 
 		resource, err := New(resourceConfig)
 		if err != nil {
@@ -536,3 +542,48 @@ func Test_Resource_Certificate_toCertificateFiles(t *testing.T) {
 		}
 	}
 }
+
+// Test_Resource_Certificate_ApplyUpdateChange_DryRun tests that certificates
+// are neither written to the filesystem nor to projected Secrets in dry-run
+// mode.
+func Test_Resource_Certificate_ApplyUpdateChange_DryRun(t *testing.T) {
+	for index, output := range []string{OutputDirectory, OutputProjection} {
+		fs := afero.NewMemMapFs()
+		k8sClient := fake.NewSimpleClientset()
+
+		r := &Resource{
+			dryRun:    newDryRun(t, true),
+			fs:        fs,
+			k8sClient: k8sClient,
+			logger:    microloggertest.New(),
+
+			certDirectory:       "/certs",
+			certPermission:      0600,
+			output:              output,
+			projectionName:      "prometheus-certificates",
+			projectionNamespace: "monitoring",
+			projectionSecrets:   2,
+		}
+
+		err := r.ApplyUpdateChange(context.TODO(), v1.Service{}, projectionFiles("xa5ly"))
+		if err != nil {
+			t.Fatalf("%d: error returned applying update change: %s\n", index, err)
+		}
+
+		exists, err := afero.DirExists(fs, "/certs")
+		if err != nil {
+			t.Fatalf("%d: error returned checking certificate directory: %s\n", index, err)
+		}
+		if exists {
+			t.Fatalf("%d: expected no certificates to be written in dry-run mode", index)
+		}
+
+		secrets, err := k8sClient.CoreV1().Secrets("monitoring").List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("%d: error returned listing secrets: %s\n", index, err)
+		}
+		if len(secrets.Items) != 0 {
+			t.Fatalf("%d: expected no projected secrets in dry-run mode, got %d", index, len(secrets.Items))
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package certificate
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/afero"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/certificatesource"
+)
+
+const (
+	// certificateRejectedEventReason is the reason of the Event recorded
+	// when a certificate is rejected by validation.
+	certificateRejectedEventReason = "CertificateRejected"
+)
+
+const (
+	// rejectionReasonExpired rejects bundles whose client certificate or
+	// verified chain expired.
+	rejectionReasonExpired = "expired"
+	// rejectionReasonInvalidCA rejects bundles whose CA does not parse.
+	rejectionReasonInvalidCA = "invalid_ca"
+	// rejectionReasonInvalidCrt rejects bundles whose client certificate does
+	// not parse.
+	rejectionReasonInvalidCrt = "invalid_crt"
+	// rejectionReasonInvalidKey rejects bundles whose key does not parse.
+	rejectionReasonInvalidKey = "invalid_key"
+	// rejectionReasonKeyMismatch rejects bundles whose key does not match the
+	// client certificate.
+	rejectionReasonKeyMismatch = "key_mismatch"
+	// rejectionReasonNotYetValid rejects bundles holding a certificate which
+	// is not valid yet.
+	rejectionReasonNotYetValid = "not_yet_valid"
+	// rejectionReasonUntrustedChain rejects bundles whose client certificate
+	// does not chain to the CA.
+	rejectionReasonUntrustedChain = "untrusted_chain"
+)
+
+// parseCertificates returns all certificates of the given PEM data.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, microerror.Maskf(invalidCertificateError, err.Error())
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	if len(certificates) == 0 {
+		return nil, microerror.Maskf(invalidCertificateError, "no PEM encoded certificate found")
+	}
+
+	return certificates, nil
+}
+
+// parsePrivateKey checks that the given PEM data holds a PKCS #1, PKCS #8 or
+// EC private key.
+func parsePrivateKey(data []byte) error {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return microerror.Maskf(invalidCertificateError, "no PEM encoded private key found")
+		}
+		if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			continue
+		}
+
+		if _, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+			return nil
+		}
+		if _, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+			return nil
+		}
+		if _, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+			return nil
+		}
+
+		return microerror.Maskf(invalidCertificateError, "failed to parse private key of type %#q", block.Type)
+	}
+}
+
+// validateCertificate checks the given certificate bundle. All certificates
+// must parse, the client certificate must be valid at the given time, match
+// the key and chain to the CA. Only the CAs of the verified chain must be
+// valid, so that a CA bundle may still hold a rotated CA which expired. It
+// returns the rejection reason along with an invalidCertificateError for
+// invalid bundles.
+func validateCertificate(c certificatesource.Certificate, now time.Time) (string, error) {
+	var err error
+
+	parts := certificateData(c)
+
+	var cas []*x509.Certificate
+	if ca, ok := parts[caKey]; ok {
+		cas, err = parseCertificates(ca)
+		if err != nil {
+			return rejectionReasonInvalidCA, microerror.Mask(err)
+		}
+	}
+
+	var crts []*x509.Certificate
+	if crt, ok := parts[crtKey]; ok {
+		crts, err = parseCertificates(crt)
+		if err != nil {
+			return rejectionReasonInvalidCrt, microerror.Mask(err)
+		}
+	}
+
+	// The validity of the chain is checked when verifying it below.
+	if len(crts) > 0 {
+		c := crts[0]
+		if now.Before(c.NotBefore) {
+			return rejectionReasonNotYetValid, microerror.Maskf(invalidCertificateError, "certificate %#q is not valid before %s", c.Subject.CommonName, c.NotBefore.UTC().Format(time.RFC3339))
+		}
+		if now.After(c.NotAfter) {
+			return rejectionReasonExpired, microerror.Maskf(invalidCertificateError, "certificate %#q expired at %s", c.Subject.CommonName, c.NotAfter.UTC().Format(time.RFC3339))
+		}
+	}
+
+	if key, ok := parts[keyKey]; ok {
+		err = parsePrivateKey(key)
+		if err != nil {
+			return rejectionReasonInvalidKey, microerror.Mask(err)
+		}
+
+		if len(crts) > 0 {
+			_, err = tls.X509KeyPair(parts[crtKey], key)
+			if err != nil {
+				return rejectionReasonKeyMismatch, microerror.Maskf(invalidCertificateError, err.Error())
+			}
+		}
+	}
+
+	if len(cas) > 0 && len(crts) > 0 {
+		_, err = verifyChain(cas, crts, now)
+		var invalidErr x509.CertificateInvalidError
+		if errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired {
+			return rejectionReasonExpired, microerror.Maskf(invalidCertificateError, err.Error())
+		} else if err != nil {
+			return rejectionReasonUntrustedChain, microerror.Maskf(invalidCertificateError, err.Error())
+		}
+	}
+
+	return "", nil
+}
+
+// verifyChain verifies that the first of the given client certificates chains
+// to one of the given CAs at the given time, using the other client
+// certificates as intermediates. It returns the verified chain, from the
+// client certificate to the CA.
+func verifyChain(cas []*x509.Certificate, crts []*x509.Certificate, now time.Time) ([]*x509.Certificate, error) {
+	roots := x509.NewCertPool()
+	for _, c := range cas {
+		roots.AddCert(c)
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range crts[1:] {
+		intermediates.AddCert(c)
+	}
+
+	chains, err := crts[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return chains[0], nil
+}
+
+// rejectCertificate exposes the rejection of the given certificate, and
+// returns the certificate files of the cluster currently on
+// disk, so that the last good files are kept.
+func (r *Resource) rejectCertificate(ctx context.Context, clusterID string, c certificatesource.Certificate, reason string, err error, labels gaugeLabels) ([]certificateFile, error) {
+	message := fmt.Sprintf("certificate %s of cluster %s rejected: %s", c.Name, clusterID, err.Error())
+
+	r.logger.LogCtx(ctx, "level", "warning", "message", message, "reason", reason)
+	if c.Object != nil {
+		r.eventRecorder.Event(c.Object, v1.EventTypeWarning, certificateRejectedEventReason, message)
+	}
+
+	labels.set(certificateRejected, 1, clusterID, reason)
+	certificateRejectionCount.WithLabelValues(reason).Inc()
+
+	var certificateFiles []certificateFile
+
+	for _, certificateKey := range []string{caKey, crtKey, keyKey} {
+		p := r.certificatePath(certificateKey, clusterID)
+
+		data, err := afero.ReadFile(r.fs, p)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		certificateFiles = append(certificateFiles, certificateFile{
+			path: p,
+			data: string(data),
+		})
+	}
+
+	if len(certificateFiles) > 0 {
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("keeping last good certificate files of cluster %#q", clusterID))
+	}
+
+	return certificateFiles, nil
+}
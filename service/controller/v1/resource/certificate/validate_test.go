@@ -0,0 +1,118 @@
+package certificate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/certificatesource"
+)
+
+// Test_Resource_Certificate_validateCertificate tests the validateCertificate
+// function.
+func Test_Resource_Certificate_validateCertificate(t *testing.T) {
+	now := time.Now()
+	pki := newTestPKI(t, now.Add(-time.Hour), now.Add(24*time.Hour))
+	otherPKI := newTestPKI(t, now.Add(-time.Hour), now.Add(24*time.Hour))
+	expiredPKI := newTestPKI(t, now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+	futurePKI := newTestPKI(t, now.Add(24*time.Hour), now.Add(48*time.Hour))
+	expiredCAPKI := newTestPKIWithCAValidity(t, now.Add(-48*time.Hour), now.Add(-24*time.Hour), now.Add(-time.Hour), now.Add(24*time.Hour))
+
+	tests := []struct {
+		certificate certificatesource.Certificate
+
+		expectedReason string
+	}{
+		// Test that a complete and valid bundle is accepted.
+		{
+			certificate: certificatesource.Certificate{CA: []byte(pki.ca), Crt: []byte(pki.crt), Key: []byte(pki.key)},
+
+			expectedReason: "",
+		},
+
+		// Test that a bundle holding only a valid CA is accepted.
+		{
+			certificate: certificatesource.Certificate{CA: []byte(pki.ca)},
+
+			expectedReason: "",
+		},
+
+		// Test that a CA which does not parse is rejected.
+		{
+			certificate: certificatesource.Certificate{CA: []byte("foo"), Crt: []byte(pki.crt), Key: []byte(pki.key)},
+
+			expectedReason: rejectionReasonInvalidCA,
+		},
+
+		// Test that a client certificate which does not parse is rejected.
+		{
+			certificate: certificatesource.Certificate{CA: []byte(pki.ca), Crt: []byte("bar"), Key: []byte(pki.key)},
+
+			expectedReason: rejectionReasonInvalidCrt,
+		},
+
+		// Test that a truncated key is rejected.
+		{
+			certificate: certificatesource.Certificate{CA: []byte(pki.ca), Crt: []byte(pki.crt), Key: []byte(pki.key[:len(pki.key)/2])},
+
+			expectedReason: rejectionReasonInvalidKey,
+		},
+
+		// Test that a key not matching the client certificate is rejected.
+		{
+			certificate: certificatesource.Certificate{CA: []byte(pki.ca), Crt: []byte(pki.crt), Key: []byte(otherPKI.key)},
+
+			expectedReason: rejectionReasonKeyMismatch,
+		},
+
+		// Test that a client certificate signed by another CA is rejected.
+		{
+			certificate: certificatesource.Certificate{CA: []byte(pki.ca), Crt: []byte(otherPKI.crt), Key: []byte(otherPKI.key)},
+
+			expectedReason: rejectionReasonUntrustedChain,
+		},
+
+		// Test that an expired bundle is rejected.
+		{
+			certificate: certificatesource.Certificate{CA: []byte(expiredPKI.ca), Crt: []byte(expiredPKI.crt), Key: []byte(expiredPKI.key)},
+
+			expectedReason: rejectionReasonExpired,
+		},
+
+		// Test that a bundle which is not valid yet is rejected.
+		{
+			certificate: certificatesource.Certificate{CA: []byte(futurePKI.ca), Crt: []byte(futurePKI.crt), Key: []byte(futurePKI.key)},
+
+			expectedReason: rejectionReasonNotYetValid,
+		},
+
+		// Test that a CA bundle holding an expired CA next to the CA the
+		// client certificate chains to is accepted.
+		{
+			certificate: certificatesource.Certificate{CA: []byte(expiredPKI.ca + pki.ca), Crt: []byte(pki.crt), Key: []byte(pki.key)},
+
+			expectedReason: "",
+		},
+
+		// Test that a valid client certificate chaining to an expired CA is
+		// rejected.
+		{
+			certificate: certificatesource.Certificate{CA: []byte(expiredCAPKI.ca), Crt: []byte(expiredCAPKI.crt), Key: []byte(expiredCAPKI.key)},
+
+			expectedReason: rejectionReasonExpired,
+		},
+	}
+
+	for index, test := range tests {
+		reason, err := validateCertificate(test.certificate, now)
+
+		if test.expectedReason == "" && err != nil {
+			t.Fatalf("%d: unexpected error returned validating certificate: %s\n", index, err)
+		}
+		if test.expectedReason != "" && !IsInvalidCertificate(err) {
+			t.Fatalf("%d: expected invalid certificate error, got %#v\n", index, err)
+		}
+		if reason != test.expectedReason {
+			t.Fatalf("%d: expected reason %#q, got %#q", index, test.expectedReason, reason)
+		}
+	}
+}
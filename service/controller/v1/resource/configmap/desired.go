@@ -3,16 +3,12 @@ package configmap
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/giantswarm/microerror"
-	"github.com/prometheus/prometheus/config"
-	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
 	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
 )
 
@@ -35,55 +31,22 @@ func (r *Resource) getDesiredState(ctx context.Context) (*corev1.ConfigMap, erro
 		return nil, microerror.Maskf(configMapKeyNotFoundError, "%s/%s - %s", r.configMapNamespace, r.configMapName, r.configMapKey)
 	}
 
-	prometheusConfig, err := config.Load(configMapData)
+	prometheusConfig, err := prometheus.LoadConfig(configMapData)
 	if err != nil {
 		return nil, microerror.Maskf(invalidConfigMapError, err.Error())
 	}
 
-	r.logger.LogCtx(ctx, "debug", fmt.Sprintf("fetching all services"))
-
-	services, err := r.k8sClient.CoreV1().Services("").List(ctx, metav1.ListOptions{
-		LabelSelector: key.LabelSelectorService().String(),
-	})
-
+	services, config, err := r.builder.Build(ctx, prometheusConfig)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 
-	config := prometheus.Config{
-		CertDirectory: r.certDirectory,
-		Provider:      r.provider,
-	}
-
 	r.logger.LogCtx(ctx, "debug", fmt.Sprintf("computing desired state of configmap"))
-	scrapeConfigs, err := prometheus.GetScrapeConfigs(services.Items, config)
-	if err != nil {
-		return nil, microerror.Mask(err)
-	}
-
-	newPrometheusConfig, err := prometheus.UpdateConfig(*prometheusConfig, scrapeConfigs)
+	newConfigMapData, err := prometheus.RenderConfig(configMapData, services, config)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 
-	// Prometheus YAML marshalling obscures remote write passwords for security,
-	// so we store the Cortex password, and write it back after marshalling.
-	var remoteWritePassword string
-	if len(newPrometheusConfig.RemoteWriteConfigs) == 1 {
-		remoteWritePassword = string(newPrometheusConfig.RemoteWriteConfigs[0].HTTPClientConfig.BasicAuth.Password)
-	}
-
-	newConfigMapData, err := yaml.Marshal(newPrometheusConfig)
-	if err != nil {
-		return nil, microerror.Mask(err)
-	}
-
-	if remoteWritePassword != "" && strings.Count(string(newConfigMapData), "password: <secret>") == 1 {
-		newConfigMapData = []byte(
-			strings.Replace(string(newConfigMapData), "password: <secret>", fmt.Sprintf("password: %s", remoteWritePassword), 1),
-		)
-	}
-
 	configMap.Data[r.configMapKey] = string(newConfigMapData)
 
 	return configMap, nil
@@ -0,0 +1,25 @@
+package configmap
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	prometheusNamespace = "prometheus_config_controller"
+	prometheusSubsystem = "configmap_resource"
+)
+
+var (
+	invalidConfig = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "invalid_config",
+			Help:      "Whether the generated Prometheus configuration is invalid and not written to the ConfigMap.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(invalidConfig)
+}
@@ -6,40 +6,75 @@ import (
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+	"github.com/spf13/afero"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/scrapeconfig"
+	"github.com/giantswarm/prometheus-config-controller/service/dryrun"
 )
 
 const (
 	Name = "configmapv1"
+
+	// invalidConfigEventReason is the reason of the Event recorded when the
+	// generated configuration is invalid.
+	invalidConfigEventReason = "InvalidConfig"
 )
 
 type Config struct {
+	// Builder gathers the clusters and the configuration their scrape
+	// configs are generated with.
+	Builder *scrapeconfig.Builder
+	// DryRun holds whether the dry-run mode is enabled, in which the
+	// ConfigMap is not updated, but the diff to the desired state is logged
+	// and stored.
+	DryRun *dryrun.Service
+	// EventRecorder records Events on the ConfigMap, e.g. when the generated
+	// configuration is invalid.
+	EventRecorder record.EventRecorder
+	// Fs is the file system the certificate files referenced by the
+	// generated configuration are validated against.
+	Fs        afero.Fs
 	K8sClient kubernetes.Interface
 	Logger    micrologger.Logger
 
-	CertDirectory string
 	// ConfigMapKey is the key in the configmap under which the prometheus configuration is held.
 	ConfigMapKey       string
 	ConfigMapName      string
 	ConfigMapNamespace string
-
-	Provider string
 }
 
 type Resource struct {
-	k8sClient kubernetes.Interface
-	logger    micrologger.Logger
+	builder       *scrapeconfig.Builder
+	dryRun        *dryrun.Service
+	eventRecorder record.EventRecorder
+	fs            afero.Fs
+	k8sClient     kubernetes.Interface
+	logger        micrologger.Logger
 
-	certDirectory      string
 	configMapKey       string
 	configMapName      string
 	configMapNamespace string
-	provider           string
 }
 
 func New(config Config) (*Resource, error) {
+	if config.Builder == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Builder must not be empty")
+	}
+	if config.DryRun == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.DryRun must not be empty")
+	}
+	if config.EventRecorder == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.EventRecorder must not be empty")
+	}
+	if config.Fs == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Fs must not be empty")
+	}
 	if config.K8sClient == nil {
 		return nil, microerror.Maskf(invalidConfigError, "config.K8sClient must not be empty")
 	}
@@ -47,9 +82,6 @@ func New(config Config) (*Resource, error) {
 		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
 	}
 
-	if config.CertDirectory == "" {
-		return nil, microerror.Maskf(invalidConfigError, "config.CertDirectory must not be empty")
-	}
 	if config.ConfigMapKey == "" {
 		return nil, microerror.Maskf(invalidConfigError, "config.ConfigMapKey must not be empty")
 	}
@@ -59,20 +91,18 @@ func New(config Config) (*Resource, error) {
 	if config.ConfigMapNamespace == "" {
 		return nil, microerror.Maskf(invalidConfigError, "config.ConfigMapNamespace must not be empty")
 	}
-	if config.Provider == "" {
-		return nil, microerror.Maskf(invalidConfigError, "config.Provider must not be empty")
-	}
 
 	r := &Resource{
-		k8sClient: config.K8sClient,
-		logger:    config.Logger,
+		builder:       config.Builder,
+		dryRun:        config.DryRun,
+		eventRecorder: config.EventRecorder,
+		fs:            config.Fs,
+		k8sClient:     config.K8sClient,
+		logger:        config.Logger,
 
-		certDirectory:      config.CertDirectory,
 		configMapKey:       config.ConfigMapKey,
 		configMapName:      config.ConfigMapName,
 		configMapNamespace: config.ConfigMapNamespace,
-
-		provider: config.Provider,
 	}
 
 	return r, nil
@@ -93,6 +123,42 @@ func (r *Resource) ensure(ctx context.Context, obj interface{}) error {
 		return microerror.Mask(err)
 	}
 
+	{
+		r.logger.LogCtx(ctx, "level", "debug", "message", "validating desired Prometheus configuration")
+
+		err = prometheus.ValidateConfig(r.fs, desiredCM.Data[r.configMapKey])
+		if prometheus.IsInvalidPrometheusConfig(err) {
+			invalidConfig.Set(1)
+			r.eventRecorder.Eventf(desiredCM, corev1.EventTypeWarning, invalidConfigEventReason, "Generated Prometheus configuration is invalid and not written: %s", err.Error())
+
+			return microerror.Mask(err)
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+		invalidConfig.Set(0)
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "validated desired Prometheus configuration")
+	}
+
+	if r.dryRun.Enabled() {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "computing diff of ConfigMap")
+
+		diff, err := prometheus.DiffConfig(currentCM.Data[r.configMapKey], desiredCM.Data[r.configMapKey])
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r.dryRun.SetDiff(diff)
+
+		if diff == "" {
+			r.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("dry-run mode is enabled, ConfigMap %#q in namespace %#q is up to date", currentCM.GetName(), currentCM.GetNamespace()))
+		} else {
+			r.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("dry-run mode is enabled, not updating ConfigMap %#q in namespace %#q", currentCM.GetName(), currentCM.GetNamespace()), "diff", diff)
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "cancelling resource")
+		return nil
+	}
+
 	cm := newConfigMapToUpdate(currentCM, desiredCM)
 
 	{
@@ -106,15 +172,20 @@ func (r *Resource) ensure(ctx context.Context, obj interface{}) error {
 			return nil
 		}
 
-		r.k8sClient.CoreV1().ConfigMaps(cm.GetNamespace()).Update(ctx, cm, metav1.UpdateOptions{})
+		_, err = r.k8sClient.CoreV1().ConfigMaps(cm.GetNamespace()).Update(ctx, cm, metav1.UpdateOptions{})
 		if apierrors.IsNotFound(err) {
-			r.k8sClient.CoreV1().ConfigMaps(cm.GetNamespace()).Create(ctx, cm, metav1.CreateOptions{})
+			// The ConfigMap was deleted since it was read. It is recreated
+			// from the desired state, which must not carry the identity of
+			// the deleted object.
+			cm.ResourceVersion = ""
+			cm.UID = ""
+
+			_, err = r.k8sClient.CoreV1().ConfigMaps(cm.GetNamespace()).Create(ctx, cm, metav1.CreateOptions{})
 			if err != nil {
 				return microerror.Mask(err)
 			}
 		} else if err != nil {
 			return microerror.Mask(err)
-
 		}
 
 		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("updated ConfigMap %#q in namespace %#q", currentCM.GetName(), currentCM.GetNamespace()))
@@ -0,0 +1,145 @@
+package reload
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	prometheusNamespace = "prometheus_config_controller"
+	prometheusSubsystem = "reload_resource"
+
+	reloaderSubsystem = "prometheus_reloader"
+)
+
+const (
+	// failureReasonCanceled is the failure reason of reloads whose
+	// verification was canceled, e.g. when the controller stops.
+	failureReasonCanceled = "canceled"
+	// failureReasonConfigNotInSync is the failure reason of reloads after
+	// which Prometheus did not load the configuration held in the ConfigMap.
+	failureReasonConfigNotInSync = "config_not_in_sync"
+	// failureReasonFetchConfig is the failure reason of reloads whose
+	// verification failed fetching the configuration loaded by Prometheus.
+	failureReasonFetchConfig = "fetch_config"
+	// failureReasonMissingConfig is the failure reason of reloads which
+	// were not attempted, because the ConfigMap does not hold the
+	// configuration.
+	failureReasonMissingConfig = "missing_config"
+	// failureReasonParseConfig is the failure reason of reloads whose
+	// verification failed parsing the configuration loaded by Prometheus.
+	failureReasonParseConfig = "parse_config"
+	// failureReasonRequest is the failure reason of reload requests which
+	// did not get a response.
+	failureReasonRequest = "request"
+)
+
+var (
+	configInSync = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "config_in_sync",
+			Help:      "Whether the configuration loaded by a Prometheus instance matches the configuration held in the ConfigMap.",
+		},
+		[]string{"target"},
+	)
+
+	configConvergeDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "config_converge_duration_seconds",
+			Help:      "Time it took Prometheus to load the configuration held in the ConfigMap after the first reload.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		},
+	)
+
+	configurationReloadCheckCount = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: reloaderSubsystem,
+			Name:      "configuration_reload_check_count",
+			Help:      "Count of the times we have checked if a reload of the prometheus configuration is necessary.",
+		},
+	)
+
+	configurationReloadIgnoredCount = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: reloaderSubsystem,
+			Name:      "configuration_reload_ignored_count",
+			Help:      "Count of the times we have ignored a reload request due to rate limiting.",
+		},
+	)
+
+	configurationReloadRequiredCount = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: reloaderSubsystem,
+			Name:      "configuration_reload_required_count",
+			Help:      "Count of the times we need to reload the prometheus configuration.",
+		},
+	)
+
+	configurationReloadCount = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: reloaderSubsystem,
+			Name:      "configuration_reload_count",
+			Help:      "Count of the times we have reloaded the prometheus configuration.",
+		},
+	)
+
+	configurationReloadDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: reloaderSubsystem,
+			Name:      "configuration_reload_duration_seconds",
+			Help:      "Duration of requests reloading the prometheus configuration.",
+			Buckets:   prometheus.DefBuckets,
+		},
+	)
+
+	configurationReloadFailureCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: reloaderSubsystem,
+			Name:      "configuration_reload_failure_count",
+			Help:      "Count of the times reloading the prometheus configuration failed, by HTTP status code or error class.",
+		},
+		[]string{"reason"},
+	)
+
+	configurationLastSuccessfulReload = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: reloaderSubsystem,
+			Name:      "configuration_last_successful_reload_timestamp_seconds",
+			Help:      "Timestamp of the last successful reload of the prometheus configuration.",
+		},
+		[]string{"target"},
+	)
+
+	configurationReloadedInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: reloaderSubsystem,
+			Name:      "configuration_reloaded_info",
+			Help:      "ConfigMap resource version last reloaded successfully, always 1.",
+		},
+		[]string{"target", "resource_version"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(configInSync)
+	prometheus.MustRegister(configConvergeDuration)
+	prometheus.MustRegister(configurationReloadCheckCount)
+	prometheus.MustRegister(configurationReloadIgnoredCount)
+	prometheus.MustRegister(configurationReloadRequiredCount)
+	prometheus.MustRegister(configurationReloadCount)
+	prometheus.MustRegister(configurationReloadDuration)
+	prometheus.MustRegister(configurationReloadFailureCount)
+	prometheus.MustRegister(configurationLastSuccessfulReload)
+	prometheus.MustRegister(configurationReloadedInfo)
+}
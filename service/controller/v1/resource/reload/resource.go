@@ -3,7 +3,8 @@ package reload
 import (
 	"context"
 	"fmt"
-	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/giantswarm/microerror"
@@ -12,39 +13,84 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 
-	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+	"github.com/giantswarm/prometheus-config-controller/service/dryrun"
 )
 
 const (
 	Name = "reloadv1"
 
+	// maxConcurrentReloads is the maximum number of Prometheus instances
+	// reloaded concurrently.
+	maxConcurrentReloads = 10
 	// minReloadInterval is the minimum time that has to pass between
 	// Prometheus reload calls unless ConfigMap resource version changes.
 	minReloadInterval = 2 * time.Minute
 )
 
 type Config struct {
+	// DryRun holds whether the dry-run mode is enabled, in which Prometheus
+	// is not reloaded.
+	DryRun    *dryrun.Service
 	K8sClient kubernetes.Interface
 	Logger    micrologger.Logger
 
+	// ConfigMapKey is the key in the ConfigMap under which the Prometheus
+	// configuration is held. It is compared to the configuration loaded by
+	// Prometheus to verify reloads.
+	ConfigMapKey       string
 	ConfigMapName      string
 	ConfigMapNamespace string
 	PrometheusAddress  string
+
+	// Mode selects the Prometheus instances to reload, one of ModeAddress,
+	// ModeSelector and ModeService.
+	Mode string
+	// Namespace is the namespace of the Pods or Service to reload in
+	// ModeSelector and ModeService.
+	Namespace string
+	// Port is the port Prometheus listens on in the Pods reloaded in
+	// ModeSelector and ModeService.
+	Port int
+	// Selector is the label selector of the Pods to reload in ModeSelector.
+	Selector string
+	// Service is the name of the Service whose Endpoints are reloaded in
+	// ModeService.
+	Service string
+	// VerifyInterval is the time waited before reloading again when the
+	// configuration loaded by Prometheus does not match the ConfigMap yet.
+	VerifyInterval time.Duration
+	// VerifyTimeout is the time after which reloading is given up when the
+	// configuration loaded by Prometheus does not match the ConfigMap.
+	VerifyTimeout time.Duration
 }
 
 type Resource struct {
+	dryRun    *dryrun.Service
 	k8sClient kubernetes.Interface
 	logger    micrologger.Logger
 
-	lastReloadTime                   time.Time
-	lastSeenConfigMapResourceVersion string
+	lastReloadTime time.Time
+	// reloadedResourceVersions holds the ConfigMap resource version last
+	// reloaded successfully by target name.
+	reloadedResourceVersions map[string]string
 
+	configMapKey       string
 	configMapName      string
 	configMapNamespace string
+	mode               string
+	namespace          string
+	port               int
 	prometheusAddress  string
+	selector           string
+	service            string
+	verifyInterval     time.Duration
+	verifyTimeout      time.Duration
 }
 
 func New(config Config) (*Resource, error) {
+	if config.DryRun == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.DryRun must not be empty", config)
+	}
 	if config.K8sClient == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
 	}
@@ -52,25 +98,62 @@ func New(config Config) (*Resource, error) {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
 
+	if config.ConfigMapKey == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ConfigMapKey must not be empty", config)
+	}
 	if config.ConfigMapName == "" {
 		return nil, microerror.Maskf(invalidConfigError, "%T.ConfigMapName must not be empty", config)
 	}
 	if config.ConfigMapNamespace == "" {
 		return nil, microerror.Maskf(invalidConfigError, "%T.ConfigMapNamespace must not be empty", config)
 	}
-	if config.PrometheusAddress == "" {
-		return nil, microerror.Maskf(invalidConfigError, "%T.PrometheusAddress must not be empty", config)
+	switch config.Mode {
+	case ModeAddress:
+		if config.PrometheusAddress == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.PrometheusAddress must not be empty", config)
+		}
+	case ModeSelector, ModeService:
+		if config.Namespace == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Namespace must not be empty", config)
+		}
+		if config.Port <= 0 {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Port must be positive", config)
+		}
+		if config.Mode == ModeSelector && config.Selector == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Selector must not be empty", config)
+		}
+		if config.Mode == ModeService && config.Service == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Service must not be empty", config)
+		}
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "%T.Mode must be one of %#q, %#q, %#q", config, ModeAddress, ModeSelector, ModeService)
+	}
+	if config.VerifyInterval <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.VerifyInterval must be positive", config)
+	}
+	if config.VerifyTimeout <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.VerifyTimeout must be positive", config)
 	}
 
 	r := &Resource{
+		dryRun:    config.DryRun,
 		k8sClient: config.K8sClient,
 		logger:    config.Logger,
 
-		lastReloadTime: time.Now().Add(-minReloadInterval),
+		lastReloadTime:           time.Now().Add(-minReloadInterval),
+		reloadedResourceVersions: map[string]string{},
 
+		configMapKey:       config.ConfigMapKey,
 		configMapName:      config.ConfigMapName,
 		configMapNamespace: config.ConfigMapNamespace,
+		mode:               config.Mode,
+		namespace:          config.Namespace,
+		port:               config.Port,
 		prometheusAddress:  config.PrometheusAddress,
+		selector:           config.Selector,
+		service:            config.Service,
+		verifyInterval:     config.VerifyInterval,
+		verifyTimeout:      config.VerifyTimeout,
 	}
 
 	return r, nil
@@ -83,6 +166,13 @@ func (r *Resource) Name() string {
 func (r *Resource) ensure(ctx context.Context, obj interface{}) error {
 	var err error
 
+	if r.dryRun.Enabled() {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "dry-run mode is enabled, not reloading prometheus configuration")
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "canceling resource")
+		return nil
+	}
+
 	var cm *corev1.ConfigMap
 	{
 		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("finding %#q ConfigMap in namespace %#q", r.configMapName, r.configMapNamespace))
@@ -95,20 +185,56 @@ func (r *Resource) ensure(ctx context.Context, obj interface{}) error {
 		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found %#q ConfigMap in namespace %#q", r.configMapName, r.configMapNamespace))
 	}
 
-	var reloadRequired bool
+	configurationReloadCheckCount.Inc()
+
+	var targets []target
+	{
+		r.logger.LogCtx(ctx, "level", "debug", "message", "finding Prometheus instances to reload")
+
+		targets, err = r.targets(ctx)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found %d Prometheus instances to reload", len(targets)))
+	}
+
+	{
+		// Forget the state of targets which are gone, e.g. deleted Pods.
+		current := map[string]bool{}
+		for _, t := range targets {
+			current[t.name] = true
+		}
+		for name, resourceVersion := range r.reloadedResourceVersions {
+			if !current[name] {
+				delete(r.reloadedResourceVersions, name)
+				configInSync.DeleteLabelValues(name)
+				configurationLastSuccessfulReload.DeleteLabelValues(name)
+				configurationReloadedInfo.DeleteLabelValues(name, resourceVersion)
+			}
+		}
+	}
+
+	var reloadTargets []target
 	{
 		r.logger.LogCtx(ctx, "level", "debug", "message", "finding out if prometheus configuration needs to be reloaded")
 
-		switch {
-		case cm.ResourceVersion != r.lastSeenConfigMapResourceVersion:
-			reloadRequired = true
-		case time.Now().Sub(r.lastReloadTime) > minReloadInterval:
-			reloadRequired = true
+		// All targets are reloaded periodically. Otherwise only the targets
+		// which did not reload the current ConfigMap successfully yet are.
+		periodic := time.Now().Sub(r.lastReloadTime) > minReloadInterval
+		for _, t := range targets {
+			if periodic || r.reloadedResourceVersions[t.name] != cm.ResourceVersion {
+				reloadTargets = append(reloadTargets, t)
+			}
 		}
 
-		if reloadRequired {
-			r.logger.LogCtx(ctx, "level", "debug", "message", "found out that prometheus configuration needs to be reloaded")
+		if len(reloadTargets) > 0 {
+			configurationReloadRequiredCount.Inc()
+
+			r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found out that prometheus configuration needs to be reloaded for %d instances", len(reloadTargets)))
 		} else {
+			configurationReloadIgnoredCount.Inc()
+
 			r.logger.LogCtx(ctx, "level", "debug", "message", "found out that prometheus configuration does not need to be reloaded")
 
 			r.logger.LogCtx(ctx, "level", "debug", "message", "canceling resource")
@@ -117,21 +243,75 @@ func (r *Resource) ensure(ctx context.Context, obj interface{}) error {
 	}
 
 	{
-		r.logger.LogCtx(ctx, "level", "debug", "message", "reloading prometheus configuration")
+		data, ok := cm.Data[r.configMapKey]
+		if !ok {
+			for _, t := range reloadTargets {
+				configInSync.WithLabelValues(t.name).Set(0)
+			}
+			configurationReloadFailureCount.WithLabelValues(failureReasonMissingConfig).Inc()
 
-		res, err := http.Post(key.PrometheusURLReload(r.prometheusAddress), "", nil)
-		if err != nil {
-			return microerror.Mask(err)
+			return microerror.Maskf(executionFailedError, "key %#q not found in ConfigMap %#q in namespace %#q", r.configMapKey, r.configMapName, r.configMapNamespace)
 		}
-		if res.StatusCode != http.StatusOK {
-			return microerror.Maskf(executionFailedError, "non-200 status code = %d was returned", res.StatusCode)
+
+		errs := r.reloadTargets(ctx, reloadTargets, data)
+
+		var failed []string
+		for i, t := range reloadTargets {
+			if errs[i] != nil {
+				r.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("failed to reload prometheus configuration of %#q", t.name), "stack", fmt.Sprintf("%#v", errs[i]))
+
+				// Failed targets are retried in the next reconciliation,
+				// since their resource version is not updated.
+				failed = append(failed, t.name)
+				continue
+			}
+
+			if previous, ok := r.reloadedResourceVersions[t.name]; ok {
+				configurationReloadedInfo.DeleteLabelValues(t.name, previous)
+			}
+			r.reloadedResourceVersions[t.name] = cm.ResourceVersion
+			configurationReloadedInfo.WithLabelValues(t.name, cm.ResourceVersion).Set(1)
+			configurationLastSuccessfulReload.WithLabelValues(t.name).SetToCurrentTime()
+
+			r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("reloaded prometheus configuration of %#q", t.name))
 		}
 
-		r.lastSeenConfigMapResourceVersion = cm.ResourceVersion
 		r.lastReloadTime = time.Now()
 
-		r.logger.LogCtx(ctx, "level", "debug", "message", "reloaded prometheus configuration")
+		if len(failed) > 0 {
+			return microerror.Maskf(executionFailedError, "failed to reload prometheus configuration of %s", strings.Join(failed, ", "))
+		}
 	}
 
 	return nil
 }
+
+// reloadTargets reloads and verifies the given targets concurrently, at most
+// maxConcurrentReloads at a time, so that slowly converging instances do not
+// delay the others. The errors are returned in the order of the targets.
+func (r *Resource) reloadTargets(ctx context.Context, targets []target, data string) []error {
+	errs := make([]error, len(targets))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentReloads)
+
+	for i, t := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, t target) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("reloading prometheus configuration of %#q", t.name))
+
+			errs[i] = r.reloadAndVerify(ctx, t, data)
+		}(i, t)
+	}
+
+	wg.Wait()
+
+	return errs
+}
@@ -0,0 +1,110 @@
+package reload
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ModeAddress reloads the single Prometheus reachable at the Prometheus
+	// address.
+	ModeAddress = "address"
+	// ModeSelector reloads every ready Pod matching a label selector.
+	ModeSelector = "selector"
+	// ModeService reloads every ready address of the Endpoints of a
+	// Service.
+	ModeService = "service"
+)
+
+// target is a Prometheus instance to reload.
+type target struct {
+	// name identifies the target, e.g. by its Pod name.
+	name string
+	// address is the HTTP URL of the target.
+	address string
+}
+
+// targets returns the Prometheus instances to reload according to the
+// configured mode, ordered by name.
+func (r *Resource) targets(ctx context.Context) ([]target, error) {
+	var targets []target
+
+	switch r.mode {
+	case ModeAddress:
+		targets = append(targets, target{
+			name:    r.prometheusAddress,
+			address: r.prometheusAddress,
+		})
+
+	case ModeSelector:
+		pods, err := r.k8sClient.CoreV1().Pods(r.namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: r.selector,
+		})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, pod := range pods.Items {
+			if pod.Status.PodIP == "" || !isPodReady(pod) {
+				continue
+			}
+
+			targets = append(targets, target{
+				name:    pod.Name,
+				address: podAddress(pod.Status.PodIP, r.port),
+			})
+		}
+
+	case ModeService:
+		endpoints, err := r.k8sClient.CoreV1().Endpoints(r.namespace).Get(ctx, r.service, metav1.GetOptions{})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, subset := range endpoints.Subsets {
+			for _, a := range subset.Addresses {
+				name := a.IP
+				if a.TargetRef != nil && a.TargetRef.Kind == "Pod" {
+					name = a.TargetRef.Name
+				}
+
+				targets = append(targets, target{
+					name:    name,
+					address: podAddress(a.IP, r.port),
+				})
+			}
+		}
+
+	default:
+		return nil, microerror.Maskf(executionFailedError, "unknown reload mode %#q", r.mode)
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].name < targets[j].name
+	})
+
+	return targets, nil
+}
+
+// isPodReady returns whether the given Pod reports the Ready condition.
+func isPodReady(pod corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// podAddress returns the HTTP URL of Prometheus listening on the given IP and
+// port.
+func podAddress(ip string, port int) string {
+	return "http://" + net.JoinHostPort(ip, strconv.Itoa(port))
+}
@@ -0,0 +1,130 @@
+package reload
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newPod(name, ip string, ready corev1.ConditionStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "monitoring",
+			Labels: map[string]string{
+				"app": "prometheus",
+			},
+		},
+		Status: corev1.PodStatus{
+			PodIP: ip,
+			Conditions: []corev1.PodCondition{
+				{
+					Type:   corev1.PodReady,
+					Status: ready,
+				},
+			},
+		},
+	}
+}
+
+// Test_Resource_Reload_targets tests the targets method.
+func Test_Resource_Reload_targets(t *testing.T) {
+	tests := []struct {
+		objects []runtime.Object
+		mode    string
+
+		expectedTargets []target
+	}{
+		// Test that the address mode returns the Prometheus address.
+		{
+			objects: nil,
+			mode:    ModeAddress,
+
+			expectedTargets: []target{
+				{name: "http://prometheus:9090", address: "http://prometheus:9090"},
+			},
+		},
+
+		// Test that the selector mode returns the ready Pods matching the
+		// selector, ordered by name.
+		{
+			objects: []runtime.Object{
+				newPod("prometheus-1", "10.0.0.2", corev1.ConditionTrue),
+				newPod("prometheus-0", "10.0.0.1", corev1.ConditionTrue),
+				newPod("prometheus-2", "10.0.0.3", corev1.ConditionFalse),
+				newPod("prometheus-3", "", corev1.ConditionTrue),
+			},
+			mode: ModeSelector,
+
+			expectedTargets: []target{
+				{name: "prometheus-0", address: "http://10.0.0.1:9090"},
+				{name: "prometheus-1", address: "http://10.0.0.2:9090"},
+			},
+		},
+
+		// Test that the service mode returns the ready addresses of the
+		// Endpoints, named after their Pods.
+		{
+			objects: []runtime.Object{
+				&corev1.Endpoints{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "prometheus",
+						Namespace: "monitoring",
+					},
+					Subsets: []corev1.EndpointSubset{
+						{
+							Addresses: []corev1.EndpointAddress{
+								{
+									IP:        "10.0.0.2",
+									TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "prometheus-1"},
+								},
+								{
+									IP: "10.0.0.1",
+								},
+							},
+							NotReadyAddresses: []corev1.EndpointAddress{
+								{
+									IP:        "10.0.0.3",
+									TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "prometheus-2"},
+								},
+							},
+						},
+					},
+				},
+			},
+			mode: ModeService,
+
+			expectedTargets: []target{
+				{name: "10.0.0.1", address: "http://10.0.0.1:9090"},
+				{name: "prometheus-1", address: "http://10.0.0.2:9090"},
+			},
+		},
+	}
+
+	for index, test := range tests {
+		r := &Resource{
+			k8sClient: fake.NewSimpleClientset(test.objects...),
+
+			mode:              test.mode,
+			namespace:         "monitoring",
+			port:              9090,
+			prometheusAddress: "http://prometheus:9090",
+			selector:          "app=prometheus",
+			service:           "prometheus",
+		}
+
+		targets, err := r.targets(context.TODO())
+		if err != nil {
+			t.Fatalf("%d: error returned finding targets: %s\n", index, err)
+		}
+
+		if !reflect.DeepEqual(test.expectedTargets, targets) {
+			t.Fatalf("%d: expected targets %#v, got %#v", index, test.expectedTargets, targets)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package reload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"gopkg.in/yaml.v2"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+)
+
+const (
+	// maskedSecret is the value secrets are masked with in the configuration
+	// served by Prometheus.
+	maskedSecret = "<secret>"
+)
+
+// configResponse is the response of the Prometheus API returning the current
+// configuration.
+type configResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		YAML string `json:"yaml"`
+	} `json:"data"`
+}
+
+// configMatches returns whether the configuration served by Prometheus
+// matches the given configuration held in the ConfigMap. The served
+// configuration has defaults filled in and secrets masked, both of which
+// depend on the Prometheus version. So the configurations are not loaded,
+// but every scrape config of the ConfigMap must be contained in the served
+// scrape config of the same job name, and no other jobs must be served.
+// Values missing in the served configuration match zero values, and masked
+// values match any value.
+func configMatches(desired, served string) (bool, error) {
+	desiredJobs, err := scrapeConfigsByJobName(desired)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+	servedJobs, err := scrapeConfigsByJobName(served)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	if len(desiredJobs) != len(servedJobs) {
+		return false, nil
+	}
+	for jobName, d := range desiredJobs {
+		s, ok := servedJobs[jobName]
+		if !ok || !isContained(d, s) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// scrapeConfigsByJobName returns the scrape configs of the given Prometheus
+// configuration by job name.
+func scrapeConfigsByJobName(data string) (map[string]yaml.MapSlice, error) {
+	var c struct {
+		ScrapeConfigs []yaml.MapSlice `yaml:"scrape_configs"`
+	}
+	err := yaml.Unmarshal([]byte(data), &c)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	jobs := map[string]yaml.MapSlice{}
+	for _, s := range c.ScrapeConfigs {
+		jobName, _ := getMapValue(s, "job_name")
+		jobs[fmt.Sprintf("%v", jobName)] = s
+	}
+
+	return jobs, nil
+}
+
+// isContained returns whether the given desired YAML value is contained in
+// the given served one, see configMatches.
+func isContained(desired, served interface{}) bool {
+	if served == maskedSecret {
+		return true
+	}
+	if served == nil {
+		return isZero(desired)
+	}
+
+	switch d := desired.(type) {
+	case yaml.MapSlice:
+		s, ok := served.(yaml.MapSlice)
+		if !ok {
+			return false
+		}
+
+		for _, item := range d {
+			v, ok := getMapValue(s, item.Key)
+			if !ok {
+				if !isZero(item.Value) {
+					return false
+				}
+				continue
+			}
+			if !isContained(item.Value, v) {
+				return false
+			}
+		}
+
+		return true
+
+	case []interface{}:
+		s, ok := served.([]interface{})
+		if !ok || len(d) != len(s) {
+			return false
+		}
+
+		for i := range d {
+			if !isContained(d[i], s[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return fmt.Sprintf("%v", desired) == fmt.Sprintf("%v", served)
+}
+
+// isZero returns whether the given YAML value is empty or the zero value of
+// its type, which Prometheus may omit when serving its configuration.
+func isZero(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case yaml.MapSlice:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case int:
+		return v == 0
+	case float64:
+		return v == 0
+	}
+
+	return false
+}
+
+// getMapValue returns the value of the given key of the given YAML map, and
+// whether the key is set.
+func getMapValue(m yaml.MapSlice, key interface{}) (interface{}, bool) {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// fetchConfig returns the configuration currently loaded by the Prometheus
+// reachable at the given address.
+func (r *Resource) fetchConfig(ctx context.Context, address string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, key.PrometheusURLConfig(address), nil)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", microerror.Maskf(executionFailedError, "non-200 status code = %d was returned fetching configuration", res.StatusCode)
+	}
+
+	var c configResponse
+	err = json.NewDecoder(res.Body).Decode(&c)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	if c.Status != "success" {
+		return "", microerror.Maskf(executionFailedError, "status %#q was returned fetching configuration", c.Status)
+	}
+
+	return c.Data.YAML, nil
+}
+
+// reload requests the Prometheus reachable at the given address to reload its
+// configuration. When it fails, the failure reason is returned with the
+// error, either the HTTP status code or failureReasonRequest.
+func (r *Resource) reload(ctx context.Context, address string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, key.PrometheusURLReload(address), nil)
+	if err != nil {
+		return failureReasonRequest, microerror.Mask(err)
+	}
+
+	start := time.Now()
+	res, err := http.DefaultClient.Do(req)
+	configurationReloadDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		return failureReasonRequest, microerror.Mask(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return strconv.Itoa(res.StatusCode), microerror.Maskf(executionFailedError, "non-200 status code = %d was returned", res.StatusCode)
+	}
+
+	configurationReloadCount.Inc()
+
+	return "", nil
+}
+
+// reloadAndVerify reloads the given target until the configuration it loaded
+// matches the given one. The kubelet projects ConfigMap updates into the Pod
+// with a delay, so Prometheus may reload the previous configuration file
+// before the new one shows up. It gives up once verifyTimeout passed. Whenever
+// it fails, the target is recorded as not in sync and the failure is counted
+// by reason.
+func (r *Resource) reloadAndVerify(ctx context.Context, t target, data string) error {
+	fail := func(reason string, err error) error {
+		configInSync.WithLabelValues(t.name).Set(0)
+		configurationReloadFailureCount.WithLabelValues(reason).Inc()
+
+		return microerror.Mask(err)
+	}
+
+	start := time.Now()
+	for {
+		reason, err := r.reload(ctx, t.address)
+		if err != nil {
+			return fail(reason, err)
+		}
+
+		live, err := r.fetchConfig(ctx, t.address)
+		if err != nil {
+			return fail(failureReasonFetchConfig, err)
+		}
+		ok, err := configMatches(data, live)
+		if err != nil {
+			return fail(failureReasonParseConfig, err)
+		}
+
+		if ok {
+			configInSync.WithLabelValues(t.name).Set(1)
+			configConvergeDuration.Observe(time.Since(start).Seconds())
+
+			return nil
+		}
+
+		if time.Since(start) >= r.verifyTimeout {
+			return fail(failureReasonConfigNotInSync, microerror.Maskf(executionFailedError, "configuration loaded by Prometheus %#q does not match the ConfigMap after %s", t.name, r.verifyTimeout))
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("configuration loaded by Prometheus %#q does not match the ConfigMap yet, reloading again in %s", t.name, r.verifyInterval))
+
+		select {
+		case <-ctx.Done():
+			return fail(failureReasonCanceled, ctx.Err())
+		case <-time.After(r.verifyInterval):
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package reload
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/prometheus/config"
+)
+
+const (
+	testConfig = `global:
+  scrape_interval: 30s
+scrape_configs:
+- job_name: prometheus
+  static_configs:
+  - targets: ['localhost:9090']
+remote_write:
+- url: http://remote/write
+  basic_auth:
+    username: user
+    password: pass
+`
+	testOtherConfig = `global:
+  scrape_interval: 30s
+scrape_configs:
+- job_name: other
+  static_configs:
+  - targets: ['localhost:9090']
+`
+)
+
+// servedConfig returns the given configuration the way Prometheus serves it,
+// with defaults filled in and secrets masked.
+func servedConfig(t *testing.T, data string) string {
+	c, err := config.Load(data)
+	if err != nil {
+		t.Fatalf("error returned loading config: %s\n", err)
+	}
+
+	return c.String()
+}
+
+// Test_Resource_Reload_reloadAndVerify tests the reloadAndVerify method.
+func Test_Resource_Reload_reloadAndVerify(t *testing.T) {
+	tests := []struct {
+		// servedConfigs are the configurations served by Prometheus, one
+		// per reload. The last one is served for all further reloads.
+		servedConfigs []string
+
+		expectedReloads int
+		expectedSuccess bool
+	}{
+		// Test that a reload is verified when Prometheus loaded the
+		// configuration right away.
+		{
+			servedConfigs: []string{testConfig},
+
+			expectedReloads: 1,
+			expectedSuccess: true,
+		},
+
+		// Test that Prometheus is reloaded again until it loaded the
+		// configuration.
+		{
+			servedConfigs: []string{testOtherConfig, testOtherConfig, testConfig},
+
+			expectedReloads: 3,
+			expectedSuccess: true,
+		},
+
+		// Test that verifying a reload fails when Prometheus never loads the
+		// configuration.
+		{
+			servedConfigs: []string{testOtherConfig},
+
+			expectedReloads: -1,
+			expectedSuccess: false,
+		},
+	}
+
+	for index, test := range tests {
+		var reloads int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/-/reload":
+				reloads++
+			case "/api/v1/status/config":
+				i := reloads - 1
+				if i >= len(test.servedConfigs) {
+					i = len(test.servedConfigs) - 1
+				}
+
+				var res configResponse
+				res.Status = "success"
+				res.Data.YAML = servedConfig(t, test.servedConfigs[i])
+
+				_ = json.NewEncoder(w).Encode(res)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		r := &Resource{
+			logger: microloggertest.New(),
+
+			verifyInterval: time.Millisecond,
+			verifyTimeout:  100 * time.Millisecond,
+		}
+
+		err := r.reloadAndVerify(context.Background(), target{name: "prometheus-0", address: server.URL}, testConfig)
+		server.Close()
+
+		if test.expectedSuccess && err != nil {
+			t.Fatalf("%d: unexpected error returned verifying reload: %s\n", index, err)
+		}
+		if !test.expectedSuccess && err == nil {
+			t.Fatalf("%d: expected error not returned verifying reload\n", index)
+		}
+		if test.expectedReloads >= 0 && reloads != test.expectedReloads {
+			t.Fatalf("%d: expected %d reloads, got %d", index, test.expectedReloads, reloads)
+		}
+	}
+}
+
+// Test_Resource_Reload_configMatches tests the configMatches function.
+func Test_Resource_Reload_configMatches(t *testing.T) {
+	tests := []struct {
+		served string
+
+		expectedMatch bool
+	}{
+		// 0. Test that the configuration served as loaded by the vendored
+		// Prometheus configuration matches.
+		{
+			served: servedConfig(t, testConfig),
+
+			expectedMatch: true,
+		},
+
+		// 1. Test that defaults and fields unknown to the vendored
+		// Prometheus configuration, as served by newer Prometheus versions,
+		// and masked secrets match.
+		{
+			served: `global:
+  scrape_interval: 30s
+  scrape_timeout: 10s
+  evaluation_interval: 1m
+scrape_configs:
+- job_name: prometheus
+  honor_timestamps: true
+  scrape_interval: 30s
+  metrics_path: /metrics
+  scheme: http
+  follow_redirects: true
+  enable_http2: true
+  static_configs:
+  - targets:
+    - localhost:9090
+remote_write:
+- url: http://remote/write
+  basic_auth:
+    username: user
+    password: <secret>
+`,
+
+			expectedMatch: true,
+		},
+
+		// 2. Test that a changed scrape config does not match.
+		{
+			served: `scrape_configs:
+- job_name: prometheus
+  static_configs:
+  - targets:
+    - localhost:9091
+`,
+
+			expectedMatch: false,
+		},
+
+		// 3. Test that missing jobs do not match.
+		{
+			served: `scrape_configs: []
+`,
+
+			expectedMatch: false,
+		},
+
+		// 4. Test that additional jobs do not match.
+		{
+			served: `scrape_configs:
+- job_name: prometheus
+  static_configs:
+  - targets:
+    - localhost:9090
+- job_name: other
+  static_configs:
+  - targets:
+    - localhost:9090
+`,
+
+			expectedMatch: false,
+		},
+	}
+
+	for index, test := range tests {
+		match, err := configMatches(testConfig, test.served)
+		if err != nil {
+			t.Fatalf("%d: error returned matching config: %s\n", index, err)
+		}
+
+		if match != test.expectedMatch {
+			t.Fatalf("%d: expected match %t, got %t\n", index, test.expectedMatch, match)
+		}
+	}
+}
+
+// Test_Resource_Reload_reloadTargets tests that the reloadTargets method
+// reloads all targets, and returns their errors in order.
+func Test_Resource_Reload_reloadTargets(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/-/reload":
+		case "/api/v1/status/config":
+			var res configResponse
+			res.Status = "success"
+			res.Data.YAML = servedConfig(t, testConfig)
+
+			_ = json.NewEncoder(w).Encode(res)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer healthy.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	r := &Resource{
+		logger: microloggertest.New(),
+
+		verifyInterval: time.Millisecond,
+		verifyTimeout:  100 * time.Millisecond,
+	}
+
+	targets := []target{
+		{name: "prometheus-0", address: healthy.URL},
+		{name: "prometheus-1", address: failing.URL},
+		{name: "prometheus-2", address: healthy.URL},
+	}
+
+	errs := r.reloadTargets(context.Background(), targets, testConfig)
+
+	if len(errs) != len(targets) {
+		t.Fatalf("expected %d errors, got %d\n", len(targets), len(errs))
+	}
+	if errs[0] != nil || errs[2] != nil {
+		t.Fatalf("unexpected errors returned reloading healthy targets: %v\n", errs)
+	}
+	if errs[1] == nil {
+		t.Fatalf("expected error not returned reloading failing target\n")
+	}
+}
@@ -2,6 +2,7 @@ package resource
 
 import (
 	"os"
+	"time"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
@@ -9,42 +10,137 @@ import (
 	"github.com/giantswarm/operatorkit/v2/pkg/resource/crud"
 	"github.com/giantswarm/operatorkit/v2/pkg/resource/wrapper/metricsresource"
 	"github.com/giantswarm/operatorkit/v2/pkg/resource/wrapper/retryresource"
+	"github.com/giantswarm/prometheus-config-controller/pkg/project"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/certificatesource"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/clustersource"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/etcd"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
 	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/resource/certificate"
 	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/resource/configmap"
 	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/resource/reload"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/resource/secret"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/scrapeconfig"
+	"github.com/giantswarm/prometheus-config-controller/service/discovery"
+	"github.com/giantswarm/prometheus-config-controller/service/dryrun"
 	"github.com/spf13/afero"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// BackendConfigMap writes the scrape configs into the Prometheus
+	// configuration held in a ConfigMap and reloads Prometheus.
+	BackendConfigMap = "configmap"
+	// BackendSecret writes the scrape configs into a Secret consumed by the
+	// Prometheus Operator as additionalScrapeConfigs.
+	BackendSecret = "secret"
+
+	// etcdProbeTimeout is the timeout of a single etcd probe.
+	etcdProbeTimeout = 5 * time.Second
 )
 
 type Config struct {
-	K8sClient kubernetes.Interface
-	Logger    micrologger.Logger
+	// CertFs is the filesystem holding the certificates, see
+	// certificate.Config.Fs.
+	CertFs            afero.Fs
+	CertificateSource certificatesource.Interface
+	ClusterSource     clustersource.Interface
+	Discovery         *discovery.Service
+	DryRun            *dryrun.Service
+	Exporters         []prometheus.Exporter
+	K8sClient         kubernetes.Interface
+	Logger            micrologger.Logger
+	SampleLimits      map[string]uint
 
+	// Backend is the output backend the scrape configs are written to, one
+	// of BackendConfigMap and BackendSecret.
+	Backend            string
 	ConfigMapKey       string
 	ConfigMapName      string
 	ConfigMapNamespace string
-	CertComponentName  string
 	CertDirectory      string
-	CertNamespace      string
-	CertPermission     int
-	PrometheusAddress  string
-	Provider           string
+	CertExpiryWarning  time.Duration
+	// CertOutput is where certificates are written to, see
+	// certificate.Config.Output.
+	CertOutput              string
+	CertPermission          int
+	CertProjectionName      string
+	CertProjectionNamespace string
+	CertProjectionSecrets   int
+	DiscoveryURL            string
+	EtcdScrapeDelay         time.Duration
+	EtcdScrapeMode          string
+	PrometheusVersion       string
+	PrometheusAddress       string
+	Provider                string
+	ReloadMode              string
+	ReloadNamespace         string
+	ReloadPort              int
+	ReloadSelector          string
+	ReloadService           string
+	ReloadVerifyInterval    time.Duration
+	ReloadVerifyTimeout     time.Duration
+	SecretKey               string
+	SecretName              string
+	SecretNamespace         string
+	ShardCount              int
+	ShardIndex              int
 }
 
 func New(config Config) ([]resource.Interface, error) {
+	if config.Backend != BackendConfigMap && config.Backend != BackendSecret {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Backend must be one of %#q, %#q", config, BackendConfigMap, BackendSecret)
+	}
+
 	var err error
 
+	var etcdProber *etcd.Prober
+	{
+		c := etcd.ProberConfig{
+			Fs:     config.CertFs,
+			Logger: config.Logger,
+
+			CertDirectory: config.CertDirectory,
+			Timeout:       etcdProbeTimeout,
+		}
+
+		etcdProber, err = etcd.NewProber(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var eventRecorder record.EventRecorder
+	{
+		b := record.NewBroadcaster()
+		b.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: config.K8sClient.CoreV1().Events("")})
+
+		eventRecorder = b.NewRecorder(scheme.Scheme, corev1.EventSource{Component: project.Name()})
+	}
+
 	var certificateResource resource.Interface
 	{
 		c := certificate.Config{
-			Fs:        afero.NewOsFs(),
-			K8sClient: config.K8sClient,
-			Logger:    config.Logger,
+			CertificateSource: config.CertificateSource,
+			ClusterSource:     config.ClusterSource,
+			DryRun:            config.DryRun,
+			EventRecorder:     eventRecorder,
+			Fs:                config.CertFs,
+			K8sClient:         config.K8sClient,
+			Logger:            config.Logger,
 
-			CertComponentName: config.CertComponentName,
-			CertDirectory:     config.CertDirectory,
-			CertNamespace:     config.CertNamespace,
-			CertPermission:    os.FileMode(config.CertPermission),
+			CertDirectory:       config.CertDirectory,
+			CertPermission:      os.FileMode(config.CertPermission),
+			ExpiryWarningWindow: config.CertExpiryWarning,
+			Output:              config.CertOutput,
+			ProjectionName:      config.CertProjectionName,
+			ProjectionNamespace: config.CertProjectionNamespace,
+			ProjectionSecrets:   config.CertProjectionSecrets,
+			ShardCount:          config.ShardCount,
+			ShardIndex:          config.ShardIndex,
 		}
 
 		ops, err := certificate.New(c)
@@ -58,18 +154,47 @@ func New(config Config) ([]resource.Interface, error) {
 		}
 	}
 
+	var scrapeConfigBuilder *scrapeconfig.Builder
+	{
+		c := scrapeconfig.BuilderConfig{
+			ClusterSource: config.ClusterSource,
+			Discovery:     config.Discovery,
+			EtcdProber:    etcdProber,
+			EventRecorder: eventRecorder,
+			Exporters:     config.Exporters,
+			Fs:            config.CertFs,
+			Logger:        config.Logger,
+			SampleLimits:  config.SampleLimits,
+
+			CertDirectory:     config.CertDirectory,
+			DiscoveryURL:      config.DiscoveryURL,
+			EtcdScrapeDelay:   config.EtcdScrapeDelay,
+			EtcdScrapeMode:    config.EtcdScrapeMode,
+			PrometheusVersion: config.PrometheusVersion,
+			Provider:          config.Provider,
+			ShardCount:        config.ShardCount,
+			ShardIndex:        config.ShardIndex,
+		}
+
+		scrapeConfigBuilder, err = scrapeconfig.NewBuilder(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var configMapResource resource.Interface
 	{
 		c := configmap.Config{
-			K8sClient: config.K8sClient,
-			Logger:    config.Logger,
+			Builder:       scrapeConfigBuilder,
+			DryRun:        config.DryRun,
+			EventRecorder: eventRecorder,
+			Fs:            config.CertFs,
+			K8sClient:     config.K8sClient,
+			Logger:        config.Logger,
 
-			CertDirectory:      config.CertDirectory,
 			ConfigMapKey:       config.ConfigMapKey,
 			ConfigMapName:      config.ConfigMapName,
 			ConfigMapNamespace: config.ConfigMapNamespace,
-
-			Provider: config.Provider,
 		}
 
 		configMapResource, err = configmap.New(c)
@@ -81,12 +206,23 @@ func New(config Config) ([]resource.Interface, error) {
 	var reloadResource resource.Interface
 	{
 		c := reload.Config{
+			DryRun:    config.DryRun,
 			K8sClient: config.K8sClient,
 			Logger:    config.Logger,
 
+			ConfigMapKey:       config.ConfigMapKey,
 			ConfigMapName:      config.ConfigMapName,
 			ConfigMapNamespace: config.ConfigMapNamespace,
 			PrometheusAddress:  config.PrometheusAddress,
+
+			Mode:      config.ReloadMode,
+			Namespace: config.ReloadNamespace,
+			Port:      config.ReloadPort,
+			Selector:  config.ReloadSelector,
+			Service:   config.ReloadService,
+
+			VerifyInterval: config.ReloadVerifyInterval,
+			VerifyTimeout:  config.ReloadVerifyTimeout,
 		}
 
 		reloadResource, err = reload.New(c)
@@ -95,10 +231,40 @@ func New(config Config) ([]resource.Interface, error) {
 		}
 	}
 
-	resources := []resource.Interface{
-		certificateResource,
-		configMapResource,
-		reloadResource,
+	var secretResource resource.Interface
+	{
+		c := secret.Config{
+			Builder:   scrapeConfigBuilder,
+			DryRun:    config.DryRun,
+			Fs:        config.CertFs,
+			K8sClient: config.K8sClient,
+			Logger:    config.Logger,
+
+			SecretKey:       config.SecretKey,
+			SecretName:      config.SecretName,
+			SecretNamespace: config.SecretNamespace,
+		}
+
+		secretResource, err = secret.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var resources []resource.Interface
+	switch config.Backend {
+	case BackendConfigMap:
+		resources = []resource.Interface{
+			certificateResource,
+			configMapResource,
+			reloadResource,
+		}
+	case BackendSecret:
+		// Reloading is left to the Prometheus Operator.
+		resources = []resource.Interface{
+			certificateResource,
+			secretResource,
+		}
 	}
 
 	{
@@ -0,0 +1,137 @@
+package secret
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/prometheus-config-controller/service/dryrun"
+)
+
+// Test_Resource_Secret_EnsureCreated_Create tests that the EnsureCreated
+// method creates the Secret when it does not exist.
+func Test_Resource_Secret_EnsureCreated_Create(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	writeCertificates(t, fs, "xa5ly")
+
+	k8sClient := fake.NewSimpleClientset()
+	r := newResource(t, k8sClient, fs, newClusterService("xa5ly"))
+
+	err := r.EnsureCreated(context.TODO(), v1.Service{})
+	if err != nil {
+		t.Fatalf("error returned ensuring created: %s\n", err)
+	}
+
+	secret, err := k8sClient.CoreV1().Secrets("monitoring").Get(context.TODO(), "additional-scrape-configs", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error returned getting secret: %s\n", err)
+	}
+	if len(secret.Data["prometheus-additional.yaml"]) == 0 {
+		t.Fatalf("expected scrape configs in secret, got none")
+	}
+}
+
+// Test_Resource_Secret_EnsureCreated_MissingCertificates tests that the
+// EnsureCreated method writes the scrape configs of the clusters whose
+// certificates exist, when the certificates of another cluster are missing.
+func Test_Resource_Secret_EnsureCreated_MissingCertificates(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	writeCertificates(t, fs, "xa5ly")
+
+	k8sClient := fake.NewSimpleClientset()
+	r := newResource(t, k8sClient, fs, newClusterService("xa5ly"), newClusterService("al9qy"))
+
+	err := r.EnsureCreated(context.TODO(), v1.Service{})
+	if err != nil {
+		t.Fatalf("error returned ensuring created: %s\n", err)
+	}
+
+	secret, err := k8sClient.CoreV1().Secrets("monitoring").Get(context.TODO(), "additional-scrape-configs", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error returned getting secret: %s\n", err)
+	}
+
+	data := string(secret.Data["prometheus-additional.yaml"])
+	if !strings.Contains(data, "workload-cluster-xa5ly-") {
+		t.Fatalf("expected scrape configs of cluster xa5ly in secret, got none")
+	}
+	if strings.Contains(data, "workload-cluster-al9qy-") {
+		t.Fatalf("expected no scrape configs of cluster al9qy in secret, got some")
+	}
+}
+
+// Test_Resource_Secret_EnsureCreated_DryRun tests that the EnsureCreated
+// method neither creates nor updates the Secret in dry-run mode, and stores
+// the diff of the scrape configs.
+func Test_Resource_Secret_EnsureCreated_DryRun(t *testing.T) {
+	existing := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "additional-scrape-configs",
+			Namespace: "monitoring",
+		},
+		Data: map[string][]byte{
+			"prometheus-additional.yaml": []byte("[]\n"),
+		},
+	}
+
+	tests := []struct {
+		objects []runtime.Object
+
+		expectedData string
+	}{
+		// 0. Test that a missing Secret is not created.
+		{
+			objects: nil,
+
+			expectedData: "",
+		},
+
+		// 1. Test that an existing Secret is not updated.
+		{
+			objects: []runtime.Object{existing},
+
+			expectedData: "[]\n",
+		},
+	}
+
+	for index, test := range tests {
+		fs := afero.NewMemMapFs()
+		writeCertificates(t, fs, "xa5ly")
+
+		k8sClient := fake.NewSimpleClientset(test.objects...)
+		r := newResource(t, k8sClient, fs, newClusterService("xa5ly"))
+
+		dryRun, err := dryrun.New(dryrun.Config{Enabled: true})
+		if err != nil {
+			t.Fatalf("%d: error returned creating dry-run service: %s\n", index, err)
+		}
+		r.dryRun = dryRun
+
+		err = r.EnsureCreated(context.TODO(), v1.Service{})
+		if err != nil {
+			t.Fatalf("%d: error returned ensuring created: %s\n", index, err)
+		}
+
+		secret, err := k8sClient.CoreV1().Secrets("monitoring").Get(context.TODO(), "additional-scrape-configs", metav1.GetOptions{})
+		if len(test.objects) == 0 {
+			if err == nil {
+				t.Fatalf("%d: expected secret not to be created\n", index)
+			}
+		} else if err != nil {
+			t.Fatalf("%d: error returned getting secret: %s\n", index, err)
+		} else if string(secret.Data["prometheus-additional.yaml"]) != test.expectedData {
+			t.Fatalf("%d: expected secret not to be updated, got:\n%s\n", index, secret.Data["prometheus-additional.yaml"])
+		}
+
+		diff, _ := dryRun.Diff()
+		if !strings.Contains(diff, "# job `workload-cluster-xa5ly-apiserver` added") {
+			t.Fatalf("%d: expected diff to list added jobs, got:\n%s\n", index, diff)
+		}
+	}
+}
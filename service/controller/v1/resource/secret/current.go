@@ -0,0 +1,29 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// getCurrentState returns the current state of the additional scrape configs
+// Secret. If the Secret does not exist, nil is returned.
+func (r *Resource) getCurrentState(ctx context.Context) (*corev1.Secret, error) {
+	r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("finding Secret %#q in namespace %#q", r.secretName, r.secretNamespace))
+
+	secret, err := r.k8sClient.CoreV1().Secrets(r.secretNamespace).Get(ctx, r.secretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("did not find Secret %#q in namespace %#q", r.secretName, r.secretNamespace))
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found Secret %#q in namespace %#q", r.secretName, r.secretNamespace))
+
+	return secret, nil
+}
@@ -0,0 +1,79 @@
+package secret
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/afero"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// Test_Resource_Secret_GetCurrentState tests the getCurrentState method.
+func Test_Resource_Secret_GetCurrentState(t *testing.T) {
+	tests := []struct {
+		secrets []*corev1.Secret
+
+		expectedSecret bool
+	}{
+		// 0. Test that nil is returned when the Secret does not exist.
+		{
+			secrets: nil,
+
+			expectedSecret: false,
+		},
+
+		// 1. Test that the Secret is returned when it exists.
+		{
+			secrets: []*corev1.Secret{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "additional-scrape-configs",
+						Namespace: "monitoring",
+					},
+				},
+			},
+
+			expectedSecret: true,
+		},
+
+		// 2. Test that Secrets of other namespaces are not returned.
+		{
+			secrets: []*corev1.Secret{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "additional-scrape-configs",
+						Namespace: "default",
+					},
+				},
+			},
+
+			expectedSecret: false,
+		},
+	}
+
+	for index, test := range tests {
+		k8sClient := fake.NewSimpleClientset()
+		for _, s := range test.secrets {
+			_, err := k8sClient.CoreV1().Secrets(s.GetNamespace()).Create(context.TODO(), s, metav1.CreateOptions{})
+			if err != nil {
+				t.Fatalf("%d: error returned creating secret: %s\n", index, err)
+			}
+		}
+
+		r := newResource(t, k8sClient, afero.NewMemMapFs())
+
+		current, err := r.getCurrentState(context.TODO())
+		if err != nil {
+			t.Fatalf("%d: error returned getting current state: %s\n", index, err)
+		}
+
+		if test.expectedSecret && current == nil {
+			t.Fatalf("%d: expected secret, got nil\n", index)
+		}
+		if !test.expectedSecret && current != nil {
+			t.Fatalf("%d: expected no secret, got %#v\n", index, current)
+		}
+	}
+}
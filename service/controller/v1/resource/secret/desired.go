@@ -0,0 +1,41 @@
+package secret
+
+import (
+	"context"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+)
+
+// getDesiredState returns the additional scrape configs Secret holding the
+// generated scrape configs of all clusters in the format expected by the
+// Prometheus Operator's additionalScrapeConfigs.
+func (r *Resource) getDesiredState(ctx context.Context) (*corev1.Secret, error) {
+	services, config, err := r.builder.Build(ctx, nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", "computing desired state of Secret")
+
+	data, err := prometheus.RenderScrapeConfigs(services, config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.secretName,
+			Namespace: r.secretNamespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			r.secretKey: data,
+		},
+	}
+
+	return secret, nil
+}
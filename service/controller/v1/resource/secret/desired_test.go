@@ -0,0 +1,114 @@
+package secret
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// Test_Resource_Secret_GetDesiredState tests the getDesiredState method.
+func Test_Resource_Secret_GetDesiredState(t *testing.T) {
+	tests := []struct {
+		services     []v1.Service
+		certificates []string
+
+		expectedJobNames []string
+	}{
+		// 0. Test that no scrape configs are generated without clusters.
+		{
+			services: nil,
+
+			expectedJobNames: nil,
+		},
+
+		// 1. Test that the scrape configs of a cluster are generated.
+		{
+			services: []v1.Service{
+				newClusterService("xa5ly"),
+			},
+			certificates: []string{"xa5ly"},
+
+			expectedJobNames: []string{
+				"workload-cluster-xa5ly-apiserver",
+				"workload-cluster-xa5ly-aws-node",
+				"workload-cluster-xa5ly-cadvisor",
+				"workload-cluster-xa5ly-calico-node",
+				"workload-cluster-xa5ly-docker-daemon",
+				"workload-cluster-xa5ly-ingress",
+				"workload-cluster-xa5ly-kube-proxy",
+				"workload-cluster-xa5ly-kube-state-managed-app",
+				"workload-cluster-xa5ly-kubelet",
+				"workload-cluster-xa5ly-managed-app",
+				"workload-cluster-xa5ly-node-exporter",
+				"workload-cluster-xa5ly-workload",
+			},
+		},
+
+		// 2. Test that clusters whose certificates are missing are not
+		// scraped, while other clusters still are.
+		{
+			services: []v1.Service{
+				newClusterService("xa5ly"),
+				newClusterService("al9qy"),
+			},
+			certificates: []string{"xa5ly"},
+
+			expectedJobNames: []string{
+				"workload-cluster-xa5ly-apiserver",
+				"workload-cluster-xa5ly-aws-node",
+				"workload-cluster-xa5ly-cadvisor",
+				"workload-cluster-xa5ly-calico-node",
+				"workload-cluster-xa5ly-docker-daemon",
+				"workload-cluster-xa5ly-ingress",
+				"workload-cluster-xa5ly-kube-proxy",
+				"workload-cluster-xa5ly-kube-state-managed-app",
+				"workload-cluster-xa5ly-kubelet",
+				"workload-cluster-xa5ly-managed-app",
+				"workload-cluster-xa5ly-node-exporter",
+				"workload-cluster-xa5ly-workload",
+			},
+		},
+	}
+
+	for index, test := range tests {
+		fs := afero.NewMemMapFs()
+		writeCertificates(t, fs, test.certificates...)
+
+		r := newResource(t, fake.NewSimpleClientset(), fs, test.services...)
+
+		desired, err := r.getDesiredState(context.TODO())
+		if err != nil {
+			t.Fatalf("%d: error returned getting desired state: %s\n", index, err)
+		}
+
+		if desired.GetName() != "additional-scrape-configs" || desired.GetNamespace() != "monitoring" {
+			t.Fatalf("%d: expected secret monitoring/additional-scrape-configs, got %s/%s\n", index, desired.GetNamespace(), desired.GetName())
+		}
+
+		var scrapeConfigs []struct {
+			JobName string `yaml:"job_name"`
+		}
+		err = yaml.Unmarshal(desired.Data["prometheus-additional.yaml"], &scrapeConfigs)
+		if err != nil {
+			t.Fatalf("%d: error returned parsing scrape configs: %s\n", index, err)
+		}
+
+		var jobNames []string
+		for _, s := range scrapeConfigs {
+			jobNames = append(jobNames, s.JobName)
+		}
+
+		if len(jobNames) != len(test.expectedJobNames) {
+			t.Fatalf("%d: expected jobs %v, got %v\n", index, test.expectedJobNames, jobNames)
+		}
+		for i := range jobNames {
+			if jobNames[i] != test.expectedJobNames[i] {
+				t.Fatalf("%d: expected jobs %v, got %v\n", index, test.expectedJobNames, jobNames)
+			}
+		}
+	}
+}
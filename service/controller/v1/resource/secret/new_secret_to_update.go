@@ -0,0 +1,26 @@
+package secret
+
+import (
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// newSecretToUpdate creates a new instance of Secret ready to be used as an
+// argument to Update method of generated client. Only the data under the
+// managed key is replaced, so other keys of the Secret are preserved. It
+// returns nil if objects don't have differences in scope of interest.
+func newSecretToUpdate(current, desired *corev1.Secret, key string) *corev1.Secret {
+	merged := current.DeepCopy()
+
+	if merged.Data == nil {
+		merged.Data = map[string][]byte{}
+	}
+	merged.Data[key] = desired.Data[key]
+
+	if reflect.DeepEqual(current, merged) {
+		return nil
+	}
+
+	return merged
+}
@@ -0,0 +1,183 @@
+// Package secret implements a resource writing the generated scrape configs
+// into a Secret, to be consumed by the Prometheus Operator as
+// additionalScrapeConfigs. Reloading Prometheus is left to the operator.
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/afero"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/scrapeconfig"
+	"github.com/giantswarm/prometheus-config-controller/service/dryrun"
+)
+
+const (
+	Name = "secretv1"
+)
+
+type Config struct {
+	// Builder gathers the clusters and the configuration their scrape
+	// configs are generated with.
+	Builder *scrapeconfig.Builder
+	// DryRun holds whether the dry-run mode is enabled, in which the diff of
+	// the Secret is computed but the Secret is not written.
+	DryRun *dryrun.Service
+	// Fs is the file system the certificate files referenced by the
+	// generated scrape configs are validated against.
+	Fs        afero.Fs
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+
+	// SecretKey is the key in the Secret under which the scrape configs are
+	// held.
+	SecretKey       string
+	SecretName      string
+	SecretNamespace string
+}
+
+type Resource struct {
+	builder   *scrapeconfig.Builder
+	dryRun    *dryrun.Service
+	fs        afero.Fs
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+
+	secretKey       string
+	secretName      string
+	secretNamespace string
+}
+
+func New(config Config) (*Resource, error) {
+	if config.Builder == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Builder must not be empty", config)
+	}
+	if config.DryRun == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.DryRun must not be empty", config)
+	}
+	if config.Fs == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Fs must not be empty", config)
+	}
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.SecretKey == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.SecretKey must not be empty", config)
+	}
+	if config.SecretName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.SecretName must not be empty", config)
+	}
+	if config.SecretNamespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.SecretNamespace must not be empty", config)
+	}
+
+	r := &Resource{
+		builder:   config.Builder,
+		dryRun:    config.DryRun,
+		fs:        config.Fs,
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		secretKey:       config.SecretKey,
+		secretName:      config.SecretName,
+		secretNamespace: config.SecretNamespace,
+	}
+
+	return r, nil
+}
+
+func (r *Resource) Name() string {
+	return Name
+}
+
+func (r *Resource) ensure(ctx context.Context, obj interface{}) error {
+	current, err := r.getCurrentState(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	desired, err := r.getDesiredState(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	{
+		r.logger.LogCtx(ctx, "level", "debug", "message", "validating desired scrape configs")
+
+		err = prometheus.ValidateScrapeConfigs(r.fs, string(desired.Data[r.secretKey]))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "validated desired scrape configs")
+	}
+
+	if r.dryRun.Enabled() {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "computing diff of Secret")
+
+		var currentData string
+		if current != nil {
+			currentData = string(current.Data[r.secretKey])
+		}
+
+		diff, err := prometheus.DiffScrapeConfigs(currentData, string(desired.Data[r.secretKey]))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r.dryRun.SetDiff(diff)
+
+		if diff == "" {
+			r.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("dry-run mode is enabled, Secret %#q in namespace %#q is up to date", desired.GetName(), desired.GetNamespace()))
+		} else {
+			r.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("dry-run mode is enabled, not writing Secret %#q in namespace %#q", desired.GetName(), desired.GetNamespace()), "diff", diff)
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "cancelling resource")
+		return nil
+	}
+
+	if current == nil {
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("creating Secret %#q in namespace %#q", desired.GetName(), desired.GetNamespace()))
+
+		_, err = r.k8sClient.CoreV1().Secrets(desired.GetNamespace()).Create(ctx, desired, metav1.CreateOptions{})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("created Secret %#q in namespace %#q", desired.GetName(), desired.GetNamespace()))
+
+		return nil
+	}
+
+	s := newSecretToUpdate(current, desired, r.secretKey)
+
+	{
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("updating Secret %#q in namespace %#q", current.GetName(), current.GetNamespace()))
+
+		if s == nil {
+			r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("Secret %#q in namespace %#q is up to date", current.GetName(), current.GetNamespace()))
+
+			r.logger.LogCtx(ctx, "level", "debug", "message", "cancelling resource")
+			return nil
+		}
+
+		_, err = r.k8sClient.CoreV1().Secrets(s.GetNamespace()).Update(ctx, s, metav1.UpdateOptions{})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("updated Secret %#q in namespace %#q", current.GetName(), current.GetNamespace()))
+	}
+
+	return nil
+}
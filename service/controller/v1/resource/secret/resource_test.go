@@ -0,0 +1,215 @@
+package secret
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/spf13/afero"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/etcd"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/scrapeconfig"
+	"github.com/giantswarm/prometheus-config-controller/service/dryrun"
+)
+
+// fakeClusterSource is a cluster source returning a fixed list of clusters.
+type fakeClusterSource struct {
+	services []v1.Service
+}
+
+func (s *fakeClusterSource) Clusters(ctx context.Context) ([]v1.Service, error) {
+	return s.services, nil
+}
+
+func (s *fakeClusterSource) NewRuntimeObject() runtime.Object {
+	return new(v1.Service)
+}
+
+func (s *fakeClusterSource) Selector() labels.Selector {
+	return labels.Everything()
+}
+
+// newClusterService returns the Service of the cluster with the given ID.
+func newClusterService(clusterID string) v1.Service {
+	return v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "master",
+			Namespace: clusterID,
+			Annotations: map[string]string{
+				prometheus.ClusterAnnotation: clusterID,
+			},
+		},
+	}
+}
+
+// writeCertificates writes the certificate files of the clusters with the
+// given IDs to the given filesystem.
+func writeCertificates(t *testing.T, fs afero.Fs, clusterIDs ...string) {
+	for _, clusterID := range clusterIDs {
+		for _, suffix := range []string{"ca", "crt", "key"} {
+			err := afero.WriteFile(fs, "/certs/"+clusterID+"-"+suffix+".pem", []byte("foo"), 0600)
+			if err != nil {
+				t.Fatalf("error returned writing certificate: %s\n", err)
+			}
+		}
+	}
+}
+
+// newResource returns a secret resource generating scrape configs for the
+// given clusters.
+func newResource(t *testing.T, k8sClient kubernetes.Interface, fs afero.Fs, services ...v1.Service) *Resource {
+	etcdProber, err := etcd.NewProber(etcd.ProberConfig{
+		Fs:     fs,
+		Logger: microloggertest.New(),
+
+		CertDirectory: "/certs",
+		Timeout:       time.Second,
+	})
+	if err != nil {
+		t.Fatalf("error returned creating etcd prober: %s\n", err)
+	}
+
+	builder, err := scrapeconfig.NewBuilder(scrapeconfig.BuilderConfig{
+		ClusterSource: &fakeClusterSource{services: services},
+		EtcdProber:    etcdProber,
+		EventRecorder: record.NewFakeRecorder(10),
+		Fs:            fs,
+		Logger:        microloggertest.New(),
+
+		CertDirectory:  "/certs",
+		EtcdScrapeMode: prometheus.EtcdScrapeModeAnnotation,
+		Provider:       "aws",
+	})
+	if err != nil {
+		t.Fatalf("error returned creating builder: %s\n", err)
+	}
+
+	dryRun, err := dryrun.New(dryrun.Config{})
+	if err != nil {
+		t.Fatalf("error returned creating dry-run service: %s\n", err)
+	}
+
+	r, err := New(Config{
+		Builder:   builder,
+		DryRun:    dryRun,
+		Fs:        fs,
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+
+		SecretKey:       "prometheus-additional.yaml",
+		SecretName:      "additional-scrape-configs",
+		SecretNamespace: "monitoring",
+	})
+	if err != nil {
+		t.Fatalf("error returned creating resource: %s\n", err)
+	}
+
+	return r
+}
+
+// Test_Resource_Secret_New tests the New function.
+func Test_Resource_Secret_New(t *testing.T) {
+	valid := func() Config {
+		return Config{
+			Builder:   &scrapeconfig.Builder{},
+			DryRun:    &dryrun.Service{},
+			Fs:        afero.NewMemMapFs(),
+			K8sClient: fake.NewSimpleClientset(),
+			Logger:    microloggertest.New(),
+
+			SecretKey:       "prometheus-additional.yaml",
+			SecretName:      "additional-scrape-configs",
+			SecretNamespace: "monitoring",
+		}
+	}
+
+	tests := []struct {
+		config func() Config
+
+		expectedErrorHandler func(error) bool
+	}{
+		// 0. Test that the default config returns an error.
+		{
+			config: func() Config { return Config{} },
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 1. Test that a valid config produces a secret resource.
+		{
+			config: valid,
+
+			expectedErrorHandler: nil,
+		},
+
+		// 2. Test that the builder must not be empty.
+		{
+			config: func() Config {
+				c := valid()
+				c.Builder = nil
+				return c
+			},
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 3. Test that the dry-run service must not be empty.
+		{
+			config: func() Config {
+				c := valid()
+				c.DryRun = nil
+				return c
+			},
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 4. Test that the fs must not be empty.
+		{
+			config: func() Config {
+				c := valid()
+				c.Fs = nil
+				return c
+			},
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// 5. Test that the secret key must not be empty.
+		{
+			config: func() Config {
+				c := valid()
+				c.SecretKey = ""
+				return c
+			},
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+	}
+
+	for index, test := range tests {
+		r, err := New(test.config())
+		if err != nil && test.expectedErrorHandler == nil {
+			t.Fatalf("%d: unexpected error returned creating secret resource: %s\n", index, err)
+		}
+		if err != nil && !test.expectedErrorHandler(err) {
+			t.Fatalf("%d: incorrect error returned creating secret resource: %s\n", index, err)
+		}
+		if err == nil && test.expectedErrorHandler != nil {
+			t.Fatalf("%d: expected error not returned creating secret resource\n", index)
+		}
+
+		if test.expectedErrorHandler == nil && r == nil {
+			t.Fatalf("%d: returned secret resource was nil", index)
+		}
+	}
+}
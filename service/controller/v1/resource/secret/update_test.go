@@ -0,0 +1,78 @@
+package secret
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// Test_Resource_Secret_EnsureCreated_Update tests that the EnsureCreated
+// method updates the managed key of an existing Secret, and preserves its
+// other keys.
+func Test_Resource_Secret_EnsureCreated_Update(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	writeCertificates(t, fs, "xa5ly", "0ba9v")
+
+	existing := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "additional-scrape-configs",
+			Namespace: "monitoring",
+		},
+		Data: map[string][]byte{
+			"prometheus-additional.yaml": []byte("[]\n"),
+			"other.yaml":                 []byte("foo"),
+		},
+	}
+
+	k8sClient := fake.NewSimpleClientset(existing)
+	r := newResource(t, k8sClient, fs, newClusterService("xa5ly"), newClusterService("0ba9v"))
+
+	err := r.EnsureCreated(context.TODO(), corev1.Service{})
+	if err != nil {
+		t.Fatalf("error returned ensuring created: %s\n", err)
+	}
+
+	secret, err := k8sClient.CoreV1().Secrets("monitoring").Get(context.TODO(), "additional-scrape-configs", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error returned getting secret: %s\n", err)
+	}
+
+	data := string(secret.Data["prometheus-additional.yaml"])
+	for _, jobName := range []string{"workload-cluster-xa5ly-apiserver", "workload-cluster-0ba9v-apiserver"} {
+		if !strings.Contains(data, jobName) {
+			t.Fatalf("expected job %#q in secret, got:\n%s", jobName, data)
+		}
+	}
+	if string(secret.Data["other.yaml"]) != "foo" {
+		t.Fatalf("expected other keys to be preserved, got %#v", secret.Data)
+	}
+}
+
+// Test_Resource_Secret_newSecretToUpdate tests the newSecretToUpdate function.
+func Test_Resource_Secret_newSecretToUpdate(t *testing.T) {
+	current := &corev1.Secret{
+		Data: map[string][]byte{
+			"prometheus-additional.yaml": []byte("foo"),
+		},
+	}
+
+	if s := newSecretToUpdate(current, current.DeepCopy(), "prometheus-additional.yaml"); s != nil {
+		t.Fatalf("expected no update for equal secrets, got %#v", s)
+	}
+
+	desired := &corev1.Secret{
+		Data: map[string][]byte{
+			"prometheus-additional.yaml": []byte("bar"),
+		},
+	}
+
+	s := newSecretToUpdate(current, desired, "prometheus-additional.yaml")
+	if s == nil || string(s.Data["prometheus-additional.yaml"]) != "bar" {
+		t.Fatalf("expected update to desired data, got %#v", s)
+	}
+}
@@ -0,0 +1,244 @@
+// Package scrapeconfig gathers the clusters scrape configs are generated for,
+// and the configuration they are generated with. It is shared by the output
+// backends, so that they all generate the same scrape configs.
+package scrapeconfig
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/prometheus/config"
+	"github.com/spf13/afero"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/clustersource"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/etcd"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+	"github.com/giantswarm/prometheus-config-controller/service/discovery"
+)
+
+const (
+	// missingCertificatesEventReason is the reason of the Event recorded when
+	// the certificates of a cluster are missing and it is not scraped.
+	missingCertificatesEventReason = "MissingCertificates"
+)
+
+type BuilderConfig struct {
+	ClusterSource clustersource.Interface
+	// Discovery is updated with the targets of all clusters on every build,
+	// to be served over HTTP service discovery. It is optional.
+	Discovery *discovery.Service
+	// EtcdProber probes etcd of clusters when EtcdScrapeMode is
+	// prometheus.EtcdScrapeModeProbe.
+	EtcdProber *etcd.Prober
+	// EventRecorder records Events on the Services of clusters whose
+	// certificates are missing.
+	EventRecorder record.EventRecorder
+	// Exporters is the exporter catalog. When nil, the built-in catalog is
+	// used.
+	Exporters []prometheus.Exporter
+	// Fs is the filesystem the certificates of clusters are written to.
+	Fs     afero.Fs
+	Logger micrologger.Logger
+	// SampleLimits are the default sample limits by job type, see
+	// prometheus.Config.SampleLimits.
+	SampleLimits map[string]uint
+
+	CertDirectory string
+	// DiscoveryURL is the URL of the targets endpoint etcd jobs discover
+	// their targets from, see prometheus.Config.DiscoveryURL.
+	DiscoveryURL string
+	// EtcdScrapeDelay and EtcdScrapeMode select when etcd of a cluster is
+	// scraped, see prometheus.GetEtcdScrapeStatus.
+	EtcdScrapeDelay time.Duration
+	EtcdScrapeMode  string
+	// PrometheusVersion is the version of Prometheus the configuration is
+	// generated for. It must support HTTP service discovery when
+	// DiscoveryURL is set, see prometheus.ValidateDiscovery.
+	PrometheusVersion string
+	Provider          string
+	// ShardCount and ShardIndex select the clusters scrape configs are
+	// generated for when Prometheus is sharded, see
+	// prometheus.FilterShardServices.
+	ShardCount int
+	ShardIndex int
+}
+
+// Builder gathers the clusters scrape configs are generated for, probes etcd
+// of the clusters of this shard if required, updates the targets served over
+// HTTP service discovery, and validates their scrape overrides. Clusters of
+// this shard whose certificates are missing are dropped, so that they do not
+// invalidate the configuration of all other clusters.
+type Builder struct {
+	clusterSource clustersource.Interface
+	discovery     *discovery.Service
+	etcdProber    *etcd.Prober
+	eventRecorder record.EventRecorder
+	exporters     []prometheus.Exporter
+	fs            afero.Fs
+	logger        micrologger.Logger
+	sampleLimits  map[string]uint
+
+	certDirectory   string
+	discoveryURL    string
+	etcdScrapeDelay time.Duration
+	etcdScrapeMode  string
+	provider        string
+	shardCount      int
+	shardIndex      int
+}
+
+func NewBuilder(config BuilderConfig) (*Builder, error) {
+	if config.ClusterSource == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ClusterSource must not be empty", config)
+	}
+	if config.EtcdProber == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.EtcdProber must not be empty", config)
+	}
+	if config.EventRecorder == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.EventRecorder must not be empty", config)
+	}
+	if config.Fs == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Fs must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.CertDirectory == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CertDirectory must not be empty", config)
+	}
+	if config.DiscoveryURL != "" {
+		err := prometheus.ValidateDiscovery(config.DiscoveryURL, config.PrometheusVersion)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "%T.DiscoveryURL can not be used: %s", config, err)
+		}
+	}
+	if config.Provider == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Provider must not be empty", config)
+	}
+
+	b := &Builder{
+		clusterSource: config.ClusterSource,
+		discovery:     config.Discovery,
+		etcdProber:    config.EtcdProber,
+		eventRecorder: config.EventRecorder,
+		exporters:     config.Exporters,
+		fs:            config.Fs,
+		logger:        config.Logger,
+		sampleLimits:  config.SampleLimits,
+
+		certDirectory:   config.CertDirectory,
+		discoveryURL:    config.DiscoveryURL,
+		etcdScrapeDelay: config.EtcdScrapeDelay,
+		etcdScrapeMode:  config.EtcdScrapeMode,
+		provider:        config.Provider,
+		shardCount:      config.ShardCount,
+		shardIndex:      config.ShardIndex,
+	}
+
+	return b, nil
+}
+
+// Build returns the Services of all clusters, and the configuration their
+// scrape configs are generated with by prometheus.GetScrapeConfigs and
+// prometheus.RenderConfig. The global scrape interval and timeout of the
+// returned configuration are taken from the given base configuration, so that
+// scrape overrides are validated against the globals they are rendered with.
+// The base configuration is nil for backends which do not hold the global
+// configuration, where the Prometheus defaults apply. The returned Services do
+// not include the clusters of this shard whose certificates are missing.
+func (b *Builder) Build(ctx context.Context, base *config.Config) ([]v1.Service, prometheus.Config, error) {
+	b.logger.LogCtx(ctx, "level", "debug", "message", "fetching all clusters")
+
+	services, err := b.clusterSource.Clusters(ctx)
+	if err != nil {
+		return nil, prometheus.Config{}, microerror.Mask(err)
+	}
+
+	config := prometheus.Config{
+		CertDirectory:   b.certDirectory,
+		DiscoveryURL:    b.discoveryURL,
+		EtcdScrapeDelay: b.etcdScrapeDelay,
+		EtcdScrapeMode:  b.etcdScrapeMode,
+		Exporters:       b.exporters,
+		Provider:        b.provider,
+		SampleLimits:    b.sampleLimits,
+		ShardCount:      b.shardCount,
+		ShardIndex:      b.shardIndex,
+	}
+	if base != nil {
+		config.GlobalScrapeInterval = base.GlobalConfig.ScrapeInterval
+		config.GlobalScrapeTimeout = base.GlobalConfig.ScrapeTimeout
+	}
+
+	validServices := prometheus.FilterInvalidServices(services)
+	validServices = prometheus.FilterShardServices(validServices, b.shardIndex, b.shardCount)
+
+	{
+		b.logger.LogCtx(ctx, "level", "debug", "message", "checking certificates")
+
+		missing := map[string]bool{}
+		missingCertificates.Reset()
+		for i, service := range validServices {
+			clusterID := prometheus.GetClusterID(service)
+
+			paths, err := prometheus.MissingCertificates(b.fs, b.certDirectory, clusterID)
+			if err != nil {
+				return nil, prometheus.Config{}, microerror.Mask(err)
+			}
+
+			if len(paths) > 0 {
+				message := fmt.Sprintf("Not scraping cluster %#q, certificate files %v are missing", clusterID, paths)
+				b.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("not scraping cluster %#q", clusterID), "reason", fmt.Sprintf("certificate files %v are missing", paths))
+				b.eventRecorder.Event(&validServices[i], v1.EventTypeWarning, missingCertificatesEventReason, message)
+
+				missing[clusterID] = true
+				missingCertificates.WithLabelValues(clusterID).Set(1)
+			} else {
+				missingCertificates.WithLabelValues(clusterID).Set(0)
+			}
+		}
+
+		if len(missing) > 0 {
+			services = prometheus.FilterClusters(services, missing)
+			validServices = prometheus.FilterClusters(validServices, missing)
+		}
+
+		b.logger.LogCtx(ctx, "level", "debug", "message", "checked certificates")
+	}
+
+	if b.etcdScrapeMode == prometheus.EtcdScrapeModeProbe {
+		b.logger.LogCtx(ctx, "level", "debug", "message", "probing etcd")
+		config.EtcdProbeErrors = b.etcdProber.Probe(ctx, validServices)
+	}
+	etcd.UpdateMetrics(validServices, config)
+	prometheus.UpdateSampleLimitMetrics(validServices, config)
+
+	if b.discovery != nil {
+		b.logger.LogCtx(ctx, "level", "debug", "message", "updating discovery targets")
+		b.discovery.Update(ctx, services, config)
+	}
+
+	b.logger.LogCtx(ctx, "level", "debug", "message", "validating scrape overrides")
+	invalidScrapeOverrides.Reset()
+	for _, service := range validServices {
+		clusterID := prometheus.GetClusterID(service)
+
+		err := prometheus.ValidateScrapeOverrides(service, config)
+		if prometheus.IsInvalidScrapeOverride(err) {
+			b.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("ignoring scrape overrides of cluster %#q", clusterID), "reason", err.Error())
+			invalidScrapeOverrides.WithLabelValues(clusterID).Set(1)
+		} else if err != nil {
+			return nil, prometheus.Config{}, microerror.Mask(err)
+		} else {
+			invalidScrapeOverrides.WithLabelValues(clusterID).Set(0)
+		}
+	}
+
+	return services, config, nil
+}
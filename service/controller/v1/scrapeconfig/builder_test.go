@@ -0,0 +1,138 @@
+package scrapeconfig
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/config"
+	"github.com/spf13/afero"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/etcd"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+)
+
+// fakeClusterSource is a cluster source returning a fixed list of clusters.
+type fakeClusterSource struct {
+	services []v1.Service
+}
+
+func (s *fakeClusterSource) Clusters(ctx context.Context) ([]v1.Service, error) {
+	return s.services, nil
+}
+
+func (s *fakeClusterSource) NewRuntimeObject() runtime.Object {
+	return new(v1.Service)
+}
+
+func (s *fakeClusterSource) Selector() labels.Selector {
+	return labels.Everything()
+}
+
+// Test_ScrapeConfig_Builder_Build_ScrapeOverrides tests that scrape overrides
+// are validated against the global scrape interval and timeout of the base
+// configuration they are rendered with.
+func Test_ScrapeConfig_Builder_Build_ScrapeOverrides(t *testing.T) {
+	tests := []struct {
+		base string
+
+		expectedInvalid     float64
+		expectedSampleLimit float64
+	}{
+		// 1. Test that without base configuration the overrides are
+		// validated against the Prometheus defaults, where a 20s timeout
+		// fits the 1m interval.
+		{
+			base: "",
+
+			expectedInvalid:     0,
+			expectedSampleLimit: 1000,
+		},
+
+		// 2. Test that a 20s timeout exceeding the 15s global interval of
+		// the base configuration is reported as invalid, and the sample
+		// limit override of the cluster is not applied either.
+		{
+			base: "global:\n  scrape_interval: 15s\n  scrape_timeout: 10s\n",
+
+			expectedInvalid:     1,
+			expectedSampleLimit: 0,
+		},
+	}
+
+	for index, test := range tests {
+		fs := afero.NewMemMapFs()
+		for _, p := range []string{key.CAPath("/certs", "xa5ly"), key.CrtPath("/certs", "xa5ly"), key.KeyPath("/certs", "xa5ly")} {
+			err := afero.WriteFile(fs, p, []byte("foo"), 0600)
+			if err != nil {
+				t.Fatalf("%d: error returned writing certificate: %s\n", index, err)
+			}
+		}
+
+		etcdProber, err := etcd.NewProber(etcd.ProberConfig{
+			Fs:     fs,
+			Logger: microloggertest.New(),
+
+			CertDirectory: "/certs",
+			Timeout:       time.Second,
+		})
+		if err != nil {
+			t.Fatalf("%d: error returned creating etcd prober: %s\n", index, err)
+		}
+
+		service := v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "master",
+				Namespace: "xa5ly",
+				Annotations: map[string]string{
+					prometheus.ClusterAnnotation:       "xa5ly",
+					prometheus.ScrapeTimeoutAnnotation: "20s",
+					prometheus.SampleLimitAnnotation:   "1000",
+				},
+			},
+		}
+
+		b, err := NewBuilder(BuilderConfig{
+			ClusterSource: &fakeClusterSource{services: []v1.Service{service}},
+			EtcdProber:    etcdProber,
+			EventRecorder: record.NewFakeRecorder(10),
+			Fs:            fs,
+			Logger:        microloggertest.New(),
+
+			CertDirectory:  "/certs",
+			EtcdScrapeMode: prometheus.EtcdScrapeModeAnnotation,
+			Provider:       "aws",
+		})
+		if err != nil {
+			t.Fatalf("%d: error returned creating builder: %s\n", index, err)
+		}
+
+		var base *config.Config
+		if test.base != "" {
+			base, err = prometheus.LoadConfig(test.base)
+			if err != nil {
+				t.Fatalf("%d: error returned loading base configuration: %s\n", index, err)
+			}
+		}
+
+		services, config, err := b.Build(context.TODO(), base)
+		if err != nil {
+			t.Fatalf("%d: error returned building: %s\n", index, err)
+		}
+
+		if v := testutil.ToFloat64(invalidScrapeOverrides.WithLabelValues("xa5ly")); v != test.expectedInvalid {
+			t.Fatalf("%d: expected invalid scrape overrides %v, got %v", index, test.expectedInvalid, v)
+		}
+		if v := float64(prometheus.GetSampleLimits(services[0], config)["apiserver"]); v != test.expectedSampleLimit {
+			t.Fatalf("%d: expected sample limit %v, got %v", index, test.expectedSampleLimit, v)
+		}
+	}
+}
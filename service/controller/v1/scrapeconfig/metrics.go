@@ -0,0 +1,36 @@
+package scrapeconfig
+
+import (
+	prometheusclient "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	prometheusNamespace = "prometheus_config_controller"
+	prometheusSubsystem = "scrape_config"
+)
+
+var (
+	invalidScrapeOverrides = prometheusclient.NewGaugeVec(
+		prometheusclient.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "invalid_scrape_overrides",
+			Help:      "Whether the scrape interval, timeout and sample limit annotations of a cluster are invalid and ignored.",
+		},
+		[]string{"cluster_id"},
+	)
+	missingCertificates = prometheusclient.NewGaugeVec(
+		prometheusclient.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "missing_certificates",
+			Help:      "Whether the certificate files of a cluster are missing and the cluster is not scraped.",
+		},
+		[]string{"cluster_id"},
+	)
+)
+
+func init() {
+	prometheusclient.MustRegister(invalidScrapeOverrides)
+	prometheusclient.MustRegister(missingCertificates)
+}
@@ -0,0 +1,88 @@
+// Package discovery provides the targets of all workload clusters in the
+// format of the Prometheus HTTP service discovery, so that Prometheus picks up
+// cluster changes without its configuration being rewritten and reloaded.
+//
+// Targets are served from a snapshot the controller updates on every
+// reconciliation, so that requests neither probe etcd nor resolve SRV
+// records. Only the replica running the controller updates the snapshot, and
+// replicas without snapshot serve no targets at all, see TargetGroups.
+package discovery
+
+import (
+	"context"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/etcd"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+)
+
+type Config struct {
+	Logger micrologger.Logger
+}
+
+type Service struct {
+	logger micrologger.Logger
+
+	mutex        sync.RWMutex
+	synced       bool
+	targetGroups []prometheus.TargetGroup
+}
+
+func New(config Config) (*Service, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	s := &Service{
+		logger: config.Logger,
+
+		targetGroups: []prometheus.TargetGroup{},
+	}
+
+	return s, nil
+}
+
+// TargetGroups returns the target groups of the last snapshot. When clusterID
+// or jobType are not empty, only target groups of that cluster or job type
+// are returned. The returned bool is false until the first snapshot, so that
+// no target groups are not mistaken for all clusters being gone.
+func (s *Service) TargetGroups(clusterID, jobType string) ([]prometheus.TargetGroup, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if !s.synced {
+		return nil, false
+	}
+
+	targetGroups := []prometheus.TargetGroup{}
+	for _, g := range s.targetGroups {
+		if clusterID != "" && g.Labels[prometheus.ClusterIDLabel] != clusterID {
+			continue
+		}
+		if jobType != "" && g.Labels[prometheus.JobTypeMetaLabel] != jobType {
+			continue
+		}
+
+		targetGroups = append(targetGroups, g)
+	}
+
+	return targetGroups, true
+}
+
+// Update replaces the snapshot with the target groups of the given clusters.
+// The configuration must hold the etcd probe results in
+// prometheus.EtcdScrapeModeProbe. SRV names of etcd are resolved into
+// endpoints.
+func (s *Service) Update(ctx context.Context, services []v1.Service, config prometheus.Config) {
+	services = etcd.ResolveServices(ctx, services)
+	targetGroups := prometheus.GetTargetGroups(services, config, "")
+
+	s.mutex.Lock()
+	s.synced = true
+	s.targetGroups = targetGroups
+	s.mutex.Unlock()
+}
@@ -0,0 +1,109 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+)
+
+// Test_Discovery_TargetGroups tests that the TargetGroups method serves the
+// snapshot of the last update, filtered by cluster ID and job type.
+func Test_Discovery_TargetGroups(t *testing.T) {
+	s, err := New(Config{Logger: microloggertest.New()})
+	if err != nil {
+		t.Fatalf("error returned creating discovery service: %s\n", err)
+	}
+
+	if g, synced := s.TargetGroups("", ""); synced || len(g) != 0 {
+		t.Fatalf("expected no target groups before the first update, got %#v", g)
+	}
+
+	services := []v1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "master",
+				Namespace: "xa5ly",
+				Annotations: map[string]string{
+					prometheus.ClusterAnnotation: "xa5ly",
+					key.AnnotationEtcdDomain:     "etcd1.xa5ly:2379,etcd2.xa5ly:2379",
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "master",
+				Namespace: "0ba9v",
+				Annotations: map[string]string{
+					prometheus.ClusterAnnotation: "0ba9v",
+				},
+			},
+		},
+	}
+
+	s.Update(context.TODO(), services, prometheus.Config{Provider: "aws"})
+
+	tests := []struct {
+		clusterID string
+		jobType   string
+
+		expectedTargets []string
+	}{
+		// 0. Test that all target groups are returned without filter.
+		{
+			clusterID: "",
+			jobType:   "",
+
+			expectedTargets: []string{"master.0ba9v", "master.xa5ly", "etcd1.xa5ly:2379", "etcd2.xa5ly:2379"},
+		},
+
+		// 1. Test that target groups are filtered by cluster ID.
+		{
+			clusterID: "0ba9v",
+			jobType:   "",
+
+			expectedTargets: []string{"master.0ba9v"},
+		},
+
+		// 2. Test that target groups are filtered by cluster ID and job type.
+		{
+			clusterID: "xa5ly",
+			jobType:   prometheus.EtcdJobType,
+
+			expectedTargets: []string{"etcd1.xa5ly:2379", "etcd2.xa5ly:2379"},
+		},
+
+		// 3. Test that an unknown cluster returns no target groups.
+		{
+			clusterID: "foo",
+			jobType:   "",
+
+			expectedTargets: nil,
+		},
+	}
+
+	for index, test := range tests {
+		var targets []string
+		targetGroups, synced := s.TargetGroups(test.clusterID, test.jobType)
+		if !synced {
+			t.Fatalf("%d: expected target groups to be synced\n", index)
+		}
+		for _, g := range targetGroups {
+			targets = append(targets, g.Targets...)
+		}
+
+		if len(targets) != len(test.expectedTargets) {
+			t.Fatalf("%d: expected targets %v, got %v\n", index, test.expectedTargets, targets)
+		}
+		for i := range targets {
+			if targets[i] != test.expectedTargets[i] {
+				t.Fatalf("%d: expected targets %v, got %v\n", index, test.expectedTargets, targets)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Package dryrun holds the state of the dry-run mode, in which the
+// Prometheus configuration is computed but neither written nor reloaded, and
+// certificates are not written.
+package dryrun
+
+import (
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// Enabled enables the dry-run mode.
+	Enabled bool
+}
+
+// Service holds whether the dry-run mode is enabled, and the diff between the
+// current and desired Prometheus configuration last computed in dry-run mode.
+type Service struct {
+	enabled bool
+
+	mutex   sync.RWMutex
+	diff    string
+	updated time.Time
+}
+
+func New(config Config) (*Service, error) {
+	s := &Service{
+		enabled: config.Enabled,
+	}
+
+	return s, nil
+}
+
+// Enabled returns whether the dry-run mode is enabled.
+func (s *Service) Enabled() bool {
+	return s.enabled
+}
+
+// Diff returns the diff last computed in dry-run mode and the time it was
+// computed at. The time is zero when no diff was computed yet.
+func (s *Service) Diff() (string, time.Time) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.diff, s.updated
+}
+
+// SetDiff stores the given diff computed in dry-run mode.
+func (s *Service) SetDiff(diff string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.diff = diff
+	s.updated = time.Now()
+}
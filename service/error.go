@@ -12,12 +12,3 @@ var invalidConfigError = &microerror.Error{
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
-
-var waitError = &microerror.Error{
-	Kind: "waitError",
-}
-
-// IsWait asserts waitError.
-func IsWait(err error) bool {
-	return microerror.Cause(err) == waitError
-}
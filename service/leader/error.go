@@ -0,0 +1,21 @@
+package leader
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var leadershipLostError = &microerror.Error{
+	Kind: "leadershipLostError",
+}
+
+// IsLeadershipLost asserts leadershipLostError.
+func IsLeadershipLost(err error) bool {
+	return microerror.Cause(err) == leadershipLostError
+}
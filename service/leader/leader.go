@@ -0,0 +1,209 @@
+// Package leader elects the replica running the controller using a Lease, so
+// that multiple replicas do not race on the same Prometheus configuration.
+package leader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+type Config struct {
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+
+	// Enabled enables leader election. When disabled, this replica is
+	// always the leader.
+	Enabled bool
+	// Identity identifies this replica in the Lease, e.g. the Pod name.
+	Identity string
+	// LeaseDuration, RenewDeadline and RetryPeriod are passed to the leader
+	// elector, see leaderelection.LeaderElectionConfig.
+	LeaseDuration  time.Duration
+	LeaseName      string
+	LeaseNamespace string
+	RenewDeadline  time.Duration
+	RetryPeriod    time.Duration
+}
+
+// Status is the leader election status as seen by this replica.
+type Status struct {
+	// Enabled is whether leader election is enabled.
+	Enabled bool `json:"enabled"`
+	// Identity is the identity of this replica.
+	Identity string `json:"identity"`
+	// IsLeader is whether this replica is the leader.
+	IsLeader bool `json:"isLeader"`
+	// Leader is the identity of the current leader. It is empty as long as
+	// no leader has been observed.
+	Leader string `json:"leader"`
+}
+
+type Service struct {
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+
+	enabled        bool
+	identity       string
+	leaseDuration  time.Duration
+	leaseName      string
+	leaseNamespace string
+	renewDeadline  time.Duration
+	retryPeriod    time.Duration
+
+	mutex    sync.RWMutex
+	isLeader bool
+	leader   string
+}
+
+func New(config Config) (*Service, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.Identity == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Identity must not be empty", config)
+	}
+
+	if config.Enabled {
+		if config.K8sClient == nil {
+			return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+		}
+
+		if config.LeaseName == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.LeaseName must not be empty", config)
+		}
+		if config.LeaseNamespace == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.LeaseNamespace must not be empty", config)
+		}
+		if config.RetryPeriod <= 0 {
+			return nil, microerror.Maskf(invalidConfigError, "%T.RetryPeriod must be positive", config)
+		}
+		if config.RenewDeadline <= config.RetryPeriod {
+			return nil, microerror.Maskf(invalidConfigError, "%T.RenewDeadline must be greater than %T.RetryPeriod", config, config)
+		}
+		if config.LeaseDuration <= config.RenewDeadline {
+			return nil, microerror.Maskf(invalidConfigError, "%T.LeaseDuration must be greater than %T.RenewDeadline", config, config)
+		}
+	}
+
+	s := &Service{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		enabled:        config.Enabled,
+		identity:       config.Identity,
+		leaseDuration:  config.LeaseDuration,
+		leaseName:      config.LeaseName,
+		leaseNamespace: config.LeaseNamespace,
+		renewDeadline:  config.RenewDeadline,
+		retryPeriod:    config.RetryPeriod,
+	}
+
+	return s, nil
+}
+
+// Run calls run once this replica is elected leader, and blocks until the
+// given context is done. The context passed to run is cancelled when the
+// leadership is lost. Run returns leadershipLostError in that case, since the
+// state held in memory by the leader can not be handed over, and the replica
+// is expected to exit so that a standby replica takes over.
+//
+// When leader election is disabled, run is called right away.
+func (s *Service) Run(ctx context.Context, run func(ctx context.Context)) error {
+	if !s.enabled {
+		s.setLeader(s.identity)
+		run(ctx)
+
+		<-ctx.Done()
+		return nil
+	}
+
+	c := leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      s.leaseName,
+				Namespace: s.leaseNamespace,
+			},
+			Client: s.k8sClient.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{
+				Identity: s.identity,
+			},
+		},
+		LeaseDuration:   s.leaseDuration,
+		RenewDeadline:   s.renewDeadline,
+		RetryPeriod:     s.retryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				s.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("started leading Lease %#q in namespace %#q", s.leaseName, s.leaseNamespace))
+				run(ctx)
+			},
+			OnStoppedLeading: func() {
+				s.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("stopped leading Lease %#q in namespace %#q", s.leaseName, s.leaseNamespace))
+			},
+			OnNewLeader: func(identity string) {
+				s.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("observed new leader %#q", identity))
+				s.setLeader(identity)
+			},
+		},
+		Name: s.leaseName,
+	}
+
+	elector, err := leaderelection.NewLeaderElector(c)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("waiting to be elected leader of Lease %#q in namespace %#q", s.leaseName, s.leaseNamespace))
+
+	// Run only returns once the context is done or the leadership is lost.
+	elector.Run(ctx)
+	s.setLeader("")
+
+	select {
+	case <-ctx.Done():
+		return nil
+	default:
+		return microerror.Maskf(leadershipLostError, "Lease %#q in namespace %#q", s.leaseName, s.leaseNamespace)
+	}
+}
+
+// Status returns the leader election status as seen by this replica.
+func (s *Service) Status() Status {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return Status{
+		Enabled:  s.enabled,
+		Identity: s.identity,
+		IsLeader: s.isLeader,
+		Leader:   s.leader,
+	}
+}
+
+func (s *Service) setLeader(identity string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.leader = identity
+	s.isLeader = identity == s.identity
+
+	leader.Reset()
+	if identity != "" {
+		leader.WithLabelValues(identity).Set(1)
+	}
+	if s.isLeader {
+		isLeader.Set(1)
+	} else {
+		isLeader.Set(0)
+	}
+}
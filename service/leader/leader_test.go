@@ -0,0 +1,146 @@
+package leader
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// Test_Leader_New tests the New function.
+func Test_Leader_New(t *testing.T) {
+	tests := []struct {
+		config Config
+
+		expectedErrorHandler func(error) bool
+	}{
+		// Test that the default config returns an error.
+		{
+			config: Config{},
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// Test that a config with leader election disabled does not require
+		// a Kubernetes client or Lease.
+		{
+			config: Config{
+				Logger: microloggertest.New(),
+
+				Identity: "replica-1",
+			},
+
+			expectedErrorHandler: nil,
+		},
+
+		// Test that a config with leader election enabled requires a
+		// Kubernetes client.
+		{
+			config: Config{
+				Logger: microloggertest.New(),
+
+				Enabled:        true,
+				Identity:       "replica-1",
+				LeaseDuration:  15 * time.Second,
+				LeaseName:      "prometheus-config-controller",
+				LeaseNamespace: "monitoring",
+				RenewDeadline:  10 * time.Second,
+				RetryPeriod:    2 * time.Second,
+			},
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// Test that the lease duration must be greater than the renew
+		// deadline.
+		{
+			config: Config{
+				K8sClient: fake.NewSimpleClientset(),
+				Logger:    microloggertest.New(),
+
+				Enabled:        true,
+				Identity:       "replica-1",
+				LeaseDuration:  10 * time.Second,
+				LeaseName:      "prometheus-config-controller",
+				LeaseNamespace: "monitoring",
+				RenewDeadline:  10 * time.Second,
+				RetryPeriod:    2 * time.Second,
+			},
+
+			expectedErrorHandler: IsInvalidConfig,
+		},
+
+		// Test that a valid config with leader election enabled is accepted.
+		{
+			config: Config{
+				K8sClient: fake.NewSimpleClientset(),
+				Logger:    microloggertest.New(),
+
+				Enabled:        true,
+				Identity:       "replica-1",
+				LeaseDuration:  15 * time.Second,
+				LeaseName:      "prometheus-config-controller",
+				LeaseNamespace: "monitoring",
+				RenewDeadline:  10 * time.Second,
+				RetryPeriod:    2 * time.Second,
+			},
+
+			expectedErrorHandler: nil,
+		},
+	}
+
+	for index, test := range tests {
+		_, err := New(test.config)
+
+		if err != nil && test.expectedErrorHandler == nil {
+			t.Fatalf("%d: unexpected error returned: %s\n", index, err)
+		}
+		if err != nil && !test.expectedErrorHandler(err) {
+			t.Fatalf("%d: incorrect error returned: %s\n", index, err)
+		}
+		if err == nil && test.expectedErrorHandler != nil {
+			t.Fatalf("%d: expected error not returned\n", index)
+		}
+	}
+}
+
+// Test_Leader_Run_Disabled tests that run is called right away and the
+// replica reports itself as leader when leader election is disabled.
+func Test_Leader_Run_Disabled(t *testing.T) {
+	s, err := New(Config{
+		Logger: microloggertest.New(),
+
+		Identity: "replica-1",
+	})
+	if err != nil {
+		t.Fatalf("error returned creating service: %s\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var called bool
+	err = s.Run(ctx, func(ctx context.Context) {
+		called = true
+		cancel()
+	})
+	if err != nil {
+		t.Fatalf("error returned running: %s\n", err)
+	}
+
+	if !called {
+		t.Fatalf("expected run to be called")
+	}
+
+	expected := Status{
+		Enabled:  false,
+		Identity: "replica-1",
+		IsLeader: true,
+		Leader:   "replica-1",
+	}
+	if status := s.Status(); !reflect.DeepEqual(expected, status) {
+		t.Fatalf("expected status %#v, got %#v", expected, status)
+	}
+}
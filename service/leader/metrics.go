@@ -0,0 +1,36 @@
+package leader
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	prometheusNamespace = "prometheus_config_controller"
+	prometheusSubsystem = "leader_election"
+)
+
+var (
+	isLeader = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "is_leader",
+			Help:      "Whether this replica is the leader running the controller.",
+		},
+	)
+
+	leader = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "leader",
+			Help:      "Identity of the current leader, as seen by this replica.",
+		},
+		[]string{"identity"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(isLeader)
+	prometheus.MustRegister(leader)
+}
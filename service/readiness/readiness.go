@@ -0,0 +1,187 @@
+// Package readiness gates booting the controller on Prometheus being ready,
+// and tracks the readiness state of this replica.
+package readiness
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+)
+
+const (
+	// StrategyConfig waits for the Prometheus API to serve the current
+	// configuration.
+	StrategyConfig = "config"
+	// StrategyNone does not wait for Prometheus.
+	StrategyNone = "none"
+	// StrategyReady waits for the Prometheus readiness endpoint to report
+	// Prometheus as ready.
+	StrategyReady = "ready"
+)
+
+const (
+	// initialInterval is the interval between the first two requests to
+	// Prometheus. It doubles with every failed request up to the maximum
+	// interval.
+	initialInterval = 500 * time.Millisecond
+	// requestTimeout is the timeout of a single request to Prometheus.
+	requestTimeout = 5 * time.Second
+)
+
+// State is the readiness state of this replica.
+type State string
+
+const (
+	StateWaitingForPrometheus State = "waiting for Prometheus"
+	StateWaitingForLeadership State = "waiting for leadership"
+	StateControllerRunning    State = "controller running"
+)
+
+// Ready returns whether this replica is ready to serve traffic. Standby
+// replicas waiting for leadership are not ready, since only the leader builds
+// the targets served over HTTP service discovery.
+func (s State) Ready() bool {
+	return s == StateControllerRunning
+}
+
+type Config struct {
+	Logger micrologger.Logger
+
+	// MaxInterval is the maximum interval between two requests to
+	// Prometheus.
+	MaxInterval       time.Duration
+	PrometheusAddress string
+	// Strategy is how to wait for Prometheus, one of StrategyConfig,
+	// StrategyNone and StrategyReady.
+	Strategy string
+}
+
+type Service struct {
+	httpClient *http.Client
+	logger     micrologger.Logger
+
+	maxInterval       time.Duration
+	prometheusAddress string
+	strategy          string
+
+	mutex sync.RWMutex
+	state State
+}
+
+func New(config Config) (*Service, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	switch config.Strategy {
+	case StrategyConfig, StrategyReady:
+		if config.PrometheusAddress == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.PrometheusAddress must not be empty", config)
+		}
+		if config.MaxInterval <= 0 {
+			return nil, microerror.Maskf(invalidConfigError, "%T.MaxInterval must be positive", config)
+		}
+	case StrategyNone:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "%T.Strategy must be one of %#q, %#q, %#q", config, StrategyConfig, StrategyNone, StrategyReady)
+	}
+
+	s := &Service{
+		httpClient: &http.Client{
+			Timeout: requestTimeout,
+		},
+		logger: config.Logger,
+
+		maxInterval:       config.MaxInterval,
+		prometheusAddress: config.PrometheusAddress,
+		strategy:          config.Strategy,
+
+		state: StateWaitingForPrometheus,
+	}
+
+	return s, nil
+}
+
+// State returns the readiness state of this replica.
+func (s *Service) State() State {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.state
+}
+
+// SetState sets the readiness state of this replica.
+func (s *Service) SetState(state State) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.state = state
+}
+
+// WaitForPrometheus blocks until Prometheus is ready according to the
+// configured strategy, retrying with exponential backoff without giving up.
+// It only returns an error when the given context is done.
+func (s *Service) WaitForPrometheus(ctx context.Context) error {
+	s.SetState(StateWaitingForPrometheus)
+
+	var url string
+	switch s.strategy {
+	case StrategyConfig:
+		url = key.PrometheusURLConfig(s.prometheusAddress)
+	case StrategyReady:
+		url = key.PrometheusURLReady(s.prometheusAddress)
+	default:
+		return nil
+	}
+
+	o := func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		res, err := s.httpClient.Do(req)
+		if err != nil {
+			return microerror.Maskf(waitError, "failed request URL %#q with error %#q", url, err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return microerror.Maskf(waitError, "expected 2xx response for URL %#q but got %d", url, res.StatusCode)
+		}
+
+		return nil
+	}
+
+	interval := initialInterval
+	if interval > s.maxInterval {
+		interval = s.maxInterval
+	}
+
+	for {
+		err := o()
+		if err == nil {
+			return nil
+		}
+
+		s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("Prometheus is not ready yet, retrying in %s", interval), "reason", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return microerror.Mask(ctx.Err())
+		case <-time.After(interval):
+		}
+
+		interval *= 2
+		if interval > s.maxInterval {
+			interval = s.maxInterval
+		}
+	}
+}
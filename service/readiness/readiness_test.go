@@ -0,0 +1,127 @@
+package readiness
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+)
+
+// Test_Readiness_WaitForPrometheus tests that the strategies request the
+// expected Prometheus URL.
+func Test_Readiness_WaitForPrometheus(t *testing.T) {
+	tests := []struct {
+		strategy string
+
+		expectedPath string
+	}{
+		// Test that the ready strategy waits for the readiness endpoint.
+		{
+			strategy: StrategyReady,
+
+			expectedPath: "/-/ready",
+		},
+
+		// Test that the config strategy waits for the configuration API.
+		{
+			strategy: StrategyConfig,
+
+			expectedPath: "/api/v1/status/config",
+		},
+
+		// Test that the none strategy does not request Prometheus.
+		{
+			strategy: StrategyNone,
+
+			expectedPath: "",
+		},
+	}
+
+	for index, test := range tests {
+		var path string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		s, err := New(Config{
+			Logger: microloggertest.New(),
+
+			MaxInterval:       time.Second,
+			PrometheusAddress: server.URL,
+			Strategy:          test.strategy,
+		})
+		if err != nil {
+			t.Fatalf("%d: error returned creating service: %s\n", index, err)
+		}
+
+		if state := s.State(); state != StateWaitingForPrometheus {
+			t.Fatalf("%d: expected state %#q, got %#q", index, StateWaitingForPrometheus, state)
+		}
+
+		err = s.WaitForPrometheus(context.Background())
+		if err != nil {
+			t.Fatalf("%d: error returned waiting for Prometheus: %s\n", index, err)
+		}
+
+		server.Close()
+
+		if path != test.expectedPath {
+			t.Fatalf("%d: expected request to %#q, got %#q", index, test.expectedPath, path)
+		}
+	}
+}
+
+// Test_Readiness_WaitForPrometheus_Cancel tests that waiting for Prometheus
+// only returns an error once the context is done.
+func Test_Readiness_WaitForPrometheus_Cancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	s, err := New(Config{
+		Logger: microloggertest.New(),
+
+		MaxInterval:       100 * time.Millisecond,
+		PrometheusAddress: server.URL,
+		Strategy:          StrategyReady,
+	})
+	if err != nil {
+		t.Fatalf("error returned creating service: %s\n", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = s.WaitForPrometheus(ctx)
+	if err == nil {
+		t.Fatalf("expected error not returned waiting for Prometheus")
+	}
+	if ctx.Err() == nil {
+		t.Fatalf("expected waiting for Prometheus to return only once the context is done")
+	}
+}
+
+// Test_Readiness_State_Ready tests that only replicas running the controller
+// are ready.
+func Test_Readiness_State_Ready(t *testing.T) {
+	tests := []struct {
+		state State
+
+		expectedReady bool
+	}{
+		{state: StateWaitingForPrometheus, expectedReady: false},
+		{state: StateWaitingForLeadership, expectedReady: false},
+		{state: StateControllerRunning, expectedReady: true},
+	}
+
+	for index, test := range tests {
+		if ready := test.state.Ready(); ready != test.expectedReady {
+			t.Fatalf("%d: expected state %#q to be ready %t, got %t", index, test.state, test.expectedReady, ready)
+		}
+	}
+}
@@ -3,23 +3,42 @@ package service
 import (
 	"context"
 	"fmt"
-	"net/http"
+	"io/ioutil"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/giantswarm/apiextensions/v2/pkg/apis/core/v1alpha1"
-	"github.com/giantswarm/backoff"
 	"github.com/giantswarm/k8sclient/v4/pkg/k8sclient"
 	"github.com/giantswarm/k8sclient/v4/pkg/k8srestconfig"
 	"github.com/giantswarm/microendpoint/service/version"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 	"k8s.io/client-go/rest"
+	capiv1alpha3 "sigs.k8s.io/cluster-api/api/v1alpha3"
 
 	"github.com/giantswarm/prometheus-config-controller/flag"
 	"github.com/giantswarm/prometheus-config-controller/service/controller"
-	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/key"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/certificatesource"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/clustersource"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/inventory"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/prometheus"
+	"github.com/giantswarm/prometheus-config-controller/service/controller/v1/resource/certificate"
+	"github.com/giantswarm/prometheus-config-controller/service/discovery"
+	"github.com/giantswarm/prometheus-config-controller/service/dryrun"
+	"github.com/giantswarm/prometheus-config-controller/service/leader"
+	"github.com/giantswarm/prometheus-config-controller/service/readiness"
+)
+
+const (
+	// inventoryResyncPeriod is the period the inventory informers resync
+	// their cache in.
+	inventoryResyncPeriod = 5 * time.Minute
+	// vaultTokenEnv is the environment variable holding the token to
+	// authenticate with Vault, unless a token file is configured.
+	vaultTokenEnv = "VAULT_TOKEN"
 )
 
 type Config struct {
@@ -35,12 +54,16 @@ type Config struct {
 }
 
 type Service struct {
-	Version *version.Service
+	Discovery *discovery.Service
+	DryRun    *dryrun.Service
+	Leader    *leader.Service
+	Readiness *readiness.Service
+	Version   *version.Service
 
-	logger micrologger.Logger
+	inventory *inventory.Inventory
+	logger    micrologger.Logger
 
 	bootOnce             sync.Once
-	prometheusAddress    string
 	prometheusController *controller.Prometheus
 }
 
@@ -80,6 +103,7 @@ func New(config Config) (*Service, error) {
 		c := k8sclient.ClientsConfig{
 			SchemeBuilder: k8sclient.SchemeBuilder{
 				v1alpha1.AddToScheme,
+				capiv1alpha3.AddToScheme,
 			},
 			Logger:     config.Logger,
 			RestConfig: restConfig,
@@ -90,21 +114,244 @@ func New(config Config) (*Service, error) {
 		}
 	}
 
-	var prometheusController *controller.Prometheus
+	var inventoryService *inventory.Inventory
 	{
-		c := controller.PrometheusConfig{
-			K8sClient: k8sClient,
+		c := inventory.Config{
+			K8sClient: k8sClient.K8sClient(),
+			Logger:    config.Logger,
+
+			CertNamespace: config.Viper.GetString(config.Flag.Service.Resource.Certificate.Namespace),
+			ResyncPeriod:  inventoryResyncPeriod,
+		}
+
+		inventoryService, err = inventory.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var certificateSource certificatesource.Interface
+	{
+		switch s := config.Viper.GetString(config.Flag.Service.Resource.Certificate.Source); s {
+		case certificatesource.KindLegacy:
+			c := certificatesource.LegacyConfig{
+				Inventory: inventoryService,
+
+				ComponentName: config.Viper.GetString(config.Flag.Service.Resource.Certificate.ComponentName),
+			}
+
+			certificateSource, err = certificatesource.NewLegacy(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		case certificatesource.KindSecret:
+			c := certificatesource.SecretConfig{
+				K8sClient: k8sClient.K8sClient(),
+
+				CAKey:             config.Viper.GetString(config.Flag.Service.Resource.Certificate.Secret.CAKey),
+				CrtKey:            config.Viper.GetString(config.Flag.Service.Resource.Certificate.Secret.CrtKey),
+				KeyKey:            config.Viper.GetString(config.Flag.Service.Resource.Certificate.Secret.KeyKey),
+				NameTemplate:      config.Viper.GetString(config.Flag.Service.Resource.Certificate.Secret.NameTemplate),
+				NamespaceTemplate: config.Viper.GetString(config.Flag.Service.Resource.Certificate.Secret.NamespaceTemplate),
+				SelectorTemplate:  config.Viper.GetString(config.Flag.Service.Resource.Certificate.Secret.SelectorTemplate),
+				ResyncPeriod:      inventoryResyncPeriod,
+			}
+
+			certificateSource, err = certificatesource.NewSecret(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		case certificatesource.KindVault:
+			c := certificatesource.VaultConfig{
+				Address:            config.Viper.GetString(config.Flag.Service.Resource.Certificate.Vault.Address),
+				CommonNameTemplate: config.Viper.GetString(config.Flag.Service.Resource.Certificate.Vault.CommonNameTemplate),
+				MountTemplate:      config.Viper.GetString(config.Flag.Service.Resource.Certificate.Vault.MountTemplate),
+				Role:               config.Viper.GetString(config.Flag.Service.Resource.Certificate.Vault.Role),
+				Token:              os.Getenv(vaultTokenEnv),
+				TokenFile:          config.Viper.GetString(config.Flag.Service.Resource.Certificate.Vault.TokenFile),
+				TTL:                config.Viper.GetDuration(config.Flag.Service.Resource.Certificate.Vault.TTL),
+			}
+
+			certificateSource, err = certificatesource.NewVault(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		default:
+			return nil, microerror.Maskf(invalidConfigError, "%T.Flag.Service.Resource.Certificate.Source must be one of %#q, %#q, %#q but got %#q", config, certificatesource.KindLegacy, certificatesource.KindSecret, certificatesource.KindVault, s)
+		}
+	}
+
+	var clusterSource clustersource.Interface
+	{
+		switch s := config.Viper.GetString(config.Flag.Service.Prometheus.ClusterSource); s {
+		case clustersource.KindService:
+			c := clustersource.ServiceConfig{
+				Inventory: inventoryService,
+			}
+
+			clusterSource, err = clustersource.NewService(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		case clustersource.KindCAPI:
+			c := clustersource.CAPIConfig{
+				CtrlClient: k8sClient.CtrlClient(),
+			}
+
+			clusterSource, err = clustersource.NewCAPI(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		default:
+			return nil, microerror.Maskf(invalidConfigError, "%T.Flag.Service.Prometheus.ClusterSource must be one of %#q, %#q but got %#q", config, clustersource.KindService, clustersource.KindCAPI, s)
+		}
+	}
+
+	var exporters []prometheus.Exporter
+	{
+		p := config.Viper.GetString(config.Flag.Service.Prometheus.ExporterCatalog)
+
+		if p != "" {
+			data, err := ioutil.ReadFile(p)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			exporters, err = prometheus.LoadExporters(data)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+	}
+
+	var sampleLimits map[string]uint
+	{
+		sampleLimits, err = prometheus.ParseSampleLimits(config.Viper.GetString(config.Flag.Service.Prometheus.SampleLimits), exporters)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var dryRunService *dryrun.Service
+	{
+		c := dryrun.Config{
+			Enabled: config.Viper.GetBool(config.Flag.Service.Resource.DryRun),
+		}
+
+		dryRunService, err = dryrun.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var leaderService *leader.Service
+	{
+		identity, err := os.Hostname()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		c := leader.Config{
+			K8sClient: k8sClient.K8sClient(),
 			Logger:    config.Logger,
 
-			ConfigMapKey:       config.Viper.GetString(config.Flag.Service.Resource.ConfigMap.Key),
-			ConfigMapName:      config.Viper.GetString(config.Flag.Service.Resource.ConfigMap.Name),
-			ConfigMapNamespace: config.Viper.GetString(config.Flag.Service.Resource.ConfigMap.Namespace),
-			CertComponentName:  config.Viper.GetString(config.Flag.Service.Resource.Certificate.ComponentName),
-			CertDirectory:      config.Viper.GetString(config.Flag.Service.Resource.Certificate.Directory),
-			CertNamespace:      config.Viper.GetString(config.Flag.Service.Resource.Certificate.Namespace),
-			CertPermission:     config.Viper.GetInt(config.Flag.Service.Resource.Certificate.Permission),
-			PrometheusAddress:  config.Viper.GetString(config.Flag.Service.Prometheus.Address),
-			Provider:           config.Viper.GetString(config.Flag.Service.Prometheus.Provider),
+			Enabled:        config.Viper.GetBool(config.Flag.Service.LeaderElection.Enabled),
+			Identity:       identity,
+			LeaseDuration:  config.Viper.GetDuration(config.Flag.Service.LeaderElection.LeaseDuration),
+			LeaseName:      config.Viper.GetString(config.Flag.Service.LeaderElection.Name),
+			LeaseNamespace: config.Viper.GetString(config.Flag.Service.LeaderElection.Namespace),
+			RenewDeadline:  config.Viper.GetDuration(config.Flag.Service.LeaderElection.RenewDeadline),
+			RetryPeriod:    config.Viper.GetDuration(config.Flag.Service.LeaderElection.RetryPeriod),
+		}
+
+		leaderService, err = leader.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var readinessService *readiness.Service
+	{
+		c := readiness.Config{
+			Logger: config.Logger,
+
+			MaxInterval:       config.Viper.GetDuration(config.Flag.Service.Prometheus.Readiness.MaxInterval),
+			PrometheusAddress: config.Viper.GetString(config.Flag.Service.Prometheus.Address),
+			Strategy:          config.Viper.GetString(config.Flag.Service.Prometheus.Readiness.Strategy),
+		}
+
+		readinessService, err = readiness.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	// Projected certificates are not on disk, so they are mirrored in memory
+	// for the etcd probers and the validation of the configuration.
+	var certFs afero.Fs
+	{
+		switch config.Viper.GetString(config.Flag.Service.Resource.Certificate.Output) {
+		case certificate.OutputProjection:
+			certFs = afero.NewMemMapFs()
+		default:
+			certFs = afero.NewOsFs()
+		}
+	}
+
+	var discoveryService *discovery.Service
+	{
+		c := discovery.Config{
+			Logger: config.Logger,
+		}
+
+		discoveryService, err = discovery.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var prometheusController *controller.Prometheus
+	{
+		c := controller.PrometheusConfig{
+			CertFs:            certFs,
+			CertificateSource: certificateSource,
+			ClusterSource:     clusterSource,
+			Discovery:         discoveryService,
+			DryRun:            dryRunService,
+			Exporters:         exporters,
+			K8sClient:         k8sClient,
+			Logger:            config.Logger,
+			SampleLimits:      sampleLimits,
+
+			Backend:                 config.Viper.GetString(config.Flag.Service.Resource.Backend),
+			ConfigMapKey:            config.Viper.GetString(config.Flag.Service.Resource.ConfigMap.Key),
+			ConfigMapName:           config.Viper.GetString(config.Flag.Service.Resource.ConfigMap.Name),
+			ConfigMapNamespace:      config.Viper.GetString(config.Flag.Service.Resource.ConfigMap.Namespace),
+			CertDirectory:           config.Viper.GetString(config.Flag.Service.Resource.Certificate.Directory),
+			CertExpiryWarning:       config.Viper.GetDuration(config.Flag.Service.Resource.Certificate.ExpiryWarningWindow),
+			CertOutput:              config.Viper.GetString(config.Flag.Service.Resource.Certificate.Output),
+			CertPermission:          config.Viper.GetInt(config.Flag.Service.Resource.Certificate.Permission),
+			CertProjectionName:      config.Viper.GetString(config.Flag.Service.Resource.Certificate.Projection.Name),
+			CertProjectionNamespace: config.Viper.GetString(config.Flag.Service.Resource.Certificate.Projection.Namespace),
+			CertProjectionSecrets:   config.Viper.GetInt(config.Flag.Service.Resource.Certificate.Projection.Secrets),
+			DiscoveryURL:            config.Viper.GetString(config.Flag.Service.Prometheus.DiscoveryURL),
+			EtcdScrapeDelay:         config.Viper.GetDuration(config.Flag.Service.Prometheus.Etcd.ScrapeDelay),
+			EtcdScrapeMode:          config.Viper.GetString(config.Flag.Service.Prometheus.Etcd.ScrapeMode),
+			PrometheusVersion:       config.Viper.GetString(config.Flag.Service.Prometheus.Version),
+			PrometheusAddress:       config.Viper.GetString(config.Flag.Service.Prometheus.Address),
+			Provider:                config.Viper.GetString(config.Flag.Service.Prometheus.Provider),
+			ReloadMode:              config.Viper.GetString(config.Flag.Service.Prometheus.Reload.Mode),
+			ReloadNamespace:         config.Viper.GetString(config.Flag.Service.Prometheus.Reload.Namespace),
+			ReloadPort:              config.Viper.GetInt(config.Flag.Service.Prometheus.Reload.Port),
+			ReloadSelector:          config.Viper.GetString(config.Flag.Service.Prometheus.Reload.Selector),
+			ReloadService:           config.Viper.GetString(config.Flag.Service.Prometheus.Reload.Service),
+			ReloadVerifyInterval:    config.Viper.GetDuration(config.Flag.Service.Prometheus.Reload.Verify.Interval),
+			ReloadVerifyTimeout:     config.Viper.GetDuration(config.Flag.Service.Prometheus.Reload.Verify.Timeout),
+			SecretKey:               config.Viper.GetString(config.Flag.Service.Resource.Secret.Key),
+			SecretName:              config.Viper.GetString(config.Flag.Service.Resource.Secret.Name),
+			SecretNamespace:         config.Viper.GetString(config.Flag.Service.Resource.Secret.Namespace),
+			ShardCount:              config.Viper.GetInt(config.Flag.Service.Prometheus.ShardCount),
+			ShardIndex:              config.Viper.GetInt(config.Flag.Service.Prometheus.ShardIndex),
 		}
 
 		prometheusController, err = controller.NewPrometheus(c)
@@ -131,13 +378,17 @@ func New(config Config) (*Service, error) {
 	}
 
 	s := &Service{
-		Version: versionService,
+		Discovery: discoveryService,
+		DryRun:    dryRunService,
+		Leader:    leaderService,
+		Readiness: readinessService,
+		Version:   versionService,
 
-		logger: config.Logger,
+		inventory: inventoryService,
+		logger:    config.Logger,
 
 		bootOnce: sync.Once{},
 
-		prometheusAddress:    config.Viper.GetString(config.Flag.Service.Prometheus.Address),
 		prometheusController: prometheusController,
 	}
 
@@ -148,7 +399,10 @@ func (s *Service) Boot() {
 	ctx := context.TODO()
 
 	err := s.boot(ctx)
-	if err != nil {
+	if leader.IsLeadershipLost(err) {
+		s.logger.LogCtx(ctx, "level", "error", "message", "lost leadership, exiting so that a standby replica takes over", "stack", fmt.Sprintf("%#v", err))
+		panic(fmt.Sprintf("lost leadership, please see the logs"))
+	} else if err != nil {
 		s.logger.LogCtx(ctx, "level", "error", "message", "failed to boot the service", "stack", fmt.Sprintf("%#v", err))
 		panic(fmt.Sprintf("failed to boot the service, please see the logs"))
 	}
@@ -158,40 +412,43 @@ func (s *Service) boot(ctx context.Context) error {
 	// Wait for Prometheus to be ready before booting the controller.
 	// Otherwise it will fail to (re)load the configuration.
 	{
-		s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("waiting for Prometheus to be up"))
+		s.logger.LogCtx(ctx, "level", "debug", "message", "waiting for Prometheus to be ready")
 
-		// Prometheus won't start in 90 seconds anyway so let's not
-		// spam with logs and wait for it.
-		time.Sleep(90 * time.Second)
-
-		url := key.PrometheusURLConfig(s.prometheusAddress)
-
-		o := func() error {
-			res, err := http.Get(url)
-			if err != nil {
-				return microerror.Maskf(waitError, "failed request URL %#q with error %#q", url, err)
-			}
+		err := s.Readiness.WaitForPrometheus(ctx)
+		if err != nil {
+			return microerror.Mask(err)
+		}
 
-			if res.StatusCode < 200 || res.StatusCode > 299 {
-				return microerror.Maskf(waitError, "expected 2xx response for URL %#q but got %d", url, res.StatusCode)
-			}
+		s.logger.LogCtx(ctx, "level", "debug", "message", "waited for Prometheus to be ready")
+	}
 
-			return nil
-		}
-		b := backoff.NewMaxRetries(10, 60*time.Second)
-		n := backoff.NewNotifier(s.logger, ctx)
+	{
+		s.logger.LogCtx(ctx, "level", "debug", "message", "starting inventory")
 
-		err := backoff.RetryNotify(o, b, n)
+		err := s.inventory.Start(ctx)
 		if err != nil {
 			return microerror.Mask(err)
 		}
 
-		s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("waited for Prometheus to be up"))
+		s.logger.LogCtx(ctx, "level", "debug", "message", "started inventory")
 	}
 
-	s.bootOnce.Do(func() {
-		go s.prometheusController.Boot(ctx)
-	})
+	// Only the leader runs the controller, since the reload resource keeps
+	// state in memory and replicas would race on the same configuration.
+	{
+		s.Readiness.SetState(readiness.StateWaitingForLeadership)
+
+		err := s.Leader.Run(ctx, func(ctx context.Context) {
+			s.Readiness.SetState(readiness.StateControllerRunning)
+
+			s.bootOnce.Do(func() {
+				go s.prometheusController.Boot(ctx)
+			})
+		})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
 
 	return nil
 }